@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Set at build time with
+// -ldflags "-X github.com/mauricioww/user_microsrv/admin.Commit=<sha> -X github.com/mauricioww/user_microsrv/admin.BuildTime=<time>".
+var (
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+func init() {
+	expvar.Publish("build", expvar.Func(func() interface{} { return version() }))
+}
+
+// NewMux returns the handler for the admin listener, kept apart from the
+// public port so operational endpoints are never exposed with the API.
+func NewMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/version", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(version())
+	})
+	return mux
+}
+
+// NewMetricsMux serves /metrics alone, for a listener scrapers can reach
+// while the admin one stays on loopback.
+func NewMetricsMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// Handler requires "Authorization: Bearer <token>" on every request when
+// token is set; an empty token leaves access to the bind address alone.
+func Handler(next http.Handler, token string) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !authorized(r.Header.Get("Authorization"), token) {
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// NewGrpcServer returns a server exposing the channelz service, guarded by
+// the same token as the admin HTTP listener.
+func NewGrpcServer(token string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if token != "" {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if err := authorizeGrpc(ctx, token); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := authorizeGrpc(ss.Context(), token); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+
+	server := grpc.NewServer(opts...)
+	channelz.RegisterChannelzServiceToServer(server)
+	reflection.Register(server)
+	return server
+}
+
+func authorizeGrpc(ctx context.Context, token string) error {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get("authorization") {
+			if authorized(v, token) {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func authorized(header string, token string) bool {
+	given := strings.TrimPrefix(header, "Bearer ")
+	return given != header && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func version() map[string]string {
+	return map[string]string{
+		"commit":     Commit,
+		"build_time": BuildTime,
+		"go_version": runtime.Version(),
+	}
+}
@@ -0,0 +1,183 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mauricioww/user_microsrv/admin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestHandler(t *testing.T) {
+	test_cases := []struct {
+		test_name     string
+		token         string
+		authorization string
+		path          string
+		httpStatus    int
+	}{
+		{
+			test_name:  "open without token",
+			path:       "/debug/pprof/",
+			httpStatus: http.StatusOK,
+		},
+		{
+			test_name:  "missing token",
+			token:      "s3cr3t",
+			path:       "/metrics",
+			httpStatus: http.StatusUnauthorized,
+		},
+		{
+			test_name:     "wrong token",
+			token:         "s3cr3t",
+			authorization: "Bearer nope",
+			path:          "/debug/vars",
+			httpStatus:    http.StatusUnauthorized,
+		},
+		{
+			test_name:     "valid token",
+			token:         "s3cr3t",
+			authorization: "Bearer s3cr3t",
+			path:          "/debug/vars",
+			httpStatus:    http.StatusOK,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			handler := admin.Handler(admin.NewMux(), tc.token)
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", tc.authorization)
+			rec := httptest.NewRecorder()
+
+			// act
+			handler.ServeHTTP(rec, req)
+
+			// assert
+			assert.Equal(t, tc.httpStatus, rec.Code)
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	admin.Commit, admin.BuildTime = "abc123", "2026-10-19T00:00:00Z"
+	rec := httptest.NewRecorder()
+
+	// act
+	admin.NewMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var body map[string]string
+	json.NewDecoder(rec.Body).Decode(&body)
+
+	// assert
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("abc123", body["commit"])
+	assert.Equal("2026-10-19T00:00:00Z", body["build_time"])
+	assert.NotEmpty(body["go_version"])
+}
+
+func TestMetricsMux(t *testing.T) {
+	test_cases := []struct {
+		test_name  string
+		path       string
+		httpStatus int
+	}{
+		{
+			test_name:  "metrics",
+			path:       "/metrics",
+			httpStatus: http.StatusOK,
+		},
+		{
+			test_name:  "no debug endpoints",
+			path:       "/debug/pprof/",
+			httpStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			rec := httptest.NewRecorder()
+
+			// act
+			admin.NewMetricsMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			// assert
+			assert.Equal(t, tc.httpStatus, rec.Code)
+		})
+	}
+}
+
+func TestChannelz(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := admin.NewGrpcServer("s3cr3t")
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, _ := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	defer conn.Close()
+	client := channelzpb.NewChannelzClient(conn)
+
+	// prepare
+	assert := assert.New(t)
+	authorized := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cr3t")
+
+	// act
+	_, denied := client.GetServers(context.Background(), &channelzpb.GetServersRequest{})
+	res, err := client.GetServers(authorized, &channelzpb.GetServersRequest{})
+
+	// assert
+	assert.Equal(codes.Unauthenticated, status.Code(denied))
+	assert.Nil(err)
+	assert.NotEmpty(res.GetServer())
+}
+
+func TestReflection(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := admin.NewGrpcServer("s3cr3t")
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, _ := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	defer conn.Close()
+	client := reflectionpb.NewServerReflectionClient(conn)
+
+	// prepare
+	assert := assert.New(t)
+	authorized := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cr3t")
+
+	// act
+	stream, err := client.ServerReflectionInfo(authorized)
+	assert.Nil(err)
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	assert.Nil(err)
+	res, err := stream.Recv()
+
+	// assert
+	assert.Nil(err)
+	var services []string
+	for _, s := range res.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	assert.Contains(services, "grpc.channelz.v1.Channelz")
+	assert.Contains(services, "grpc.reflection.v1alpha.ServerReflection")
+}
@@ -1,138 +1,274 @@
 package errors
 
 import (
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// Domain identifies this system in the google.rpc.ErrorInfo detail.
+const Domain = "user_microsrv"
+
+// Reasons are stable and machine-readable; clients may switch on them, so
+// existing values must never change meaning.
 const (
-	badRequestEmail    = 0
-	badRequestPassword = 1
-	unauthenticated    = 2
-	unauthorized       = 3
-	userNotFound       = 4
-	serverFail         = 5
+	ReasonMissingEmail       = "MISSING_EMAIL"
+	ReasonMissingPassword    = "MISSING_PASSWORD"
+	ReasonInvalidArgument    = "INVALID_ARGUMENT"
+	ReasonInvalidCredentials = "INVALID_CREDENTIALS"
+	ReasonUnauthorized       = "UNAUTHORIZED"
+	ReasonUserNotFound       = "USER_NOT_FOUND"
+	ReasonWebhookNotFound    = "WEBHOOK_NOT_FOUND"
+	ReasonUnavailable        = "UNAVAILABLE"
+	ReasonCircuitOpen        = "CIRCUIT_OPEN"
+	ReasonInternal           = "INTERNAL"
+	ReasonDeadlineExceeded   = "DEADLINE_EXCEEDED"
+	ReasonCanceled           = "CANCELED"
+	ReasonUnknown            = "UNKNOWN"
+
+	// HTTP only: gRPC has its own framing and message size limits.
+	ReasonNotAcceptable        = "NOT_ACCEPTABLE"
+	ReasonUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	ReasonPayloadTooLarge      = "PAYLOAD_TOO_LARGE"
 )
 
-var message = map[int]string{
-	badRequestEmail:    "Missing field 'email'",
-	badRequestPassword: "Missing field 'password'",
-	unauthenticated:    "Password or email error",
-	unauthorized:       "Unauthorized user",
-	userNotFound:       "User not found",
-	serverFail:         "Internal server error",
-}
+var message = map[string]string{
+	ReasonMissingEmail:       "Missing field 'email'",
+	ReasonMissingPassword:    "Missing field 'password'",
+	ReasonInvalidArgument:    "Invalid request",
+	ReasonInvalidCredentials: "Password or email error",
+	ReasonUnauthorized:       "Unauthorized user",
+	ReasonUserNotFound:       "User not found",
+	ReasonWebhookNotFound:    "Webhook subscription not found",
+	ReasonUnavailable:        "Service unavailable",
+	ReasonInternal:           "Internal server error",
+	ReasonDeadlineExceeded:   "Request timed out",
+	ReasonCanceled:           "Request canceled",
 
-func MessageError(code int) string {
-	return message[code]
+	ReasonNotAcceptable:        "None of the accepted media types can be produced",
+	ReasonUnsupportedMediaType: "Unsupported request media type",
+	ReasonPayloadTooLarge:      "Request body too large",
 }
 
-func resolveGrpc(err int) codes.Code {
-	switch err {
-	case badRequestEmail, badRequestPassword:
-		return codes.FailedPrecondition
-	case unauthenticated, unauthorized:
-		return codes.Unauthenticated
-	case userNotFound:
-		return codes.NotFound
-	default:
-		return codes.Internal
-	}
+func MessageError(reason string) string {
+	return message[reason]
 }
 
-func ResolveHttp(c codes.Code) int {
-	switch c {
-	case codes.FailedPrecondition:
-		return 400
-	case codes.Unauthenticated:
-		return 401
-	case codes.NotFound:
-		return 404
-	default:
-		return 500
+type (
+	FieldViolation struct {
+		Field       string
+		Description string
 	}
-}
 
-type (
-	BadRequestEmailError    int
-	BadRequestPasswordError int
-	InternalError           int
-	UserNotFoundError       int
-	UnauthorizedError       int
-	UnauthenticatedError    int
+	// Error is the error every layer returns. Its message is safe to show to
+	// callers; the wrapped cause is kept for logs and errors.Is/As only.
+	Error struct {
+		Code       codes.Code
+		Reason     string
+		Message    string
+		Violations []FieldViolation
+		RetryAfter time.Duration
+		Metadata   map[string]string
+		cause      error
+	}
 
 	ErrorResolver interface {
 		GrpcCode() codes.Code
 	}
 )
 
-func NewBadRequestEmailError() BadRequestEmailError {
-	return badRequestEmail
+func New(c codes.Code, reason string, msg string) *Error {
+	return &Error{Code: c, Reason: reason, Message: msg}
 }
 
-func NewBadRequestPasswordError() BadRequestPasswordError {
-	return badRequestPassword
+func NewBadRequestEmailError() *Error {
+	e := New(codes.FailedPrecondition, ReasonMissingEmail, message[ReasonMissingEmail])
+	e.Violations = []FieldViolation{{Field: "email", Description: "is required"}}
+	return e
 }
 
-func NewInternalError() InternalError {
-	return serverFail
+func NewBadRequestPasswordError() *Error {
+	e := New(codes.FailedPrecondition, ReasonMissingPassword, message[ReasonMissingPassword])
+	e.Violations = []FieldViolation{{Field: "password", Description: "is required"}}
+	return e
 }
 
-func NewUserNotFoundError() UserNotFoundError {
-	return userNotFound
+// NewInvalidArgumentError reports every violation found in a request at once.
+func NewInvalidArgumentError(violations ...FieldViolation) *Error {
+	e := New(codes.FailedPrecondition, ReasonInvalidArgument, message[ReasonInvalidArgument])
+	e.Violations = violations
+	return e
 }
 
-func NewUnauthorizedError() UnauthorizedError {
-	return unauthorized
+func NewInternalError() *Error {
+	return New(codes.Internal, ReasonInternal, message[ReasonInternal])
 }
 
-func NewUnauthenticatedError() UnauthenticatedError {
-	return unauthenticated
+func NewUserNotFoundError() *Error {
+	return New(codes.NotFound, ReasonUserNotFound, message[ReasonUserNotFound])
 }
 
-func (e BadRequestEmailError) Error() string {
-	return MessageError(int(e))
+func NewWebhookNotFoundError() *Error {
+	return New(codes.NotFound, ReasonWebhookNotFound, message[ReasonWebhookNotFound])
 }
 
-func (e BadRequestPasswordError) Error() string {
-	return MessageError(int(e))
+func NewUnauthorizedError() *Error {
+	return New(codes.Unauthenticated, ReasonUnauthorized, message[ReasonUnauthorized])
 }
 
-func (e InternalError) Error() string {
-	return MessageError(int(e))
+func NewUnauthenticatedError() *Error {
+	return New(codes.Unauthenticated, ReasonInvalidCredentials, message[ReasonInvalidCredentials])
 }
 
-func (e UserNotFoundError) Error() string {
-	return MessageError(int(e))
+func NewUnavailableError(retry_after time.Duration) *Error {
+	e := New(codes.Unavailable, ReasonUnavailable, message[ReasonUnavailable])
+	e.RetryAfter = retry_after
+	return e
 }
 
-func (e UnauthorizedError) Error() string {
-	return MessageError(int(e))
+// NewNotAcceptableError lists the media types a response can be had in.
+func NewNotAcceptableError(supported ...string) *Error {
+	return New(codes.InvalidArgument, ReasonNotAcceptable, message[ReasonNotAcceptable]+"; supported: "+strings.Join(supported, ", "))
 }
 
-func (e UnauthenticatedError) Error() string {
-	return MessageError(int(e))
+// NewUnsupportedMediaTypeError lists the media types a request body may be
+// sent in.
+func NewUnsupportedMediaTypeError(supported ...string) *Error {
+	return New(codes.InvalidArgument, ReasonUnsupportedMediaType, message[ReasonUnsupportedMediaType]+"; supported: "+strings.Join(supported, ", "))
 }
 
-func (e BadRequestEmailError) GrpcCode() codes.Code {
-	return resolveGrpc(int(e))
+// NewPayloadTooLargeError tells the largest body accepted, in bytes.
+func NewPayloadTooLargeError(limit int64) *Error {
+	return New(codes.InvalidArgument, ReasonPayloadTooLarge, message[ReasonPayloadTooLarge]+"; limit: "+strconv.FormatInt(limit, 10)+" bytes")
 }
 
-func (e BadRequestPasswordError) GrpcCode() codes.Code {
-	return resolveGrpc(int(e))
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
 }
 
-func (e InternalError) GrpcCode() codes.Code {
-	return resolveGrpc(int(e))
+func (e *Error) Unwrap() error {
+	return e.cause
 }
 
-func (e UserNotFoundError) GrpcCode() codes.Code {
-	return resolveGrpc(int(e))
+// Is matches on the reason, so errors.Is(err, NewUserNotFoundError()) holds
+// whatever the cause or message.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Reason == e.Reason
 }
 
-func (e UnauthorizedError) GrpcCode() codes.Code {
-	return resolveGrpc(int(e))
+func (e *Error) GrpcCode() codes.Code {
+	return e.Code
 }
 
-func (e UnauthenticatedError) GrpcCode() codes.Code {
-	return resolveGrpc(int(e))
+// Wrap returns a copy of e carrying cause.
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// GRPCStatus attaches ErrorInfo, BadRequest and RetryInfo details; grpc-go
+// uses it whenever e is returned from a handler.
+func (e *Error) GRPCStatus() *status.Status {
+	s := status.New(e.Code, e.Message)
+
+	with := []proto.Message{&errdetails.ErrorInfo{Reason: e.Reason, Domain: Domain, Metadata: e.Metadata}}
+	if len(e.Violations) > 0 {
+		bad := &errdetails.BadRequest{}
+		for _, v := range e.Violations {
+			bad.FieldViolations = append(bad.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: v.Field, Description: v.Description})
+		}
+		with = append(with, bad)
+	}
+	if e.RetryAfter > 0 {
+		with = append(with, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	}
+
+	if d, err := s.WithDetails(with...); err == nil {
+		return d
+	}
+	return s
+}
+
+// FromError recovers the typed error from err: either an *Error from this
+// process or the status of a downstream call, details included. Anything
+// else keeps its gRPC code and message under ReasonUnknown.
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if stderrors.As(err, &e) {
+		return e
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return New(codes.Unknown, ReasonUnknown, err.Error()).Wrap(err)
+	}
+
+	e = New(s.Code(), ReasonUnknown, s.Message())
+	for _, d := range s.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			e.Reason = d.GetReason()
+			e.Metadata = d.GetMetadata()
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				e.Violations = append(e.Violations, FieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+			}
+		case *errdetails.RetryInfo:
+			e.RetryAfter = d.GetRetryDelay().AsDuration()
+		}
+	}
+	return e.Wrap(err)
+}
+
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	} else if e, ok := err.(ErrorResolver); ok {
+		return e.GrpcCode()
+	}
+	return status.Code(err)
+}
+
+func ResolveHttp(c codes.Code) int {
+	switch c {
+	case codes.FailedPrecondition, codes.InvalidArgument, codes.OutOfRange:
+		return 400
+	case codes.Unauthenticated:
+		return 401
+	case codes.PermissionDenied:
+		return 403
+	case codes.NotFound:
+		return 404
+	case codes.AlreadyExists, codes.Aborted:
+		return 409
+	case codes.ResourceExhausted:
+		return 429
+	case codes.Canceled:
+		// nginx's "client closed request"; nobody is left to read it
+		return 499
+	case codes.Unimplemented:
+		return 501
+	case codes.Unavailable:
+		return 503
+	case codes.DeadlineExceeded:
+		return 504
+	default:
+		return 500
+	}
 }
@@ -0,0 +1,125 @@
+package errors_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		err       *errors.Error
+	}{
+		{
+			test_name: "field violations",
+			err: errors.NewInvalidArgumentError(
+				errors.FieldViolation{Field: "email", Description: "is required"},
+				errors.FieldViolation{Field: "age", Description: "must be positive"},
+			),
+		},
+		{
+			test_name: "retry info",
+			err:       errors.NewUnavailableError(1500 * time.Millisecond),
+		},
+		{
+			test_name: "metadata",
+			err: func() *errors.Error {
+				e := errors.NewUserNotFoundError()
+				e.Metadata = map[string]string{"user_id": "7"}
+				return e
+			}(),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			sent := tc.err.Wrap(fmt.Errorf("driver failure"))
+
+			// act
+			// a status rebuilt from its proto is what a client sees after the hop
+			wire := status.FromProto(status.Convert(sent).Proto()).Err()
+			res := errors.FromError(wire)
+
+			// assert
+			assert.Equal(tc.err.Code, status.Code(wire))
+			assert.Equal(tc.err.Message, status.Convert(wire).Message())
+			assert.Equal(tc.err.Code, res.Code)
+			assert.Equal(tc.err.Reason, res.Reason)
+			assert.Equal(tc.err.Message, res.Message)
+			assert.Equal(tc.err.Violations, res.Violations)
+			assert.Equal(tc.err.RetryAfter, res.RetryAfter)
+			assert.Equal(tc.err.Metadata, res.Metadata)
+			assert.NotContains(status.Convert(wire).Message(), "driver failure")
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	cause := fmt.Errorf("connection reset")
+
+	// act
+	err := fmt.Errorf("get user: %w", errors.NewInternalError().Wrap(cause))
+
+	// assert
+	assert.ErrorIs(err, cause)
+	assert.ErrorIs(err, errors.NewInternalError())
+	assert.False(stderrors.Is(err, errors.NewUserNotFoundError()))
+	assert.Equal("get user: Internal server error: connection reset", err.Error())
+	assert.Equal(codes.Internal, errors.FromError(err).Code)
+}
+
+func TestFromError(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		err       error
+		code      codes.Code
+		reason    string
+	}{
+		{
+			test_name: "nil",
+			err:       nil,
+		},
+		{
+			test_name: "status without details",
+			err:       status.Error(codes.NotFound, "User not found"),
+			code:      codes.NotFound,
+			reason:    errors.ReasonUnknown,
+		},
+		{
+			test_name: "plain error",
+			err:       fmt.Errorf("boom"),
+			code:      codes.Unknown,
+			reason:    errors.ReasonUnknown,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			res := errors.FromError(tc.err)
+
+			// assert
+			if tc.err == nil {
+				assert.Nil(res)
+				return
+			}
+			assert.Equal(tc.code, res.Code)
+			assert.Equal(tc.reason, res.Reason)
+			assert.ErrorIs(res, tc.err)
+		})
+	}
+}
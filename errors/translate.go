@@ -0,0 +1,44 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Translate is the single place deciding what a caller learns about err.
+// Context errors become DeadlineExceeded or Canceled wherever they were
+// wrapped, typed errors and downstream statuses keep their code, and
+// anything else is reported as Internal so its text never leaves the
+// process; the original error stays reachable through Unwrap for logging.
+func Translate(err error) *Error {
+	switch {
+	case err == nil:
+		return nil
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return New(codes.DeadlineExceeded, ReasonDeadlineExceeded, message[ReasonDeadlineExceeded]).Wrap(err)
+	case stderrors.Is(err, context.Canceled):
+		return New(codes.Canceled, ReasonCanceled, message[ReasonCanceled]).Wrap(err)
+	}
+
+	var e *Error
+	if stderrors.As(err, &e) {
+		return e
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.DeadlineExceeded:
+			return New(codes.DeadlineExceeded, ReasonDeadlineExceeded, message[ReasonDeadlineExceeded]).Wrap(err)
+		case codes.Canceled:
+			return New(codes.Canceled, ReasonCanceled, message[ReasonCanceled]).Wrap(err)
+		case codes.Unknown:
+			return NewInternalError().Wrap(err)
+		}
+		return FromError(err)
+	}
+
+	return NewInternalError().Wrap(err)
+}
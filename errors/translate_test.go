@@ -0,0 +1,104 @@
+package errors_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTranslate(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		err       error
+		code      codes.Code
+		reason    string
+		msg       string
+	}{
+		{
+			test_name: "typed error",
+			err:       errors.NewUserNotFoundError(),
+			code:      codes.NotFound,
+			reason:    errors.ReasonUserNotFound,
+			msg:       "User not found",
+		},
+		{
+			test_name: "downstream status",
+			err:       status.Error(codes.Unauthenticated, "Password or email error"),
+			code:      codes.Unauthenticated,
+			reason:    errors.ReasonUnknown,
+			msg:       "Password or email error",
+		},
+		{
+			test_name: "downstream unknown status",
+			err:       status.Error(codes.Unknown, "sql: database is closed"),
+			code:      codes.Internal,
+			reason:    errors.ReasonInternal,
+			msg:       "Internal server error",
+		},
+		{
+			test_name: "plain error",
+			err:       fmt.Errorf("dial tcp 10.0.0.3:3306: connection refused"),
+			code:      codes.Internal,
+			reason:    errors.ReasonInternal,
+			msg:       "Internal server error",
+		},
+		{
+			test_name: "deadline exceeded",
+			err:       context.DeadlineExceeded,
+			code:      codes.DeadlineExceeded,
+			reason:    errors.ReasonDeadlineExceeded,
+			msg:       "Request timed out",
+		},
+		{
+			test_name: "wrapped deadline exceeded",
+			err:       errors.NewInternalError().Wrap(context.DeadlineExceeded),
+			code:      codes.DeadlineExceeded,
+			reason:    errors.ReasonDeadlineExceeded,
+			msg:       "Request timed out",
+		},
+		{
+			test_name: "downstream deadline exceeded",
+			err:       status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+			code:      codes.DeadlineExceeded,
+			reason:    errors.ReasonDeadlineExceeded,
+			msg:       "Request timed out",
+		},
+		{
+			test_name: "canceled",
+			err:       fmt.Errorf("get user: %w", context.Canceled),
+			code:      codes.Canceled,
+			reason:    errors.ReasonCanceled,
+			msg:       "Request canceled",
+		},
+		{
+			test_name: "downstream canceled",
+			err:       status.Error(codes.Canceled, "context canceled"),
+			code:      codes.Canceled,
+			reason:    errors.ReasonCanceled,
+			msg:       "Request canceled",
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			res := errors.Translate(tc.err)
+
+			// assert
+			assert.Equal(tc.code, res.Code)
+			assert.Equal(tc.reason, res.Reason)
+			assert.Equal(tc.msg, status.Convert(res).Message())
+			assert.ErrorIs(res, tc.err)
+		})
+	}
+
+	assert.Nil(t, errors.Translate(nil))
+}
@@ -0,0 +1,206 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var errNotChecked = errors.New("not checked yet")
+
+type Check func(ctx context.Context) error
+
+type dependency struct {
+	name  string
+	check Check
+}
+
+type publisher struct {
+	server   *grpc_health.Server
+	services []string
+}
+
+// Monitor runs the dependency checks every interval and caches the
+// outcome. The service is ready while the lifecycle reports ready and every
+// check passed on the last run; that verdict is pushed to the registered
+// gRPC health servers and served by ReadinessHandler.
+type Monitor struct {
+	interval time.Duration
+	timeout  time.Duration
+	ready    func() bool
+	logger   log.Logger
+
+	deps       []dependency
+	publishers []publisher
+
+	mtx     sync.RWMutex
+	results map[string]error
+}
+
+func NewMonitor(interval time.Duration, timeout time.Duration, ready func() bool, l log.Logger) *Monitor {
+	return &Monitor{
+		interval: interval,
+		timeout:  timeout,
+		ready:    ready,
+		logger:   log.With(l, "component", "health"),
+		results:  make(map[string]error),
+	}
+}
+
+func (m *Monitor) AddCheck(name string, c Check) {
+	m.mtx.Lock()
+	m.deps = append(m.deps, dependency{name: name, check: c})
+	m.results[name] = errNotChecked
+	m.mtx.Unlock()
+}
+
+func (m *Monitor) Publish(s *grpc_health.Server, services ...string) {
+	m.mtx.Lock()
+	m.publishers = append(m.publishers, publisher{server: s, services: append([]string{""}, services...)})
+	m.mtx.Unlock()
+
+	m.Refresh()
+}
+
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.Update(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Monitor) Update(ctx context.Context) {
+	m.mtx.RLock()
+	deps := m.deps
+	m.mtx.RUnlock()
+
+	results := make(map[string]error, len(deps))
+
+	for _, d := range deps {
+		check_ctx, cancel := context.WithTimeout(ctx, m.timeout)
+		err := d.check(check_ctx)
+		cancel()
+
+		if err != nil {
+			level.Warn(m.logger).Log("dependency", d.name, "check", err)
+		}
+		results[d.name] = err
+	}
+
+	m.mtx.Lock()
+	m.results = results
+	m.mtx.Unlock()
+
+	m.Refresh()
+}
+
+func (m *Monitor) Ready() bool {
+	if !m.ready() {
+		return false
+	}
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for _, err := range m.results {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Refresh re-publishes the cached verdict without re-running the checks.
+func (m *Monitor) Refresh() {
+	status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if m.Ready() {
+		status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+
+	m.mtx.RLock()
+	publishers := m.publishers
+	m.mtx.RUnlock()
+
+	for _, p := range publishers {
+		for _, s := range p.services {
+			p.server.SetServingStatus(s, status)
+		}
+	}
+}
+
+func (m *Monitor) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+func (m *Monitor) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m.mtx.RLock()
+		deps := make(map[string]interface{}, len(m.results))
+		for name, err := range m.results {
+			if err != nil {
+				deps[name] = map[string]string{"status": "unavailable", "error": err.Error()}
+			} else {
+				deps[name] = map[string]string{"status": "ok"}
+			}
+		}
+		m.mtx.RUnlock()
+
+		code, status := http.StatusOK, "ok"
+		if !m.Ready() {
+			code, status = http.StatusServiceUnavailable, "unavailable"
+		}
+
+		writeJSON(rw, code, map[string]interface{}{
+			"status":       status,
+			"accepting":    m.ready(),
+			"dependencies": deps,
+		})
+	})
+}
+
+// GrpcCheck asks a downstream server for its grpc.health.v1 status.
+func GrpcCheck(conn *grpc.ClientConn, service string) Check {
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	return func(ctx context.Context) error {
+		res, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if res.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			return statusError(res.GetStatus())
+		}
+		return nil
+	}
+}
+
+type statusError grpc_health_v1.HealthCheckResponse_ServingStatus
+
+func (e statusError) Error() string {
+	return grpc_health_v1.HealthCheckResponse_ServingStatus(e).String()
+}
+
+func writeJSON(rw http.ResponseWriter, code int, body interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(body)
+}
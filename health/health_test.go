@@ -0,0 +1,114 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/health"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func ready() bool {
+	return true
+}
+
+func TestReadinessHandler(t *testing.T) {
+	test_cases := []struct {
+		test_name  string
+		ready      func() bool
+		check_err  error
+		httpStatus int
+		body       string
+	}{
+		{
+			test_name:  "all dependencies ok",
+			ready:      ready,
+			httpStatus: http.StatusOK,
+			body:       `"mysql":{"status":"ok"}`,
+		},
+		{
+			test_name:  "dependency down",
+			ready:      ready,
+			check_err:  errors.New("connection refused"),
+			httpStatus: http.StatusServiceUnavailable,
+			body:       `"mysql":{"error":"connection refused","status":"unavailable"}`,
+		},
+		{
+			test_name:  "shutting down",
+			ready:      func() bool { return false },
+			httpStatus: http.StatusServiceUnavailable,
+			body:       `"accepting":false`,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			monitor := health.NewMonitor(time.Second, time.Second, tc.ready, log.NewNopLogger())
+			monitor.AddCheck("mysql", func(context.Context) error { return tc.check_err })
+			monitor.Update(context.Background())
+			rec := httptest.NewRecorder()
+
+			// act
+			monitor.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			// assert
+			assert.Equal(tc.httpStatus, rec.Code)
+			assert.Contains(rec.Body.String(), tc.body)
+		})
+	}
+}
+
+func TestNotReadyBeforeFirstCheck(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	monitor := health.NewMonitor(time.Second, time.Second, ready, log.NewNopLogger())
+
+	// act
+	monitor.AddCheck("mongodb", func(context.Context) error { return nil })
+
+	// assert
+	assert.False(monitor.Ready())
+}
+
+func TestPublishAndGrpcCheck(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	is_ready := true
+	health_srv := grpc_health.NewServer()
+	monitor := health.NewMonitor(time.Second, time.Second, func() bool { return is_ready }, log.NewNopLogger())
+	monitor.Publish(health_srv, "UserService")
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health_srv)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, _ := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	defer conn.Close()
+	check := health.GrpcCheck(conn, "UserService")
+
+	// act
+	serving := check(context.Background())
+	is_ready = false
+	monitor.Refresh()
+	not_serving := check(context.Background())
+
+	// assert
+	assert.Nil(serving)
+	assert.EqualError(not_serving, "NOT_SERVING")
+}
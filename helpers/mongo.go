@@ -10,7 +10,7 @@ import (
 
 func BuildUpdateBson(d entities.UserDetails) bson.D {
 	return bson.D{
-		{"$set", injectFields(d)},
+		{Key: "$set", Value: injectFields(d)},
 	}
 }
 
@@ -22,7 +22,7 @@ func BuildInsertBson(d entities.UserDetails) bson.M {
 
 func NoExists(coll *mongo.Collection, ctx context.Context, id int) bool {
 	var results bson.M
-	err := coll.FindOne(ctx, bson.D{{"_id", id}}).Decode(&results)
+	err := coll.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&results)
 	return err == mongo.ErrNoDocuments
 }
 
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+)
+
+// Cache stores composed user profiles by user id. Implementations must be
+// safe for concurrent use; a miss and a backend failure look the same to
+// callers, who fall back to the gRPC services.
+type Cache interface {
+	Get(ctx context.Context, id int) (entities.User, bool)
+	Set(ctx context.Context, id int, user entities.User)
+	Delete(ctx context.Context, id int)
+}
+
+type entry struct {
+	id      int
+	user    entities.User
+	expires time.Time
+}
+
+type lru struct {
+	size int
+	ttl  time.Duration
+
+	mtx     sync.Mutex
+	order   *list.List
+	entries map[int]*list.Element
+}
+
+func NewLRU(size int, ttl time.Duration) Cache {
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[int]*list.Element, size),
+	}
+}
+
+func (c *lru) Get(_ context.Context, id int) (entities.User, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	el, ok := c.entries[id]
+	if !ok {
+		return entities.User{}, false
+	}
+
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return entities.User{}, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.user, true
+}
+
+func (c *lru) Set(_ context.Context, id int, user entities.User) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if el, ok := c.entries[id]; ok {
+		e := el.Value.(*entry)
+		e.user, e.expires = user, time.Now().Add(c.ttl)
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(&entry{id: id, user: user, expires: time.Now().Add(c.ttl)})
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *lru) Delete(_ context.Context, id int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if el, ok := c.entries[id]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *lru) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry).id)
+}
@@ -0,0 +1,65 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/http_srv/cache"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEviction(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	c := cache.NewLRU(2, time.Minute)
+
+	// act
+	c.Set(ctx, 1, entities.User{Email: "one@email.com"})
+	c.Set(ctx, 2, entities.User{Email: "two@email.com"})
+	c.Get(ctx, 1)
+	c.Set(ctx, 3, entities.User{Email: "three@email.com"})
+	_, one := c.Get(ctx, 1)
+	_, two := c.Get(ctx, 2)
+	three, ok := c.Get(ctx, 3)
+
+	// assert
+	assert.True(one)
+	assert.False(two)
+	assert.True(ok)
+	assert.Equal("three@email.com", three.Email)
+}
+
+func TestLRUExpiration(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	c := cache.NewLRU(2, 10*time.Millisecond)
+	c.Set(ctx, 1, entities.User{Email: "one@email.com"})
+
+	// act
+	_, fresh := c.Get(ctx, 1)
+	time.Sleep(20 * time.Millisecond)
+	_, expired := c.Get(ctx, 1)
+
+	// assert
+	assert.True(fresh)
+	assert.False(expired)
+}
+
+func TestLRUDelete(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	c := cache.NewLRU(2, time.Minute)
+	c.Set(ctx, 1, entities.User{Email: "one@email.com"})
+
+	// act
+	c.Delete(ctx, 1)
+	_, ok := c.Get(ctx, 1)
+
+	// assert
+	assert.False(ok)
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	LeastRequest = "least_request"
+	RoundRobin   = "round_robin"
+
+	dnsScheme    = "dns"
+	staticScheme = "static"
+)
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(LeastRequest, &leastRequestBuilder{}, base.Config{HealthCheck: true}))
+	resolver.Register(&staticBuilder{})
+}
+
+// Target builds the dial target for a backend. With the "dns" resolver the
+// single host is resolved and every A record becomes an endpoint; with
+// "static" the addresses are used as given.
+func Target(resolver_name string, hosts []string, port int) (string, error) {
+	if len(hosts) == 0 {
+		return "", fmt.Errorf("client: no hosts to dial")
+	}
+
+	addrs := make([]string, len(hosts))
+	for i, h := range hosts {
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, strconv.Itoa(port))
+		}
+		addrs[i] = h
+	}
+
+	switch resolver_name {
+	case staticScheme:
+		return staticScheme + ":///" + strings.Join(addrs, ","), nil
+	case dnsScheme:
+		if len(addrs) > 1 {
+			return "", fmt.Errorf("client: the dns resolver takes one host, not %d; use the static resolver for a list", len(addrs))
+		}
+		return dnsScheme + ":///" + addrs[0], nil
+	default:
+		return "", fmt.Errorf("client: unknown resolver %q, want %q or %q", resolver_name, dnsScheme, staticScheme)
+	}
+}
+
+// Balancing picks the load balancing policy and, when service is not
+// empty, drops endpoints whose grpc.health.v1 status is not SERVING.
+func Balancing(policy string, service string) grpc.DialOption {
+	health := ""
+	if service != "" {
+		health = fmt.Sprintf(`,"healthCheckConfig":{"serviceName":%q}`, service)
+	}
+	return grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]%s}`, policy, health))
+}
+
+type leastRequestBuilder struct{}
+
+func (*leastRequestBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	scs := make([]*subConn, 0, len(info.ReadySCs))
+	for sc := range info.ReadySCs {
+		scs = append(scs, &subConn{sc: sc})
+	}
+	return &leastRequestPicker{scs: scs}
+}
+
+type subConn struct {
+	sc       balancer.SubConn
+	inflight int64
+}
+
+// leastRequestPicker samples two ready endpoints and sends the call to the
+// one with fewer requests in flight.
+type leastRequestPicker struct {
+	scs []*subConn
+}
+
+func (p *leastRequestPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	pick := p.scs[rand.Intn(len(p.scs))]
+	if len(p.scs) > 1 {
+		other := p.scs[rand.Intn(len(p.scs))]
+		if atomic.LoadInt64(&other.inflight) < atomic.LoadInt64(&pick.inflight) {
+			pick = other
+		}
+	}
+
+	atomic.AddInt64(&pick.inflight, 1)
+	return balancer.PickResult{
+		SubConn: pick.sc,
+		Done: func(balancer.DoneInfo) {
+			atomic.AddInt64(&pick.inflight, -1)
+		},
+	}, nil
+}
+
+type staticBuilder struct{}
+
+func (*staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	var addrs []resolver.Address
+	for _, a := range strings.Split(strings.TrimPrefix(target.URL.Path, "/"), ",") {
+		if a != "" {
+			addrs = append(addrs, resolver.Address{Addr: a})
+		}
+	}
+
+	if err := cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		return nil, err
+	}
+	return staticResolver{}, nil
+}
+
+func (*staticBuilder) Scheme() string {
+	return staticScheme
+}
+
+type staticResolver struct{}
+
+func (staticResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (staticResolver) Close() {}
@@ -0,0 +1,136 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/http_srv/client"
+	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type replica struct {
+	userpb.UnimplementedUserServiceServer
+	calls chan struct{}
+}
+
+func (r *replica) GetUser(context.Context, *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	r.calls <- struct{}{}
+	return &userpb.GetUserResponse{}, nil
+}
+
+func startReplica(t *testing.T) (string, *replica, *grpc_health.Server) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &replica{calls: make(chan struct{}, 100)}
+	health_srv := grpc_health.NewServer()
+	health_srv.SetServingStatus("UserService", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	server := grpc.NewServer()
+	userpb.RegisterUserServiceServer(server, r)
+	grpc_health_v1.RegisterHealthServer(server, health_srv)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String(), r, health_srv
+}
+
+func TestTarget(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		resolver  string
+		hosts     []string
+		target    string
+		err       bool
+	}{
+		{
+			test_name: "dns with default port",
+			resolver:  "dns",
+			hosts:     []string{"user"},
+			target:    "dns:///user:50051",
+		},
+		{
+			test_name: "static list with mixed ports",
+			resolver:  "static",
+			hosts:     []string{"user1", "user2:6000"},
+			target:    "static:///user1:50051,user2:6000",
+		},
+		{
+			test_name: "dns with several hosts",
+			resolver:  "dns",
+			hosts:     []string{"user1", "user2"},
+			err:       true,
+		},
+		{
+			test_name: "unknown resolver",
+			resolver:  "consul",
+			hosts:     []string{"user"},
+			err:       true,
+		},
+		{
+			test_name: "no hosts",
+			resolver:  "static",
+			err:       true,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// act
+			target, err := client.Target(tc.resolver, tc.hosts, 50051)
+
+			// assert
+			assert.Equal(t, tc.target, target)
+			assert.Equal(t, tc.err, err != nil)
+		})
+	}
+}
+
+func TestBalancing(t *testing.T) {
+	for _, policy := range []string{client.RoundRobin, client.LeastRequest} {
+		t.Run(policy, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			addr1, replica1, _ := startReplica(t)
+			addr2, replica2, health2 := startReplica(t)
+
+			target, _ := client.Target("static", []string{addr1, addr2}, 0)
+			conn, err := grpc.Dial(target, grpc.WithInsecure(), client.Balancing(policy, "UserService"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			user_client := userpb.NewUserServiceClient(conn)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			// act
+			for len(replica1.calls) == 0 || len(replica2.calls) == 0 {
+				if _, err := user_client.GetUser(ctx, &userpb.GetUserRequest{}, grpc.WaitForReady(true)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			health2.SetServingStatus("UserService", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+			time.Sleep(100 * time.Millisecond)
+			for len(replica2.calls) > 0 {
+				<-replica2.calls
+			}
+			for i := 0; i < 20; i++ {
+				user_client.GetUser(ctx, &userpb.GetUserRequest{}, grpc.WaitForReady(true))
+			}
+
+			// assert
+			assert.Equal(0, len(replica2.calls))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errOpen = errors.New(codes.Unavailable, errors.ReasonCircuitOpen, "circuit breaker is open")
+
+type BreakerSettings struct {
+	Failures uint32
+	Timeout  time.Duration
+}
+
+type Breaker struct {
+	cb   *gobreaker.CircuitBreaker
+	open *errors.Error
+}
+
+// NewBreaker trips after s.Failures consecutive backend failures and lets a
+// probe call through once s.Timeout has elapsed. The gauge tracks the state
+// labelled by backend: 0 closed, 1 half-open, 2 open.
+func NewBreaker(backend string, s BreakerSettings, state metrics.Gauge) *Breaker {
+	state = state.With("backend", backend)
+	state.Set(float64(gobreaker.StateClosed))
+
+	// callers are told to come back once the breaker lets a probe through
+	open := *errOpen
+	open.RetryAfter = s.Timeout
+
+	return &Breaker{
+		open: &open,
+		cb: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    backend,
+			Timeout: s.Timeout,
+			ReadyToTrip: func(c gobreaker.Counts) bool {
+				return c.ConsecutiveFailures >= s.Failures
+			},
+			IsSuccessful: func(err error) bool {
+				return err == nil || !backendFailure(err)
+			},
+			OnStateChange: func(_ string, _, to gobreaker.State) {
+				state.Set(float64(to))
+			},
+		}),
+	}
+}
+
+func (b *Breaker) Name() string {
+	return b.cb.Name()
+}
+
+func (b *Breaker) State() gobreaker.State {
+	return b.cb.State()
+}
+
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, err := b.cb.Execute(func() (interface{}, error) {
+			return nil, invoker(ctx, method, req, reply, cc, opts...)
+		})
+
+		if err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
+			return b.open
+		}
+
+		return err
+	}
+}
+
+// Business errors such as NotFound or FailedPrecondition mean the backend
+// is healthy and must not count towards tripping the breaker.
+func backendFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+func BreakersHandler(breakers ...*Breaker) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		res := make(map[string]interface{}, len(breakers))
+
+		for _, b := range breakers {
+			counts := b.cb.Counts()
+			res[b.Name()] = map[string]interface{}{
+				"state":                 b.State().String(),
+				"requests":              counts.Requests,
+				"consecutive_failures":  counts.ConsecutiveFailures,
+				"consecutive_successes": counts.ConsecutiveSuccesses,
+			}
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(res)
+	})
+}
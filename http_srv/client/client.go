@@ -0,0 +1,16 @@
+package client
+
+import (
+	"google.golang.org/grpc"
+)
+
+// Interceptors chains the client middleware for one backend. Retries sit
+// outermost so every attempt passes through the breaker and gets its own
+// deadline.
+func Interceptors(b *Breaker, r RetryPolicy, d Deadlines) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(
+		Retry(r),
+		b.UnaryClientInterceptor(),
+		Deadline(d),
+	)
+}
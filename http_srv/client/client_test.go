@@ -0,0 +1,135 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/mauricioww/user_microsrv/http_srv/client"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func failingInvoker(calls *int, err error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		return err
+	}
+}
+
+func TestRetry(t *testing.T) {
+	retry := client.Retry(client.RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+
+	test_cases := []struct {
+		test_name string
+		method    string
+		err       error
+		calls     int
+	}{
+		{
+			test_name: "idempotent call retried on unavailable",
+			method:    "/UserService/GetUser",
+			err:       status.Error(codes.Unavailable, "connection refused"),
+			calls:     3,
+		},
+		{
+			test_name: "non idempotent call not retried",
+			method:    "/UserService/CreateUser",
+			err:       status.Error(codes.Unavailable, "connection refused"),
+			calls:     1,
+		},
+		{
+			test_name: "business error not retried",
+			method:    "/UserService/GetUser",
+			err:       status.Error(codes.NotFound, "User not found"),
+			calls:     1,
+		},
+		{
+			test_name: "success not retried",
+			method:    "/UserDetailsService/GetUserDetails",
+			calls:     1,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			calls := 0
+
+			// act
+			err := retry(context.Background(), tc.method, nil, nil, nil, failingInvoker(&calls, tc.err))
+
+			// assert
+			assert.Equal(tc.calls, calls)
+			assert.Equal(tc.err, err)
+		})
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	deadline := client.Deadline(client.Deadlines{
+		Default: time.Hour,
+		Methods: map[string]time.Duration{"/UserService/GetUser": time.Second},
+	})
+	var remaining time.Duration
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		d, _ := ctx.Deadline()
+		remaining = time.Until(d)
+		return nil
+	}
+
+	// act
+	deadline(context.Background(), "/UserService/GetUser", nil, nil, nil, invoker)
+
+	// assert
+	assert.True(remaining > 0 && remaining <= time.Second)
+}
+
+func TestBreaker(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	state := generic.NewGauge("breaker_state")
+	breaker := client.NewBreaker("user", client.BreakerSettings{Failures: 2, Timeout: time.Minute}, state)
+	interceptor := breaker.UnaryClientInterceptor()
+	calls := 0
+	not_found := failingInvoker(&calls, status.Error(codes.NotFound, "User not found"))
+	unavailable := failingInvoker(&calls, status.Error(codes.Unavailable, "connection refused"))
+
+	// act
+	interceptor(context.Background(), "/UserService/GetUser", nil, nil, nil, not_found)
+	interceptor(context.Background(), "/UserService/GetUser", nil, nil, nil, not_found)
+	closed := breaker.State()
+	interceptor(context.Background(), "/UserService/GetUser", nil, nil, nil, unavailable)
+	interceptor(context.Background(), "/UserService/GetUser", nil, nil, nil, unavailable)
+	err := interceptor(context.Background(), "/UserService/GetUser", nil, nil, nil, unavailable)
+
+	// assert
+	assert.Equal(gobreaker.StateClosed, closed)
+	assert.Equal(gobreaker.StateOpen, breaker.State())
+	assert.Equal(codes.Unavailable, status.Code(err))
+	assert.Equal(4, calls)
+}
+
+func TestBreakersHandler(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	breaker := client.NewBreaker("details", client.BreakerSettings{Failures: 1, Timeout: time.Minute}, generic.NewGauge("breaker_state"))
+	rec := httptest.NewRecorder()
+
+	// act
+	client.BreakersHandler(breaker).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/breakers", nil))
+
+	// assert
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Contains(rec.Body.String(), `"details":{`)
+	assert.Contains(rec.Body.String(), `"state":"closed"`)
+}
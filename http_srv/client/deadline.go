@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+type Deadlines struct {
+	Default time.Duration
+	Methods map[string]time.Duration
+}
+
+// Deadline bounds every attempt, so a retried call gets a fresh budget
+// while the caller's own deadline still caps the total.
+func Deadline(d Deadlines) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		timeout, ok := d.Methods[method]
+		if !ok {
+			timeout = d.Default
+		}
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Only calls that can be repeated without side effects are retried.
+// CreateUser and the deletes are excluded: a retry after a lost response
+// would duplicate the user or report NotFound for a successful delete.
+var idempotent = map[string]bool{
+	"/UserService/Authenticate":           true,
+	"/UserService/GetUser":                true,
+	"/UserService/GetUsers":               true,
+	"/UserService/UpdateUser":             true,
+	"/UserDetailsService/GetUserDetails":  true,
+	"/UserDetailsService/GetUsersDetails": true,
+	"/UserDetailsService/SetUserDetails":  true,
+}
+
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func Retry(p RetryPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		if !idempotent[method] {
+			return err
+		}
+
+		for attempt := 1; attempt < p.Attempts && retryable(ctx, err); attempt++ {
+			select {
+			case <-time.After(p.backoff(attempt)):
+			case <-ctx.Done():
+				return err
+			}
+			err = invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		return err
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff << uint(attempt-1)
+	if d <= 0 || d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || stderrors.Is(err, errOpen) {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
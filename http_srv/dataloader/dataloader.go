@@ -0,0 +1,168 @@
+// Package dataloader batches and caches lookups by id for the lifetime of a
+// single request, in the manner of the dataloader pattern.
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var errNoResult = errors.New("dataloader: no result for key")
+
+type (
+	// BatchFunc resolves keys in one go; the i-th result belongs to the i-th
+	// key.
+	BatchFunc func(ctx context.Context, keys []int) []Result
+
+	Result struct {
+		Value interface{}
+		Err   error
+	}
+
+	// Loader collects the keys asked for within wait of each other, or until
+	// max of them are pending, and resolves them with a single call to fetch.
+	// Every result is remembered, so a key is fetched at most once.
+	Loader struct {
+		ctx   context.Context
+		fetch BatchFunc
+		wait  time.Duration
+		max   int
+
+		mu      sync.Mutex
+		entries map[int]*entry
+		pending *batch
+	}
+
+	entry struct {
+		done chan struct{}
+		Result
+	}
+
+	batch struct {
+		keys    []int
+		entries []*entry
+		sent    bool
+	}
+)
+
+// New returns a loader for the request ctx belongs to; batches run with ctx
+// rather than the context of whichever caller opened them.
+func New(ctx context.Context, fetch BatchFunc, wait time.Duration, max int) *Loader {
+	return &Loader{
+		ctx:     ctx,
+		fetch:   fetch,
+		wait:    wait,
+		max:     max,
+		entries: map[int]*entry{},
+	}
+}
+
+// Load waits for the value of key, joining the pending batch if needed.
+func (l *Loader) Load(ctx context.Context, key int) (interface{}, error) {
+	return l.LoadMany(ctx, []int{key})[0].unpack()
+}
+
+// LoadMany loads keys together and returns their results in the same order.
+func (l *Loader) LoadMany(ctx context.Context, keys []int) []Result {
+	entries := make([]*entry, len(keys))
+
+	l.mu.Lock()
+	for i, key := range keys {
+		e, ok := l.entries[key]
+		if !ok {
+			e = &entry{done: make(chan struct{})}
+			l.entries[key] = e
+			l.enqueue(key, e)
+		}
+		entries[i] = e
+	}
+	l.mu.Unlock()
+
+	res := make([]Result, len(keys))
+	for i, e := range entries {
+		select {
+		case <-e.done:
+			res[i] = e.Result
+		case <-ctx.Done():
+			res[i].Err = ctx.Err()
+		}
+	}
+	return res
+}
+
+func (r Result) unpack() (interface{}, error) {
+	return r.Value, r.Err
+}
+
+// Prime remembers value for key unless it is already known, as when a
+// mutation or a search has just returned it.
+func (l *Loader) Prime(key int, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.entries[key]; ok {
+		return
+	}
+	e := &entry{done: make(chan struct{}), Result: Result{Value: value}}
+	close(e.done)
+	l.entries[key] = e
+}
+
+// enqueue must be called with mu held.
+func (l *Loader) enqueue(key int, e *entry) {
+	if l.pending == nil {
+		b := &batch{}
+		l.pending = b
+		time.AfterFunc(l.wait, func() { l.dispatch(b) })
+	}
+
+	b := l.pending
+	b.keys = append(b.keys, key)
+	b.entries = append(b.entries, e)
+
+	if l.max > 0 && len(b.keys) >= l.max {
+		l.pending = nil
+		b.sent = true
+		go l.run(b)
+	}
+}
+
+func (l *Loader) dispatch(b *batch) {
+	l.mu.Lock()
+	if b.sent {
+		l.mu.Unlock()
+		return
+	}
+	b.sent = true
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	l.run(b)
+}
+
+// run never leaves a caller waiting: keys fetch gives no result for, or
+// all of them if it panics, fail instead.
+func (l *Loader) run(b *batch) {
+	var res []Result
+	defer func() {
+		p := recover()
+		for i, e := range b.entries {
+			switch {
+			case p != nil:
+				e.Err = fmt.Errorf("dataloader: batch panicked: %v", p)
+			case i < len(res):
+				e.Result = res[i]
+			default:
+				e.Err = errNoResult
+			}
+			close(e.done)
+		}
+	}()
+
+	res = l.fetch(l.ctx, b.keys)
+}
@@ -0,0 +1,164 @@
+package dataloader_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/http_srv/dataloader"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *recorder) fetch(ctx context.Context, keys []int) []dataloader.Result {
+	r.mu.Lock()
+	r.batches = append(r.batches, append([]int(nil), keys...))
+	r.mu.Unlock()
+
+	res := make([]dataloader.Result, len(keys))
+	for i, key := range keys {
+		if key < 0 {
+			res[i].Err = errors.New("negative key")
+		} else {
+			res[i].Value = key * 10
+		}
+	}
+	return res
+}
+
+func TestLoader(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		max       int
+		keys      []int
+		batches   [][]int
+	}{
+		{
+			test_name: "concurrent loads share a batch",
+			keys:      []int{1, 2, 3},
+			batches:   [][]int{{1, 2, 3}},
+		},
+		{
+			test_name: "repeated keys are fetched once",
+			keys:      []int{1, 1, 2, 2},
+			batches:   [][]int{{1, 2}},
+		},
+		{
+			test_name: "full batches go out at once",
+			max:       2,
+			keys:      []int{1, 2, 3},
+			batches:   [][]int{{1, 2}, {3}},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			ctx := context.Background()
+			r := &recorder{}
+			loader := dataloader.New(ctx, r.fetch, 20*time.Millisecond, tc.max)
+
+			// act
+			res := loader.LoadMany(ctx, tc.keys)
+
+			// assert
+			for i, key := range tc.keys {
+				assert.Nil(res[i].Err)
+				assert.Equal(key*10, res[i].Value)
+			}
+			assert.Len(r.batches, len(tc.batches))
+			for _, b := range tc.batches {
+				assert.Contains(r.batches, b)
+			}
+		})
+	}
+}
+
+func TestLoaderCache(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	r := &recorder{}
+	loader := dataloader.New(ctx, r.fetch, time.Millisecond, 0)
+	loader.Prime(7, 700)
+
+	// act
+	first, first_err := loader.Load(ctx, 1)
+	again, again_err := loader.Load(ctx, 1)
+	primed, primed_err := loader.Load(ctx, 7)
+	_, failed := loader.Load(ctx, -1)
+
+	// assert
+	assert.Nil(first_err)
+	assert.Nil(again_err)
+	assert.Nil(primed_err)
+	assert.Equal(10, first)
+	assert.Equal(10, again)
+	assert.Equal(700, primed)
+	assert.EqualError(failed, "negative key")
+	assert.Equal([][]int{{1}, {-1}}, r.batches)
+}
+
+func TestLoaderFailures(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		fetch     dataloader.BatchFunc
+		err       string
+	}{
+		{
+			test_name: "missing result",
+			fetch: func(context.Context, []int) []dataloader.Result {
+				return nil
+			},
+			err: "dataloader: no result for key",
+		},
+		{
+			test_name: "panicking batch",
+			fetch: func(context.Context, []int) []dataloader.Result {
+				panic("boom")
+			},
+			err: "dataloader: batch panicked: boom",
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			ctx := context.Background()
+			loader := dataloader.New(ctx, tc.fetch, time.Millisecond, 0)
+
+			// act
+			_, err := loader.Load(ctx, 1)
+
+			// assert
+			assert.EqualError(err, tc.err)
+		})
+	}
+}
+
+func TestLoaderCanceled(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	release := make(chan struct{})
+	defer close(release)
+	loader := dataloader.New(context.Background(), func(ctx context.Context, keys []int) []dataloader.Result {
+		<-release
+		return make([]dataloader.Result, len(keys))
+	}, time.Millisecond, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// act
+	_, err := loader.Load(ctx, 1)
+
+	// assert
+	assert.Equal(context.DeadlineExceeded, err)
+}
@@ -26,4 +26,51 @@ type (
 		UserId int
 		User
 	}
+
+	Account struct {
+		Id    int
+		Email string
+		Age   int
+	}
+
+	// DetailsPatch holds the details fields a client sent; the others are
+	// nil and left as they are.
+	DetailsPatch struct {
+		Country      *string  `json:"country"`
+		City         *string  `json:"city"`
+		MobileNumber *string  `json:"mobile_number"`
+		Married      *bool    `json:"married"`
+		Height       *float32 `json:"height_m"`
+		Weight       *float32 `json:"weight_kg"`
+	}
+
+	AccountUpdate struct {
+		UserId   int
+		Email    string
+		Password string
+		Age      int
+	}
 )
+
+// Apply returns details with the fields of p replaced.
+func (p DetailsPatch) Apply(details Details) Details {
+	if p.Country != nil {
+		details.Country = *p.Country
+	}
+	if p.City != nil {
+		details.City = *p.City
+	}
+	if p.MobileNumber != nil {
+		details.MobileNumber = *p.MobileNumber
+	}
+	if p.Married != nil {
+		details.Married = *p.Married
+	}
+	if p.Height != nil {
+		details.Height = *p.Height
+	}
+	if p.Weight != nil {
+		details.Weight = *p.Weight
+	}
+	return details
+}
@@ -3,18 +3,31 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
 	"github.com/caarlos0/env/v6"
-	"github.com/go-kit/kit/log"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/log/level"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/mauricioww/user_microsrv/admin"
+	"github.com/mauricioww/user_microsrv/health"
+	"github.com/mauricioww/user_microsrv/http_srv/cache"
+	"github.com/mauricioww/user_microsrv/http_srv/client"
+	"github.com/mauricioww/user_microsrv/http_srv/gatewaypb"
 	"github.com/mauricioww/user_microsrv/http_srv/repository"
 	"github.com/mauricioww/user_microsrv/http_srv/service"
 	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+	"github.com/mauricioww/user_microsrv/lifecycle"
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/mauricioww/user_microsrv/recovery"
+	"github.com/mauricioww/user_microsrv/tracing"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
@@ -24,74 +37,383 @@ func main() {
 		fmt.Printf("%+v\n", err)
 	}
 
-	var logger log.Logger
-	{
-		logger = log.NewLogfmtLogger(os.Stderr)
-		logger = log.NewSyncLogger(logger)
-		logger = log.With(
-			logger,
-			"service",
-			"HTTP_SRV",
-			"time",
-			log.DefaultTimestampUTC,
-			"caller",
-			log.DefaultCaller,
-		)
+	logger, err := logging.New(os.Stderr, "HTTP_SRV", logging.Config{Format: cts.LogFormat, Level: cts.LogLevel})
+	if err != nil {
+		fmt.Printf("%+v\n", err)
+		os.Exit(-1)
 	}
 
 	level.Info(logger).Log("mesg", "service started")
 
 	defer level.Info(logger).Log("msg", "service ended")
 
+	shutdown_tracing, err := tracing.Init(context.Background(), "http_srv", tracingConfig(cts))
+	if err != nil {
+		level.Error(logger).Log("exit", err)
+		os.Exit(-1)
+	}
+
+	var user_breaker, details_breaker *client.Breaker
+	{
+		breaker_state := kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "http_srv",
+			Subsystem: "grpc_client",
+			Name:      "breaker_state",
+			Help:      "Circuit breaker state per backend (0 closed, 1 half-open, 2 open).",
+		}, []string{"backend"})
+
+		settings := client.BreakerSettings{Failures: cts.BreakerFailures, Timeout: cts.BreakerTimeout}
+		user_breaker = client.NewBreaker("user", settings, breaker_state)
+		details_breaker = client.NewBreaker("details", settings, breaker_state)
+	}
+
+	retry := client.RetryPolicy{
+		Attempts:   cts.GrpcAttempts,
+		Backoff:    cts.GrpcBackoff,
+		MaxBackoff: cts.GrpcMaxBackoff,
+	}
+	deadlines := client.Deadlines{
+		Default: cts.GrpcWriteTimeout,
+		Methods: map[string]time.Duration{
+			"/UserService/Authenticate":           cts.GrpcReadTimeout,
+			"/UserService/GetUser":                cts.GrpcReadTimeout,
+			"/UserService/GetUsers":               cts.GrpcReadTimeout,
+			"/UserService/SearchUsers":            cts.GrpcReadTimeout,
+			"/UserDetailsService/GetUserDetails":  cts.GrpcReadTimeout,
+			"/UserDetailsService/GetUsersDetails": cts.GrpcReadTimeout,
+		},
+	}
+
 	var user_grpc, details_grpc *grpc.ClientConn
 	var grpc_err error
 	{
 		// user_grpc
-		user_addr := fmt.Sprintf("%v:%v", cts.UserHost, cts.DetailsPort)
-		user_grpc, grpc_err = grpc.Dial(user_addr, grpc.WithInsecure())
+		user_target, err := client.Target(cts.GrpcResolver, cts.UserHosts, cts.UserPort)
+		if err != nil {
+			level.Error(logger).Log("gRPC", err)
+			os.Exit(-1)
+		}
+		user_grpc, grpc_err = grpc.Dial(user_target, grpc.WithInsecure(), client.Interceptors(user_breaker, retry, deadlines),
+			grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor(), tracing.UnaryClientInterceptor(), grpc_prometheus.UnaryClientInterceptor),
+			client.Balancing(cts.GrpcLbPolicy, healthService(cts, "UserService")))
 		if grpc_err != nil {
 			level.Error(logger).Log("gRPC", grpc_err)
 			os.Exit(-1)
 		}
 
 		// details_grpc
-		details_addr := fmt.Sprintf("%v:%v", cts.DetailsHost, cts.DetailsPort)
-		details_grpc, grpc_err = grpc.Dial(details_addr, grpc.WithInsecure())
+		details_target, err := client.Target(cts.GrpcResolver, cts.DetailsHosts, cts.DetailsPort)
+		if err != nil {
+			level.Error(logger).Log("gRPC", err)
+			os.Exit(-1)
+		}
+		details_grpc, grpc_err = grpc.Dial(details_target, grpc.WithInsecure(), client.Interceptors(details_breaker, retry, deadlines),
+			grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor(), tracing.UnaryClientInterceptor(), grpc_prometheus.UnaryClientInterceptor),
+			client.Balancing(cts.GrpcLbPolicy, healthService(cts, "UserDetailsService")))
 		if grpc_err != nil {
 			level.Error(logger).Log("gRPC", grpc_err)
 			os.Exit(-1)
 		}
 	}
 
+	lc := lifecycle.New(lifecycle.Config{Timeout: cts.ShutdownTimeout, DrainDelay: cts.ShutdownDelay}, logger)
+	monitor := health.NewMonitor(cts.HealthInterval, cts.HealthTimeout, lc.Ready, logger)
+	{
+		monitor.AddCheck("user", health.GrpcCheck(user_grpc, "UserService"))
+		monitor.AddCheck("details", health.GrpcCheck(details_grpc, "UserDetailsService"))
+		health_ctx, stop_health := context.WithCancel(context.Background())
+		go monitor.Run(health_ctx)
+		lc.AddCloser("health", func(context.Context) error {
+			stop_health()
+			return nil
+		})
+	}
+
+	lc.AddCloser("user_grpc", func(context.Context) error {
+		return user_grpc.Close()
+	})
+	lc.AddCloser("details_grpc", func(context.Context) error {
+		return details_grpc.Close()
+	})
+
+	dispatcher := webhook.NewDispatcher(webhook.NewMemoryStore(cts.WebhookLogSize), webhook.Config{
+		MaxAttempts: cts.WebhookMaxAttempts,
+		Backoff:     cts.WebhookBackoff,
+		MaxBackoff:  cts.WebhookMaxBackoff,
+		Timeout:     cts.WebhookTimeout,
+		Poll:        cts.WebhookPoll,
+		Workers:     cts.WebhookWorkers,
+	}, logger)
+	{
+		webhook_ctx, stop_webhooks := context.WithCancel(context.Background())
+		go dispatcher.Run(webhook_ctx)
+		lc.AddCloser("webhooks", func(context.Context) error {
+			stop_webhooks()
+			return nil
+		})
+	}
+
 	ctx := context.Background()
 	var http_srv service.HttpService
 	{
-		repository := repository.NewHttpRepository(user_grpc, details_grpc, logger)
-		http_srv = service.NewHttpService(repository, logger)
-	}
+		http_repository := repository.NewHttpRepository(user_grpc, details_grpc, logger)
+
+		if cts.CacheSize > 0 {
+			hits := kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "http_srv",
+				Subsystem: "user_cache",
+				Name:      "hits_total",
+				Help:      "Profile lookups served from the cache.",
+			}, []string{})
+			misses := kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "http_srv",
+				Subsystem: "user_cache",
+				Name:      "misses_total",
+				Help:      "Profile lookups fetched from the gRPC services.",
+			}, []string{})
+			http_repository = repository.NewCachedHttpRepository(http_repository, cache.NewLRU(cts.CacheSize, cts.CacheTTL), hits, misses)
+		}
 
-	err := make(chan error)
+		http_srv = service.NewHttpService(http_repository, logger)
+		http_srv = service.WebhookMiddleware(dispatcher)(http_srv)
 
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		err <- fmt.Errorf("%s", <-c)
-	}()
+		fields := []string{"method"}
+		http_srv = service.InstrumentingMiddleware(service.Metrics{
+			Requests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "http_srv",
+				Subsystem: "service",
+				Name:      "requests_total",
+				Help:      "Number of requests received.",
+			}, fields),
+			Errors: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "http_srv",
+				Subsystem: "service",
+				Name:      "errors_total",
+				Help:      "Number of failed requests by error code.",
+			}, []string{"method", "code"}),
+			Latency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "http_srv",
+				Subsystem: "service",
+				Name:      "request_duration_seconds",
+				Help:      "Request latency in seconds.",
+			}, fields),
+		})(http_srv)
+	}
 
 	http_endpoints := transport.MakeHttpEndpoints(http_srv)
 
-	go func() {
-		fmt.Println("Listengin on port: 8080")
-		http_handler := transport.NewHTTPServer(ctx, http_endpoints)
-		err <- http.ListenAndServe(":8080", http_handler)
-	}()
+	http_options := []transport.ServerOption{transport.WithGraphQL(http_srv), transport.WithV1Sunset(cts.V1Sunset), transport.WithMaxBodyBytes(cts.HttpMaxBodyBytes), transport.WithWebhooks(dispatcher)}
+	gateway_interceptors := []grpc.UnaryServerInterceptor{logging.UnaryServerInterceptor(), recovery.UnaryServerInterceptor(logger), tracing.UnaryServerInterceptor(), grpc_prometheus.UnaryServerInterceptor}
+	http_options = append(http_options, transport.WithSecurityHeaders(securityConfig(cts)))
+	if cors := corsConfig(cts); len(cors.AllowedOrigins) > 0 {
+		if err := cors.Validate(); err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
+		}
+		http_options = append(http_options, transport.WithCORS(cors))
+	}
+	if cts.AuthRequired {
+		http_options = append(http_options, transport.WithAuthentication())
+		gateway_interceptors = append(gateway_interceptors, transport.GatewayAuthInterceptor())
+	}
+	if cts.OpenAPIValidation && cts.Environment != "production" {
+		level.Info(logger).Log("msg", "validating requests and responses against the OpenAPI document", "environment", cts.Environment)
+		http_options = append(http_options, transport.WithOpenAPIValidation())
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", transport.NewHTTPServer(ctx, http_endpoints, logger, http_options...))
+	mux.Handle("/healthz", monitor.LivenessHandler())
+	mux.Handle("/readyz", monitor.ReadinessHandler())
+	lc.AddHttpServer("http", httpServer(cts, ":8080", mux))
+
+	if cts.GatewayAddr != "" {
+		gateway_listener, err := net.Listen("tcp", cts.GatewayAddr)
+		if err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
+		}
+		gateway := grpc.NewServer(grpc.ChainUnaryInterceptor(gateway_interceptors...))
+		gatewaypb.RegisterUserGatewayServer(gateway, transport.NewGatewayServer(http_endpoints))
+		// reflection lists the gateway to anyone able to reach it, which is
+		// only wanted while debugging
+		if cts.Environment != "production" {
+			reflection.Register(gateway)
+		}
+		grpc_prometheus.Register(gateway)
+		lc.AddGrpcServer("gateway", gateway, gateway_listener)
+	}
+
+	if cts.AdminAddr != "" {
+		admin_mux := admin.NewMux()
+		admin_mux.Handle("/debug/breakers", client.BreakersHandler(user_breaker, details_breaker))
+		lc.AddHttpServer("admin", httpServer(cts, cts.AdminAddr, admin.Handler(admin_mux, cts.AdminToken)))
+	}
 
-	level.Error(logger).Log("exit: ", <-err)
+	if cts.MetricsAddr != "" {
+		lc.AddHttpServer("metrics", httpServer(cts, cts.MetricsAddr, admin.NewMetricsMux()))
+	}
+
+	if cts.AdminGrpcAddr != "" {
+		admin_listener, err := net.Listen("tcp", cts.AdminGrpcAddr)
+		if err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
+		}
+		lc.AddGrpcServer("admin_grpc", admin.NewGrpcServer(cts.AdminToken), admin_listener)
+	}
+	lc.AddCloser("tracing", shutdown_tracing)
+
+	level.Error(logger).Log("exit: ", lc.Run())
 }
 
 type constants struct {
-	UserHost    string `env:"USER_SERVER,required"`
-	UserPort    int    `env:"USER_PORT" envDefault:"50051"`
-	DetailsHost string `env:"DETAILS_SERVER,required"`
-	DetailsPort int    `env:"DETAILS_PORT" envDefault:"50051"`
+	Environment string `env:"ENVIRONMENT" envDefault:"production"`
+
+	LogFormat string `env:"LOG_FORMAT" envDefault:"logfmt"`
+	LogLevel  string `env:"LOG_LEVEL" envDefault:"info"`
+
+	// OpenAPIValidation is ignored in production: buffering and checking
+	// every response is a development aid.
+	OpenAPIValidation bool `env:"OPENAPI_VALIDATION" envDefault:"true"`
+
+	// AuthRequired guards both the /users routes and the gateway with the
+	// tokens /auth issues.
+	AuthRequired bool   `env:"AUTH_REQUIRED" envDefault:"false"`
+	GatewayAddr  string `env:"GATEWAY_ADDR" envDefault:":50051"`
+
+	// V1Sunset, RFC 3339, is announced on every v1 response once set.
+	V1Sunset time.Time `env:"V1_SUNSET"`
+
+	// The write timeout has to outlast the slowest request: GrpcAttempts
+	// writes of GrpcWriteTimeout each, plus their backoff.
+	HttpReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s"`
+	HttpReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"10s"`
+	HttpWriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"30s"`
+	HttpIdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"120s"`
+	HttpMaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" envDefault:"65536"`
+	HttpMaxBodyBytes      int64         `env:"HTTP_MAX_BODY_BYTES" envDefault:"1048576"`
+
+	// CorsAllowedOrigins left empty keeps browsers on other origins out in
+	// production and lets any in, without credentials, elsewhere. "*" with
+	// CorsAllowCredentials is refused at startup.
+	CorsAllowedOrigins   []string      `env:"CORS_ALLOWED_ORIGINS" envSeparator:","`
+	CorsAllowedMethods   []string      `env:"CORS_ALLOWED_METHODS" envSeparator:"," envDefault:"GET,POST,PUT,PATCH,DELETE"`
+	CorsAllowedHeaders   []string      `env:"CORS_ALLOWED_HEADERS" envSeparator:"," envDefault:"Accept,Authorization,Content-Type"`
+	CorsAllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS" envDefault:"false"`
+	CorsMaxAge           time.Duration `env:"CORS_MAX_AGE" envDefault:"10m"`
+
+	// HstsMaxAge has to be 0s wherever the API is served over plain HTTP;
+	// unset, it is a year in production and 0s elsewhere.
+	HstsMaxAge            *time.Duration `env:"HSTS_MAX_AGE"`
+	HstsIncludeSubdomains bool           `env:"HSTS_INCLUDE_SUBDOMAINS" envDefault:"false"`
+	FrameOptions          string         `env:"FRAME_OPTIONS" envDefault:"DENY"`
+	DocsCSP               string         `env:"DOCS_CSP"`
+
+	UserHosts    []string `env:"USER_SERVER,required" envSeparator:","`
+	UserPort     int      `env:"USER_PORT" envDefault:"50051"`
+	DetailsHosts []string `env:"DETAILS_SERVER,required" envSeparator:","`
+	DetailsPort  int      `env:"DETAILS_PORT" envDefault:"50051"`
+
+	GrpcResolver    string `env:"GRPC_RESOLVER" envDefault:"dns"`
+	GrpcLbPolicy    string `env:"GRPC_LB_POLICY" envDefault:"round_robin"`
+	GrpcHealthCheck bool   `env:"GRPC_HEALTH_CHECK" envDefault:"true"`
+
+	GrpcReadTimeout  time.Duration `env:"GRPC_READ_TIMEOUT" envDefault:"1s"`
+	GrpcWriteTimeout time.Duration `env:"GRPC_WRITE_TIMEOUT" envDefault:"3s"`
+	GrpcAttempts     int           `env:"GRPC_ATTEMPTS" envDefault:"3"`
+	GrpcBackoff      time.Duration `env:"GRPC_BACKOFF" envDefault:"50ms"`
+	GrpcMaxBackoff   time.Duration `env:"GRPC_MAX_BACKOFF" envDefault:"1s"`
+	BreakerFailures  uint32        `env:"BREAKER_FAILURES" envDefault:"5"`
+	BreakerTimeout   time.Duration `env:"BREAKER_TIMEOUT" envDefault:"30s"`
+
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+	ShutdownDelay   time.Duration `env:"SHUTDOWN_DELAY" envDefault:"0s"`
+
+	HealthInterval time.Duration `env:"HEALTH_INTERVAL" envDefault:"5s"`
+	HealthTimeout  time.Duration `env:"HEALTH_TIMEOUT" envDefault:"1s"`
+
+	CacheSize int           `env:"CACHE_SIZE" envDefault:"1000"`
+	CacheTTL  time.Duration `env:"CACHE_TTL" envDefault:"30s"`
+
+	// subscriptions and their delivery logs live in memory; WebhookLogSize
+	// deliveries are kept per subscription
+	WebhookMaxAttempts int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"5"`
+	WebhookBackoff     time.Duration `env:"WEBHOOK_BACKOFF" envDefault:"1s"`
+	WebhookMaxBackoff  time.Duration `env:"WEBHOOK_MAX_BACKOFF" envDefault:"5m"`
+	WebhookTimeout     time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	WebhookPoll        time.Duration `env:"WEBHOOK_POLL" envDefault:"1s"`
+	WebhookWorkers     int           `env:"WEBHOOK_WORKERS" envDefault:"4"`
+	WebhookLogSize     int           `env:"WEBHOOK_LOG_SIZE" envDefault:"1000"`
+
+	AdminAddr     string `env:"ADMIN_ADDR" envDefault:"127.0.0.1:9090"`
+	AdminGrpcAddr string `env:"ADMIN_GRPC_ADDR" envDefault:"127.0.0.1:9091"`
+	AdminToken    string `env:"ADMIN_TOKEN"`
+	// MetricsAddr serves /metrics apart from the admin listener, which is
+	// on loopback by default
+	MetricsAddr string `env:"METRICS_ADDR" envDefault:":9100"`
+
+	TraceExporter    string  `env:"TRACE_EXPORTER" envDefault:"none"`
+	TraceEndpoint    string  `env:"TRACE_ENDPOINT" envDefault:"localhost:4317"`
+	TraceInsecure    bool    `env:"TRACE_INSECURE" envDefault:"true"`
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO" envDefault:"1"`
+}
+
+func healthService(cts constants, service string) string {
+	if !cts.GrpcHealthCheck {
+		return ""
+	}
+	return service
+}
+
+// httpServer applies the timeouts and header limit to every HTTP server.
+func httpServer(cts constants, addr string, handler http.Handler) *http.Server {
+	return lifecycle.NewHttpServer(addr, handler, lifecycle.HttpConfig{
+		ReadHeaderTimeout: cts.HttpReadHeaderTimeout,
+		ReadTimeout:       cts.HttpReadTimeout,
+		WriteTimeout:      cts.HttpWriteTimeout,
+		IdleTimeout:       cts.HttpIdleTimeout,
+		MaxHeaderBytes:    cts.HttpMaxHeaderBytes,
+	})
+}
+
+// securityConfig leaves HSTS off outside production unless HSTS_MAX_AGE
+// says otherwise, development being served over plain HTTP.
+func securityConfig(cts constants) transport.SecurityConfig {
+	res := transport.SecurityConfig{
+		HSTSIncludeSubdomains: cts.HstsIncludeSubdomains,
+		FrameOptions:          cts.FrameOptions,
+		DocsCSP:               cts.DocsCSP,
+	}
+	if cts.HstsMaxAge != nil {
+		res.HSTSMaxAge = *cts.HstsMaxAge
+	} else if cts.Environment == "production" {
+		res.HSTSMaxAge = 365 * 24 * time.Hour
+	}
+	return res
+}
+
+// corsConfig lets any origin in outside production unless
+// CORS_ALLOWED_ORIGINS narrows it down.
+func corsConfig(cts constants) transport.CORSConfig {
+	origins := cts.CorsAllowedOrigins
+	if len(origins) == 0 && cts.Environment != "production" {
+		origins = []string{"*"}
+	}
+	return transport.CORSConfig{
+		AllowedOrigins:   origins,
+		AllowedMethods:   cts.CorsAllowedMethods,
+		AllowedHeaders:   cts.CorsAllowedHeaders,
+		AllowCredentials: cts.CorsAllowCredentials,
+		MaxAge:           cts.CorsMaxAge,
+	}
+}
+
+func tracingConfig(cts constants) tracing.Config {
+	return tracing.Config{
+		Exporter:    cts.TraceExporter,
+		Endpoint:    cts.TraceEndpoint,
+		Insecure:    cts.TraceInsecure,
+		SampleRatio: cts.TraceSampleRatio,
+	}
 }
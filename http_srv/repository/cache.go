@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/mauricioww/user_microsrv/http_srv/cache"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"golang.org/x/sync/singleflight"
+)
+
+// fetchTimeout bounds a coalesced fetch, which runs on behalf of every
+// caller waiting for it and so on none of their contexts.
+const fetchTimeout = 10 * time.Second
+
+type cachedRepository struct {
+	HttpRepository
+	cache  cache.Cache
+	group  singleflight.Group
+	hits   metrics.Counter
+	misses metrics.Counter
+
+	mtx sync.Mutex
+	// fetches are the ids being fetched, whose generation invalidate bumps
+	fetches map[int]*fetch
+}
+
+type fetch struct {
+	generation uint64
+	refs       int
+}
+
+// NewCachedHttpRepository serves GetUser from c and fetches misses through
+// next, coalescing concurrent misses for the same id into a single pair of
+// gRPC calls. Updates and deletes invalidate the cached profile.
+func NewCachedHttpRepository(next HttpRepository, c cache.Cache, hits metrics.Counter, misses metrics.Counter) HttpRepository {
+	return &cachedRepository{
+		HttpRepository: next,
+		cache:          c,
+		hits:           hits,
+		misses:         misses,
+		fetches:        make(map[int]*fetch),
+	}
+}
+
+func (r *cachedRepository) GetUser(ctx context.Context, id int) (entities.User, error) {
+	if u, ok := r.cache.Get(ctx, id); ok {
+		r.hits.Add(1)
+		return u, nil
+	}
+
+	r.misses.Add(1)
+
+	ch := r.group.DoChan(strconv.Itoa(id), func() (interface{}, error) {
+		fetch_ctx, cancel := context.WithTimeout(detached{ctx}, fetchTimeout)
+		defer cancel()
+
+		generation := r.startFetch(id)
+		u, err := r.HttpRepository.GetUser(fetch_ctx, id)
+		r.endFetch(fetch_ctx, id, generation, u, err)
+		return u, err
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val.(entities.User), res.Err
+	case <-ctx.Done():
+		return entities.User{}, ctx.Err()
+	}
+}
+
+func (r *cachedRepository) startFetch(id int) uint64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	f, ok := r.fetches[id]
+	if !ok {
+		f = &fetch{}
+		r.fetches[id] = f
+	}
+	f.refs++
+	return f.generation
+}
+
+// endFetch caches u unless id was invalidated since startFetch returned
+// generation: a user changed while it was being fetched may be stale, and
+// is left for the next miss.
+func (r *cachedRepository) endFetch(ctx context.Context, id int, generation uint64, u entities.User, err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	f := r.fetches[id]
+	f.refs--
+	if f.refs == 0 {
+		delete(r.fetches, id)
+	}
+	if err == nil && f.generation == generation {
+		r.cache.Set(ctx, id, u)
+	}
+}
+
+func (r *cachedRepository) UpdateUser(ctx context.Context, user entities.UserUpdate) (bool, error) {
+	defer r.invalidate(ctx, user.UserId)
+	return r.HttpRepository.UpdateUser(ctx, user)
+}
+
+func (r *cachedRepository) UpdateAccount(ctx context.Context, account entities.AccountUpdate) (bool, error) {
+	defer r.invalidate(ctx, account.UserId)
+	return r.HttpRepository.UpdateAccount(ctx, account)
+}
+
+func (r *cachedRepository) SetUserDetails(ctx context.Context, id int, details entities.Details) (bool, error) {
+	defer r.invalidate(ctx, id)
+	return r.HttpRepository.SetUserDetails(ctx, id, details)
+}
+
+func (r *cachedRepository) DeleteUserDetails(ctx context.Context, id int) (bool, error) {
+	defer r.invalidate(ctx, id)
+	return r.HttpRepository.DeleteUserDetails(ctx, id)
+}
+
+func (r *cachedRepository) DeleteUser(ctx context.Context, id int) (bool, error) {
+	defer r.invalidate(ctx, id)
+	return r.HttpRepository.DeleteUser(ctx, id)
+}
+
+func (r *cachedRepository) invalidate(ctx context.Context, id int) {
+	r.mtx.Lock()
+	if f, ok := r.fetches[id]; ok {
+		f.generation++
+	}
+	r.mtx.Unlock()
+
+	r.group.Forget(strconv.Itoa(id))
+	r.cache.Delete(ctx, id)
+}
+
+// detached keeps the values of a context, the request id and trace among
+// them, but not its deadline nor its cancellation.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/mauricioww/user_microsrv/http_srv/cache"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/repository"
+	"github.com/mauricioww/user_microsrv/user_details_srv/detailspb"
+	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedGetUser(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	details_mock := new(repository.GrpcDetailsMock)
+	conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+	defer conn1.Close()
+	defer conn2.Close()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	hits, misses := generic.NewCounter("hits"), generic.NewCounter("misses")
+	cached := repository.NewCachedHttpRepository(http_repository, cache.NewLRU(10, time.Minute), hits, misses)
+
+	user_mock.On("GetUser", mock.Anything, &userpb.GetUserRequest{Id: 1}).
+		Return(&userpb.GetUserResponse{Email: "email@domain.com", Age: 10}, nil).
+		After(50 * time.Millisecond)
+	details_mock.On("GetUserDetails", mock.Anything, &detailspb.GetUserDetailsRequest{UserId: 1}).
+		Return(&detailspb.GetUserDetailsResponse{Country: "Mexico"}, nil)
+
+	// act
+	var wg sync.WaitGroup
+	results := make([]entities.User, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cached.GetUser(ctx, 1)
+		}(i)
+	}
+	wg.Wait()
+	res, err := cached.GetUser(ctx, 1)
+
+	// assert
+	assert.Nil(err)
+	assert.Equal("email@domain.com", res.Email)
+	assert.Equal("Mexico", res.Country)
+	for _, r := range results {
+		assert.Equal(res, r)
+	}
+	user_mock.AssertNumberOfCalls(t, "GetUser", 1)
+	assert.Equal(float64(1), hits.Value())
+	assert.Equal(float64(5), misses.Value())
+}
+
+func TestCachedInvalidation(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	details_mock := new(repository.GrpcDetailsMock)
+	conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+	defer conn1.Close()
+	defer conn2.Close()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	c := cache.NewLRU(10, time.Minute)
+	cached := repository.NewCachedHttpRepository(http_repository, c, generic.NewCounter("hits"), generic.NewCounter("misses"))
+	c.Set(ctx, 1, entities.User{Email: "old@domain.com"})
+	c.Set(ctx, 2, entities.User{Email: "gone@domain.com"})
+	c.Set(ctx, 3, entities.User{Email: "account@domain.com"})
+	c.Set(ctx, 4, entities.User{Email: "details@domain.com"})
+
+	user_mock.On("UpdateUser", mock.Anything, mock.Anything).Return(&userpb.UpdateUserResponse{Success: true}, nil)
+	details_mock.On("SetUserDetails", mock.Anything, mock.Anything).Return(&detailspb.SetUserDetailsResponse{Success: true}, nil)
+	user_mock.On("DeleteUser", mock.Anything, mock.Anything).Return(&userpb.DeleteUserResponse{Success: true}, nil)
+	details_mock.On("DeleteUserDetails", mock.Anything, mock.Anything).Return(&detailspb.DeleteUserDetailsResponse{Success: true}, nil)
+
+	// act
+	cached.UpdateUser(ctx, entities.UserUpdate{UserId: 1, User: entities.User{Email: "new@domain.com", Password: "qwerty"}})
+	cached.DeleteUser(ctx, 2)
+	cached.UpdateAccount(ctx, entities.AccountUpdate{UserId: 3, Email: "new@domain.com", Password: "qwerty"})
+	cached.SetUserDetails(ctx, 4, entities.Details{Country: "MX"})
+	_, updated := c.Get(ctx, 1)
+	_, deleted := c.Get(ctx, 2)
+	_, account := c.Get(ctx, 3)
+	_, details := c.Get(ctx, 4)
+
+	// assert
+	assert.False(updated)
+	assert.False(deleted)
+	assert.False(account)
+	assert.False(details)
+}
+
+func TestCachedInvalidationDuringFetch(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	details_mock := new(repository.GrpcDetailsMock)
+	conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+	defer conn1.Close()
+	defer conn2.Close()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	c := cache.NewLRU(10, time.Minute)
+	cached := repository.NewCachedHttpRepository(http_repository, c, generic.NewCounter("hits"), generic.NewCounter("misses"))
+
+	user_mock.On("GetUser", mock.Anything, &userpb.GetUserRequest{Id: 1}).
+		Return(&userpb.GetUserResponse{Email: "deleted@domain.com"}, nil).
+		After(100 * time.Millisecond)
+	details_mock.On("GetUserDetails", mock.Anything, mock.Anything).Return(&detailspb.GetUserDetailsResponse{Country: "MX"}, nil)
+	user_mock.On("DeleteUser", mock.Anything, mock.Anything).Return(&userpb.DeleteUserResponse{Success: true}, nil)
+	details_mock.On("DeleteUserDetails", mock.Anything, mock.Anything).Return(&detailspb.DeleteUserDetailsResponse{Success: true}, nil)
+
+	// act
+	done := make(chan struct{})
+	go func() {
+		cached.GetUser(ctx, 1)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cached.DeleteUser(ctx, 1)
+	<-done
+	_, ok := c.Get(ctx, 1)
+
+	// assert
+	assert.False(ok, "the user fetched before the delete is not cached")
+}
+
+func TestCachedGetUserCanceled(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	details_mock := new(repository.GrpcDetailsMock)
+	conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+	defer conn1.Close()
+	defer conn2.Close()
+
+	// prepare
+	assert := assert.New(t)
+	cached := repository.NewCachedHttpRepository(http_repository, cache.NewLRU(10, time.Minute), generic.NewCounter("hits"), generic.NewCounter("misses"))
+
+	user_mock.On("GetUser", mock.Anything, &userpb.GetUserRequest{Id: 1}).
+		Return(&userpb.GetUserResponse{Email: "email@domain.com"}, nil).
+		After(100 * time.Millisecond)
+	details_mock.On("GetUserDetails", mock.Anything, mock.Anything).Return(&detailspb.GetUserDetailsResponse{Country: "MX"}, nil)
+
+	// act
+	first_ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cached.GetUser(first_ctx, 1)
+		first <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	second := make(chan error, 1)
+	go func() {
+		_, err := cached.GetUser(context.Background(), 1)
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	// assert
+	assert.ErrorIs(<-first, context.Canceled)
+	assert.Nil(<-second)
+	user_mock.AssertNumberOfCalls(t, "GetUser", 1)
+}
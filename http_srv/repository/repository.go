@@ -5,10 +5,13 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/logging"
 	"github.com/mauricioww/user_microsrv/user_details_srv/detailspb"
 	"github.com/mauricioww/user_microsrv/user_srv/userpb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 type HttpRepository interface {
@@ -17,6 +20,14 @@ type HttpRepository interface {
 	UpdateUser(ctx context.Context, user entities.UserUpdate) (bool, error)
 	GetUser(ctx context.Context, id int) (entities.User, error)
 	DeleteUser(ctx context.Context, id int) (bool, error)
+	GetAccount(ctx context.Context, id int) (entities.Account, error)
+	GetUserDetails(ctx context.Context, id int) (entities.Details, error)
+	GetAccounts(ctx context.Context, ids []int) (map[int]entities.Account, error)
+	GetUsersDetails(ctx context.Context, ids []int) (map[int]entities.Details, error)
+	UpdateAccount(ctx context.Context, account entities.AccountUpdate) (bool, error)
+	SetUserDetails(ctx context.Context, id int, details entities.Details) (bool, error)
+	DeleteUserDetails(ctx context.Context, id int) (bool, error)
+	SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error)
 }
 
 type httpRepository struct {
@@ -34,7 +45,7 @@ func NewHttpRepository(conn1 *grpc.ClientConn, conn2 *grpc.ClientConn, logger lo
 }
 
 func (r *httpRepository) CreateUser(ctx context.Context, user entities.User) (int, error) {
-	logger := log.With(r.logger, "method", "create_users")
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "create_users")
 	res := -1
 
 	userpb_req := userpb.CreateUserRequest{
@@ -73,7 +84,7 @@ func (r *httpRepository) CreateUser(ctx context.Context, user entities.User) (in
 }
 
 func (r *httpRepository) Authenticate(ctx context.Context, session entities.Session) (int, error) {
-	logger := log.With(r.logger, "method", "authenticate_user")
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "authenticate_user")
 	res := -1
 
 	auth_req := userpb.AuthenticateRequest{
@@ -92,7 +103,7 @@ func (r *httpRepository) Authenticate(ctx context.Context, session entities.Sess
 }
 
 func (r *httpRepository) UpdateUser(ctx context.Context, user entities.UserUpdate) (bool, error) {
-	logger := log.With(r.logger, "method", "update_user")
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "update_user")
 	var res bool
 
 	user_req := userpb.UpdateUserRequest{
@@ -129,7 +140,7 @@ func (r *httpRepository) UpdateUser(ctx context.Context, user entities.UserUpdat
 }
 
 func (r *httpRepository) GetUser(ctx context.Context, id int) (entities.User, error) {
-	logger := log.With(r.logger, "method", "get_user")
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "get_user")
 	var res entities.User
 
 	user_req := userpb.GetUserRequest{
@@ -146,8 +157,9 @@ func (r *httpRepository) GetUser(ctx context.Context, id int) (entities.User, er
 		return res, err
 	}
 
+	// a user whose details were deleted has none rather than being gone
 	details_res, err := r.details_client.GetUserDetails(ctx, &details_req)
-	if err != nil {
+	if err != nil && errors.Code(err) != codes.NotFound {
 		level.Error(logger).Log("err_details", err)
 		return res, err
 	}
@@ -170,7 +182,7 @@ func (r *httpRepository) GetUser(ctx context.Context, id int) (entities.User, er
 }
 
 func (r *httpRepository) DeleteUser(ctx context.Context, id int) (bool, error) {
-	logger := log.With(r.logger, "method", "delete_user")
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "delete_user")
 	var res bool
 
 	user_req := userpb.DeleteUserRequest{
@@ -188,7 +200,10 @@ func (r *httpRepository) DeleteUser(ctx context.Context, id int) (bool, error) {
 	}
 	details_res, err := r.details_client.DeleteUserDetails(ctx, &details_req)
 
-	if err != nil {
+	if errors.Code(err) == codes.NotFound {
+		// the details were deleted on their own before
+		return user_res.GetSuccess(), nil
+	} else if err != nil {
 		level.Error(logger).Log("err_details", err)
 		return res, err
 	}
@@ -197,3 +212,158 @@ func (r *httpRepository) DeleteUser(ctx context.Context, id int) (bool, error) {
 
 	return res, nil
 }
+
+// GetAccount fetches the account alone, without asking details_srv.
+func (r *httpRepository) GetAccount(ctx context.Context, id int) (entities.Account, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "get_account")
+
+	user_res, err := r.user_client.GetUser(ctx, &userpb.GetUserRequest{Id: uint32(id)})
+	if err != nil {
+		level.Error(logger).Log("err_user", err)
+		return entities.Account{}, err
+	}
+
+	return entities.Account{Id: id, Email: user_res.GetEmail(), Age: int(user_res.GetAge())}, nil
+}
+
+// GetUserDetails fetches the details alone, without asking user_srv.
+func (r *httpRepository) GetUserDetails(ctx context.Context, id int) (entities.Details, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "get_user_details")
+
+	details_res, err := r.details_client.GetUserDetails(ctx, &detailspb.GetUserDetailsRequest{UserId: uint32(id)})
+	if err != nil {
+		level.Error(logger).Log("err_details", err)
+		return entities.Details{}, err
+	}
+
+	return entities.Details{
+		Country:      details_res.GetCountry(),
+		City:         details_res.GetCity(),
+		MobileNumber: details_res.GetMobileNumber(),
+		Married:      details_res.GetMarried(),
+		Height:       details_res.GetHeight(),
+		Weight:       details_res.GetWeight(),
+	}, nil
+}
+
+// GetAccounts fetches several accounts in a single call to user_srv; the ids
+// with no account are missing from the result.
+func (r *httpRepository) GetAccounts(ctx context.Context, ids []int) (map[int]entities.Account, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "get_accounts")
+
+	users_req := userpb.GetUsersRequest{Ids: make([]uint32, len(ids))}
+	for i, id := range ids {
+		users_req.Ids[i] = uint32(id)
+	}
+	users_res, err := r.user_client.GetUsers(ctx, &users_req)
+	if err != nil {
+		level.Error(logger).Log("err_user", err)
+		return nil, err
+	}
+
+	res := make(map[int]entities.Account, len(users_res.GetUsers()))
+	for _, u := range users_res.GetUsers() {
+		res[int(u.GetId())] = entities.Account{Id: int(u.GetId()), Email: u.GetEmail(), Age: int(u.GetAge())}
+	}
+
+	return res, nil
+}
+
+// GetUsersDetails fetches the details of several users in a single call to
+// details_srv; the users with none are missing from the result.
+func (r *httpRepository) GetUsersDetails(ctx context.Context, ids []int) (map[int]entities.Details, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "get_users_details")
+
+	details_req := detailspb.GetUsersDetailsRequest{UserIds: make([]uint32, len(ids))}
+	for i, id := range ids {
+		details_req.UserIds[i] = uint32(id)
+	}
+	details_res, err := r.details_client.GetUsersDetails(ctx, &details_req)
+	if err != nil {
+		level.Error(logger).Log("err_details", err)
+		return nil, err
+	}
+
+	res := make(map[int]entities.Details, len(details_res.GetDetails()))
+	for _, d := range details_res.GetDetails() {
+		res[int(d.GetUserId())] = entities.Details{
+			Country:      d.GetCountry(),
+			City:         d.GetCity(),
+			MobileNumber: d.GetMobileNumber(),
+			Married:      d.GetMarried(),
+			Height:       d.GetHeight(),
+			Weight:       d.GetWeight(),
+		}
+	}
+
+	return res, nil
+}
+
+// UpdateAccount replaces the account alone, leaving details_srv untouched.
+func (r *httpRepository) UpdateAccount(ctx context.Context, account entities.AccountUpdate) (bool, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "update_account")
+
+	user_res, err := r.user_client.UpdateUser(ctx, &userpb.UpdateUserRequest{
+		Id:       uint32(account.UserId),
+		Email:    account.Email,
+		Password: account.Password,
+		Age:      uint32(account.Age),
+	})
+	if err != nil {
+		level.Error(logger).Log("err_user", err)
+		return false, err
+	}
+
+	return user_res.GetSuccess(), nil
+}
+
+// SetUserDetails replaces the details alone, without asking user_srv.
+func (r *httpRepository) SetUserDetails(ctx context.Context, id int, details entities.Details) (bool, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "set_user_details")
+
+	details_res, err := r.details_client.SetUserDetails(ctx, &detailspb.SetUserDetailsRequest{
+		UserId:       uint32(id),
+		Country:      details.Country,
+		City:         details.City,
+		MobileNumber: details.MobileNumber,
+		Married:      details.Married,
+		Height:       details.Height,
+		Weight:       details.Weight,
+	})
+	if err != nil {
+		level.Error(logger).Log("err_details", err)
+		return false, err
+	}
+
+	return details_res.GetSuccess(), nil
+}
+
+// DeleteUserDetails deletes the details alone, keeping the account.
+func (r *httpRepository) DeleteUserDetails(ctx context.Context, id int) (bool, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "delete_user_details")
+
+	details_res, err := r.details_client.DeleteUserDetails(ctx, &detailspb.DeleteUserDetailsRequest{UserId: uint32(id)})
+	if err != nil {
+		level.Error(logger).Log("err_details", err)
+		return false, err
+	}
+
+	return details_res.GetSuccess(), nil
+}
+
+func (r *httpRepository) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "search_users")
+
+	search_res, err := r.user_client.SearchUsers(ctx, &userpb.SearchUsersRequest{Email: email, Limit: uint32(limit)})
+	if err != nil {
+		level.Error(logger).Log("err_user", err)
+		return nil, err
+	}
+
+	res := make([]entities.Account, 0, len(search_res.GetUsers()))
+	for _, u := range search_res.GetUsers() {
+		res = append(res, entities.Account{Id: int(u.GetId()), Email: u.GetEmail(), Age: int(u.GetAge())})
+	}
+
+	return res, nil
+}
@@ -111,6 +111,18 @@ func (m *GrpcUserMock) DeleteUser(ctx context.Context, req *userpb.DeleteUserReq
 	return args.Get(0).(*userpb.DeleteUserResponse), args.Error(1)
 }
 
+func (m *GrpcUserMock) SearchUsers(ctx context.Context, req *userpb.SearchUsersRequest) (*userpb.SearchUsersResponse, error) {
+	args := m.Called(ctx, req)
+
+	return args.Get(0).(*userpb.SearchUsersResponse), args.Error(1)
+}
+
+func (m *GrpcUserMock) GetUsers(ctx context.Context, req *userpb.GetUsersRequest) (*userpb.GetUsersResponse, error) {
+	args := m.Called(ctx, req)
+
+	return args.Get(0).(*userpb.GetUsersResponse), args.Error(1)
+}
+
 func (m *GrpcDetailsMock) SetUserDetails(ctx context.Context, req *detailspb.SetUserDetailsRequest) (*detailspb.SetUserDetailsResponse, error) {
 	args := m.Called(ctx, req)
 
@@ -123,6 +135,12 @@ func (m *GrpcDetailsMock) GetUserDetails(ctx context.Context, req *detailspb.Get
 	return args.Get(0).(*detailspb.GetUserDetailsResponse), args.Error(1)
 }
 
+func (m *GrpcDetailsMock) GetUsersDetails(ctx context.Context, req *detailspb.GetUsersDetailsRequest) (*detailspb.GetUsersDetailsResponse, error) {
+	args := m.Called(ctx, req)
+
+	return args.Get(0).(*detailspb.GetUsersDetailsResponse), args.Error(1)
+}
+
 func (m *GrpcDetailsMock) DeleteUserDetails(ctx context.Context, req *detailspb.DeleteUserDetailsRequest) (*detailspb.DeleteUserDetailsResponse, error) {
 	args := m.Called(ctx, req)
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/http_srv/entities"
 	"github.com/mauricioww/user_microsrv/http_srv/repository"
 	"github.com/mauricioww/user_microsrv/user_details_srv/detailspb"
@@ -411,3 +412,202 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestDeletedDetails(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	details_mock := new(repository.GrpcDetailsMock)
+	conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+	defer conn1.Close()
+	defer conn2.Close()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	not_found := status.Error(codes.NotFound, "User not found")
+	user_mock.On("GetUser", mock.Anything, &userpb.GetUserRequest{Id: 1}).Return(&userpb.GetUserResponse{Email: "email@domain.com", Age: 10}, nil)
+	user_mock.On("DeleteUser", mock.Anything, &userpb.DeleteUserRequest{Id: 1}).Return(&userpb.DeleteUserResponse{Success: true}, nil)
+	details_mock.On("DeleteUserDetails", mock.Anything, &detailspb.DeleteUserDetailsRequest{UserId: 1}).Return((*detailspb.DeleteUserDetailsResponse)(nil), not_found)
+	details_mock.On("GetUserDetails", mock.Anything, &detailspb.GetUserDetailsRequest{UserId: 1}).Return((*detailspb.GetUserDetailsResponse)(nil), not_found)
+
+	// act
+	_, details_err := http_repository.DeleteUserDetails(ctx, 1)
+	user, get_err := http_repository.GetUser(ctx, 1)
+	deleted, delete_err := http_repository.DeleteUser(ctx, 1)
+
+	// assert
+	assert.True(repository.TestErrors(details_err, not_found))
+	assert.Nil(get_err)
+	assert.Equal(entities.User{Email: "email@domain.com", Age: 10}, user)
+	assert.Nil(delete_err)
+	assert.True(deleted)
+}
+
+func TestBatchLookups(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	details_mock := new(repository.GrpcDetailsMock)
+	conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+	defer conn1.Close()
+	defer conn2.Close()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	user_mock.On("GetUsers", mock.Anything, &userpb.GetUsersRequest{Ids: []uint32{1, 2, 3}}).Return(&userpb.GetUsersResponse{Users: []*userpb.UserSummary{
+		{Id: 1, Email: "first@domain.com", Age: 23},
+		{Id: 3, Email: "third@domain.com", Age: 32},
+	}}, nil)
+	details_mock.On("GetUsersDetails", mock.Anything, &detailspb.GetUsersDetailsRequest{UserIds: []uint32{1, 2, 3}}).Return(&detailspb.GetUsersDetailsResponse{Details: []*detailspb.UserDetails{
+		{UserId: 2, Country: "MX", City: "CDMX", Height: 1.75},
+	}}, nil)
+
+	// act
+	accounts, accounts_err := http_repository.GetAccounts(ctx, []int{1, 2, 3})
+	details, details_err := http_repository.GetUsersDetails(ctx, []int{1, 2, 3})
+
+	// assert
+	assert.Nil(accounts_err)
+	assert.Equal(map[int]entities.Account{
+		1: {Id: 1, Email: "first@domain.com", Age: 23},
+		3: {Id: 3, Email: "third@domain.com", Age: 32},
+	}, accounts)
+	assert.Nil(details_err)
+	assert.Equal(map[int]entities.Details{2: {Country: "MX", City: "CDMX", Height: 1.75}}, details)
+	user_mock.AssertNumberOfCalls(t, "GetUsers", 1)
+	details_mock.AssertNumberOfCalls(t, "GetUsersDetails", 1)
+}
+
+func TestUpdateAccount(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		data      entities.AccountUpdate
+		res       bool
+		err       error
+	}{
+		{
+			test_name: "account updated success",
+			data:      entities.AccountUpdate{UserId: 1, Email: "new@email.com", Password: "qwerty123", Age: 30},
+			res:       true,
+			err:       nil,
+		},
+		{
+			test_name: "account update error",
+			data:      entities.AccountUpdate{UserId: 2, Email: "new@email.com", Password: "qwerty123", Age: 30},
+			res:       false,
+			err:       status.Error(codes.NotFound, "User not found"),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			user_mock := new(repository.GrpcUserMock)
+			details_mock := new(repository.GrpcDetailsMock)
+			conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+			defer conn1.Close()
+			defer conn2.Close()
+
+			// prepare
+			assert := assert.New(t)
+			ctx := context.Background()
+			var user_res *userpb.UpdateUserResponse
+			if tc.err == nil {
+				user_res = &userpb.UpdateUserResponse{Success: tc.res}
+			}
+			user_req := &userpb.UpdateUserRequest{Id: uint32(tc.data.UserId), Email: tc.data.Email, Password: tc.data.Password, Age: uint32(tc.data.Age)}
+			user_mock.On("UpdateUser", mock.Anything, user_req).Return(user_res, tc.err)
+
+			// act
+			res, err := http_repository.UpdateAccount(ctx, tc.data)
+
+			// assert
+			assert.Equal(tc.res, res)
+			assert.True(repository.TestErrors(err, tc.err))
+			details_mock.AssertNotCalled(t, "SetUserDetails", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSetUserDetails(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		id        int
+		data      entities.Details
+		res       bool
+		err       error
+	}{
+		{
+			test_name: "details set success",
+			id:        1,
+			data:      entities.Details{Country: "MX", City: "CDMX", Height: 1.75},
+			res:       true,
+			err:       nil,
+		},
+		{
+			test_name: "details set error",
+			id:        2,
+			data:      entities.Details{Country: "MX"},
+			res:       false,
+			err:       status.Error(codes.Unavailable, "Unavailable"),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			user_mock := new(repository.GrpcUserMock)
+			details_mock := new(repository.GrpcDetailsMock)
+			conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+			defer conn1.Close()
+			defer conn2.Close()
+
+			// prepare
+			assert := assert.New(t)
+			ctx := context.Background()
+			var details_res *detailspb.SetUserDetailsResponse
+			if tc.err == nil {
+				details_res = &detailspb.SetUserDetailsResponse{Success: tc.res}
+			}
+			details_req := &detailspb.SetUserDetailsRequest{UserId: uint32(tc.id), Country: tc.data.Country, City: tc.data.City, Height: tc.data.Height}
+			details_mock.On("SetUserDetails", mock.Anything, details_req).Return(details_res, tc.err)
+
+			// act
+			res, err := http_repository.SetUserDetails(ctx, tc.id, tc.data)
+
+			// assert
+			assert.Equal(tc.res, res)
+			assert.True(repository.TestErrors(err, tc.err))
+			user_mock.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestErrorDetailsRoundTrip(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	details_mock := new(repository.GrpcDetailsMock)
+	conn1, conn2, http_repository := repository.InitRepoMock(user_mock, details_mock)
+
+	defer conn1.Close()
+	defer conn2.Close()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	sent := errors.NewInvalidArgumentError(
+		errors.FieldViolation{Field: "email", Description: "is required"},
+		errors.FieldViolation{Field: "age", Description: "must be positive"},
+	)
+	user_mock.On("GetUser", mock.Anything, &userpb.GetUserRequest{Id: 3}).Return(&userpb.GetUserResponse{}, sent)
+
+	// act
+	_, err := http_repository.GetUser(ctx, 3)
+	res := errors.FromError(err)
+
+	// assert
+	assert.ErrorIs(res, sent)
+	assert.Equal(codes.FailedPrecondition, res.Code)
+	assert.Equal(errors.ReasonInvalidArgument, res.Reason)
+	assert.Equal(sent.Message, res.Message)
+	assert.Equal(sent.Violations, res.Violations)
+}
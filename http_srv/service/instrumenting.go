@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+)
+
+type Middleware func(HttpService) HttpService
+
+type Metrics struct {
+	Requests metrics.Counter
+	Errors   metrics.Counter
+	Latency  metrics.Histogram
+}
+
+type instrumentingMiddleware struct {
+	metrics Metrics
+	next    HttpService
+}
+
+func InstrumentingMiddleware(m Metrics) Middleware {
+	return func(next HttpService) HttpService {
+		return &instrumentingMiddleware{metrics: m, next: next}
+	}
+}
+
+func (mw *instrumentingMiddleware) observe(method string, begin time.Time, err error) {
+	mw.metrics.Requests.With("method", method).Add(1)
+	if err != nil {
+		mw.metrics.Errors.With("method", method, "code", errors.Code(err).String()).Add(1)
+	}
+	mw.metrics.Latency.With("method", method).Observe(time.Since(begin).Seconds())
+}
+
+func (mw *instrumentingMiddleware) CreateUser(ctx context.Context, email string, pwd string, age int, details entities.Details) (res int, err error) {
+	defer func(begin time.Time) {
+		mw.observe("create_user", begin, err)
+	}(time.Now())
+	return mw.next.CreateUser(ctx, email, pwd, age, details)
+}
+
+func (mw *instrumentingMiddleware) Authenticate(ctx context.Context, email string, pwd string) (res string, err error) {
+	defer func(begin time.Time) {
+		mw.observe("authenticate", begin, err)
+	}(time.Now())
+	return mw.next.Authenticate(ctx, email, pwd)
+}
+
+func (mw *instrumentingMiddleware) UpdateUser(ctx context.Context, user_id int, email string, pwd string, age int, details entities.Details) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("update_user", begin, err)
+	}(time.Now())
+	return mw.next.UpdateUser(ctx, user_id, email, pwd, age, details)
+}
+
+func (mw *instrumentingMiddleware) GetUser(ctx context.Context, user_id int) (res entities.User, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_user", begin, err)
+	}(time.Now())
+	return mw.next.GetUser(ctx, user_id)
+}
+
+func (mw *instrumentingMiddleware) DeleteUser(ctx context.Context, user_id int) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("delete_user", begin, err)
+	}(time.Now())
+	return mw.next.DeleteUser(ctx, user_id)
+}
+
+func (mw *instrumentingMiddleware) GetAccount(ctx context.Context, user_id int) (res entities.Account, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_account", begin, err)
+	}(time.Now())
+	return mw.next.GetAccount(ctx, user_id)
+}
+
+func (mw *instrumentingMiddleware) GetUserDetails(ctx context.Context, user_id int) (res entities.Details, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_user_details", begin, err)
+	}(time.Now())
+	return mw.next.GetUserDetails(ctx, user_id)
+}
+
+func (mw *instrumentingMiddleware) GetAccounts(ctx context.Context, user_ids []int) (res map[int]entities.Account, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_accounts", begin, err)
+	}(time.Now())
+	return mw.next.GetAccounts(ctx, user_ids)
+}
+
+func (mw *instrumentingMiddleware) GetUsersDetails(ctx context.Context, user_ids []int) (res map[int]entities.Details, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_users_details", begin, err)
+	}(time.Now())
+	return mw.next.GetUsersDetails(ctx, user_ids)
+}
+
+func (mw *instrumentingMiddleware) UpdateAccount(ctx context.Context, user_id int, email string, pwd string, age int) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("update_account", begin, err)
+	}(time.Now())
+	return mw.next.UpdateAccount(ctx, user_id, email, pwd, age)
+}
+
+func (mw *instrumentingMiddleware) SetUserDetails(ctx context.Context, user_id int, details entities.Details) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("set_user_details", begin, err)
+	}(time.Now())
+	return mw.next.SetUserDetails(ctx, user_id, details)
+}
+
+func (mw *instrumentingMiddleware) PatchUserDetails(ctx context.Context, user_id int, patch entities.DetailsPatch) (res entities.Details, err error) {
+	defer func(begin time.Time) {
+		mw.observe("patch_user_details", begin, err)
+	}(time.Now())
+	return mw.next.PatchUserDetails(ctx, user_id, patch)
+}
+
+func (mw *instrumentingMiddleware) DeleteUserDetails(ctx context.Context, user_id int) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("delete_user_details", begin, err)
+	}(time.Now())
+	return mw.next.DeleteUserDetails(ctx, user_id)
+}
+
+func (mw *instrumentingMiddleware) SearchUsers(ctx context.Context, email string, limit int) (res []entities.Account, err error) {
+	defer func(begin time.Time) {
+		mw.observe("search_users", begin, err)
+	}(time.Now())
+	return mw.next.SearchUsers(ctx, email, limit)
+}
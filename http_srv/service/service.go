@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"strconv"
 	"time"
 
@@ -11,6 +10,7 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/mauricioww/user_microsrv/http_srv/entities"
 	"github.com/mauricioww/user_microsrv/http_srv/repository"
+	"github.com/mauricioww/user_microsrv/logging"
 )
 
 type HttpService interface {
@@ -19,6 +19,15 @@ type HttpService interface {
 	UpdateUser(ctx context.Context, user_id int, email string, pwd string, age int, details entities.Details) (bool, error)
 	GetUser(ctx context.Context, user_id int) (entities.User, error)
 	DeleteUser(ctx context.Context, user_id int) (bool, error)
+	GetAccount(ctx context.Context, user_id int) (entities.Account, error)
+	GetUserDetails(ctx context.Context, user_id int) (entities.Details, error)
+	GetAccounts(ctx context.Context, user_ids []int) (map[int]entities.Account, error)
+	GetUsersDetails(ctx context.Context, user_ids []int) (map[int]entities.Details, error)
+	UpdateAccount(ctx context.Context, user_id int, email string, pwd string, age int) (bool, error)
+	SetUserDetails(ctx context.Context, user_id int, details entities.Details) (bool, error)
+	PatchUserDetails(ctx context.Context, user_id int, patch entities.DetailsPatch) (entities.Details, error)
+	DeleteUserDetails(ctx context.Context, user_id int) (bool, error)
+	SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error)
 }
 
 type httpService struct {
@@ -34,7 +43,7 @@ func NewHttpService(r repository.HttpRepository, l log.Logger) HttpService {
 }
 
 func (s *httpService) CreateUser(ctx context.Context, email string, pwd string, age int, details entities.Details) (int, error) {
-	logger := log.With(s.logger, "method", "create_user")
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "create_user")
 
 	user := entities.User{
 		Email:    email,
@@ -46,8 +55,6 @@ func (s *httpService) CreateUser(ctx context.Context, email string, pwd string,
 	res, err := s.repository.CreateUser(ctx, user)
 
 	if err != nil {
-		fmt.Println("Create Error")
-		fmt.Printf("Service %+v\n", err)
 		level.Error(logger).Log("ERROR: ", err)
 	} else {
 		logger.Log("action", "success")
@@ -57,7 +64,7 @@ func (s *httpService) CreateUser(ctx context.Context, email string, pwd string,
 }
 
 func (s *httpService) Authenticate(ctx context.Context, email string, pwd string) (string, error) {
-	logger := log.With(s.logger, "method", "authenticate")
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "authenticate")
 	var response string
 
 	session := entities.Session{
@@ -92,7 +99,7 @@ func (s *httpService) Authenticate(ctx context.Context, email string, pwd string
 }
 
 func (s *httpService) UpdateUser(ctx context.Context, user_id int, email string, pwd string, age int, details entities.Details) (bool, error) {
-	logger := log.With(s.logger, "method", "update_user")
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "update_user")
 	info_update := entities.UserUpdate{
 		UserId: user_id,
 		User: entities.User{
@@ -115,7 +122,7 @@ func (s *httpService) UpdateUser(ctx context.Context, user_id int, email string,
 }
 
 func (s *httpService) GetUser(ctx context.Context, user_id int) (entities.User, error) {
-	logger := log.With(s.logger, "method", "get_user")
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "get_user")
 
 	res, err := s.repository.GetUser(ctx, user_id)
 
@@ -129,7 +136,7 @@ func (s *httpService) GetUser(ctx context.Context, user_id int) (entities.User,
 }
 
 func (s *httpService) DeleteUser(ctx context.Context, user_id int) (bool, error) {
-	logger := log.With(s.logger, "method", "delete_user")
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "delete_user")
 
 	res, err := s.repository.DeleteUser(ctx, user_id)
 
@@ -141,3 +148,147 @@ func (s *httpService) DeleteUser(ctx context.Context, user_id int) (bool, error)
 
 	return res, err
 }
+
+func (s *httpService) GetAccount(ctx context.Context, user_id int) (entities.Account, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "get_account")
+
+	res, err := s.repository.GetAccount(ctx, user_id)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
+
+func (s *httpService) GetUserDetails(ctx context.Context, user_id int) (entities.Details, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "get_user_details")
+
+	res, err := s.repository.GetUserDetails(ctx, user_id)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
+
+// GetAccounts looks several accounts up at once; ids with no account are
+// missing from the result.
+func (s *httpService) GetAccounts(ctx context.Context, user_ids []int) (map[int]entities.Account, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "get_accounts")
+
+	res, err := s.repository.GetAccounts(ctx, user_ids)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
+
+// GetUsersDetails looks the details of several users up at once; users with
+// none are missing from the result.
+func (s *httpService) GetUsersDetails(ctx context.Context, user_ids []int) (map[int]entities.Details, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "get_users_details")
+
+	res, err := s.repository.GetUsersDetails(ctx, user_ids)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
+
+func (s *httpService) UpdateAccount(ctx context.Context, user_id int, email string, pwd string, age int) (bool, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "update_account")
+	account := entities.AccountUpdate{
+		UserId:   user_id,
+		Email:    email,
+		Password: pwd,
+		Age:      age,
+	}
+
+	res, err := s.repository.UpdateAccount(ctx, account)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
+
+func (s *httpService) SetUserDetails(ctx context.Context, user_id int, details entities.Details) (bool, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "set_user_details")
+
+	res, err := s.repository.SetUserDetails(ctx, user_id, details)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
+
+// PatchUserDetails replaces the fields patch sets and returns the result.
+// The read and the write are separate calls, so of two concurrent patches
+// the last one written wins.
+func (s *httpService) PatchUserDetails(ctx context.Context, user_id int, patch entities.DetailsPatch) (entities.Details, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "patch_user_details")
+
+	current, err := s.repository.GetUserDetails(ctx, user_id)
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+		return entities.Details{}, err
+	}
+
+	res := patch.Apply(current)
+	if _, err := s.repository.SetUserDetails(ctx, user_id, res); err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+		return entities.Details{}, err
+	}
+
+	logger.Log("action", "success")
+	return res, nil
+}
+
+func (s *httpService) DeleteUserDetails(ctx context.Context, user_id int) (bool, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "delete_user_details")
+
+	res, err := s.repository.DeleteUserDetails(ctx, user_id)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
+
+func (s *httpService) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	logger := log.With(s.logger, "request_id", logging.RequestID(ctx), "method", "search_users")
+
+	res, err := s.repository.SearchUsers(ctx, email, limit)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR: ", err)
+	} else {
+		logger.Log("action", "success")
+	}
+
+	return res, err
+}
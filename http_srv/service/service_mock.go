@@ -44,6 +44,62 @@ func (r *RepoMock) DeleteUser(ctx context.Context, id int) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func (r *RepoMock) GetAccount(ctx context.Context, id int) (entities.Account, error) {
+	args := r.Called(ctx, id)
+
+	return args.Get(0).(entities.Account), args.Error(1)
+}
+
+func (r *RepoMock) GetUserDetails(ctx context.Context, id int) (entities.Details, error) {
+	args := r.Called(ctx, id)
+
+	return args.Get(0).(entities.Details), args.Error(1)
+}
+
+func (r *RepoMock) GetAccounts(ctx context.Context, ids []int) (map[int]entities.Account, error) {
+	args := r.Called(ctx, ids)
+
+	return args.Get(0).(map[int]entities.Account), args.Error(1)
+}
+
+func (r *RepoMock) GetUsersDetails(ctx context.Context, ids []int) (map[int]entities.Details, error) {
+	args := r.Called(ctx, ids)
+
+	return args.Get(0).(map[int]entities.Details), args.Error(1)
+}
+
+func (r *RepoMock) UpdateAccount(ctx context.Context, account entities.AccountUpdate) (bool, error) {
+	args := r.Called(ctx, account)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *RepoMock) SetUserDetails(ctx context.Context, id int, details entities.Details) (bool, error) {
+	args := r.Called(ctx, id, details)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *RepoMock) DeleteUserDetails(ctx context.Context, id int) (bool, error) {
+	args := r.Called(ctx, id)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *RepoMock) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	args := r.Called(ctx, email, limit)
+
+	return args.Get(0).([]entities.Account), args.Error(1)
+}
+
+type PublisherMock struct {
+	mock.Mock
+}
+
+func (p *PublisherMock) Publish(ctx context.Context, event_type string, data interface{}) {
+	p.Called(ctx, event_type, data)
+}
+
 func GenenerateDetails() entities.Details {
 	return entities.Details{
 		Country:      "Mexico",
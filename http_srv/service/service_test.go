@@ -1,11 +1,13 @@
 package service_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
 	"github.com/mauricioww/user_microsrv/http_srv/entities"
 	"github.com/mauricioww/user_microsrv/http_srv/service"
+	"github.com/mauricioww/user_microsrv/logging"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -307,3 +309,128 @@ func TestDeleteUser(t *testing.T) {
 		assert.True(service.TestErrors(err, tc.err))
 	}
 }
+
+func TestPatchUserDetails(t *testing.T) {
+	current := entities.Details{Country: "MX", City: "CDMX", Height: 1.75}
+	city, married := "Monterrey", true
+
+	test_cases := []struct {
+		test_name string
+		data      int
+		patch     entities.DetailsPatch
+		get_err   error
+		set_err   error
+		res       entities.Details
+		err       error
+	}{
+		{
+			test_name: "fields replaced",
+			data:      1,
+			patch:     entities.DetailsPatch{City: &city, Married: &married},
+			res:       entities.Details{Country: "MX", City: "Monterrey", Married: true, Height: 1.75},
+		},
+		{
+			test_name: "details not found",
+			data:      2,
+			patch:     entities.DetailsPatch{City: &city},
+			get_err:   status.Error(codes.NotFound, "User not found"),
+			err:       status.Error(codes.NotFound, "User not found"),
+		},
+		{
+			test_name: "write failed",
+			data:      3,
+			patch:     entities.DetailsPatch{City: &city},
+			set_err:   status.Error(codes.Unavailable, "Service unavailable"),
+			err:       status.Error(codes.Unavailable, "Service unavailable"),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			repository_mock := new(service.RepoMock)
+			http_service := service.NewHttpService(repository_mock, service.InitLogger())
+			ctx := context.Background()
+			assert := assert.New(t)
+			repository_mock.On("GetUserDetails", ctx, tc.data).Return(current, tc.get_err)
+			repository_mock.On("SetUserDetails", ctx, tc.data, tc.patch.Apply(current)).Return(tc.set_err == nil, tc.set_err)
+
+			// act
+			res, err := http_service.PatchUserDetails(ctx, tc.data, tc.patch)
+
+			// assert
+			assert.Equal(tc.res, res)
+			assert.True(service.TestErrors(err, tc.err))
+			if tc.get_err != nil {
+				repository_mock.AssertNotCalled(t, "SetUserDetails", ctx, tc.data, tc.patch.Apply(current))
+			}
+		})
+	}
+}
+
+func TestDeleteUserDetails(t *testing.T) {
+	repository_mock := new(service.RepoMock)
+	http_service := service.NewHttpService(repository_mock, service.InitLogger())
+
+	test_cases := []struct {
+		test_name string
+		data      int
+		res       bool
+		err       error
+	}{
+		{
+			test_name: "details deleted success",
+			data:      1,
+			res:       true,
+			err:       nil,
+		},
+		{
+			test_name: "details not found error",
+			data:      2,
+			res:       false,
+			err:       status.Error(codes.NotFound, "User not found"),
+		},
+	}
+
+	for _, tc := range test_cases {
+		// prepare
+		ctx := context.Background()
+		assert := assert.New(t)
+
+		// act
+		repository_mock.On("DeleteUserDetails", ctx, tc.data).Return(tc.res, tc.err)
+		res, err := http_service.DeleteUserDetails(ctx, tc.data)
+
+		// assert
+		assert.Equal(tc.res, res)
+		assert.True(service.TestErrors(err, tc.err))
+		repository_mock.AssertNotCalled(t, "DeleteUser", ctx, tc.data)
+	}
+}
+
+func TestCreateUserLogsRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := logging.New(&buf, "HTTP_SRV", logging.Config{})
+	repository_mock := new(service.RepoMock)
+	http_service := service.NewHttpService(repository_mock, logger)
+
+	// prepare
+	ctx := context.Background()
+	user := entities.User{
+		Email:    "leak@domain.com",
+		Password: "qwerty",
+		Age:      23,
+		Details:  service.GenenerateDetails(),
+	}
+	repository_mock.On("CreateUser", ctx, user).
+		Return(-1, status.Error(codes.AlreadyExists, "Duplicate entry 'leak@domain.com' for password=qwerty"))
+
+	// act
+	http_service.CreateUser(ctx, user.Email, user.Password, user.Age, user.Details)
+
+	// assert
+	assert.Contains(t, buf.String(), "level=error")
+	for _, s := range []string{user.Email, user.Password, user.Details.MobileNumber} {
+		assert.NotContains(t, buf.String(), s)
+	}
+}
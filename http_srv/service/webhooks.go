@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+)
+
+// Publisher is told of every user mutation that succeeded; webhook's
+// Dispatcher is one.
+type Publisher interface {
+	Publish(ctx context.Context, event_type string, data interface{})
+}
+
+type webhookMiddleware struct {
+	publisher Publisher
+	next      HttpService
+}
+
+// WebhookMiddleware publishes the user events once the services have
+// applied a mutation, whichever transport it came through.
+func WebhookMiddleware(p Publisher) Middleware {
+	return func(next HttpService) HttpService {
+		return &webhookMiddleware{publisher: p, next: next}
+	}
+}
+
+func (mw *webhookMiddleware) CreateUser(ctx context.Context, email string, pwd string, age int, details entities.Details) (int, error) {
+	res, err := mw.next.CreateUser(ctx, email, pwd, age, details)
+	if err == nil {
+		mw.publisher.Publish(ctx, webhook.UserCreated, webhook.UserData{UserId: res, Email: email, Age: age, Profile: &details})
+	}
+	return res, err
+}
+
+func (mw *webhookMiddleware) Authenticate(ctx context.Context, email string, pwd string) (string, error) {
+	return mw.next.Authenticate(ctx, email, pwd)
+}
+
+func (mw *webhookMiddleware) UpdateUser(ctx context.Context, user_id int, email string, pwd string, age int, details entities.Details) (bool, error) {
+	res, err := mw.next.UpdateUser(ctx, user_id, email, pwd, age, details)
+	if err == nil && res {
+		mw.publisher.Publish(ctx, webhook.UserUpdated, webhook.UserData{UserId: user_id, Email: email, Age: age, Profile: &details})
+	}
+	return res, err
+}
+
+func (mw *webhookMiddleware) GetUser(ctx context.Context, user_id int) (entities.User, error) {
+	return mw.next.GetUser(ctx, user_id)
+}
+
+func (mw *webhookMiddleware) DeleteUser(ctx context.Context, user_id int) (bool, error) {
+	res, err := mw.next.DeleteUser(ctx, user_id)
+	if err == nil && res {
+		mw.publisher.Publish(ctx, webhook.UserDeleted, webhook.UserData{UserId: user_id})
+	}
+	return res, err
+}
+
+func (mw *webhookMiddleware) GetAccount(ctx context.Context, user_id int) (entities.Account, error) {
+	return mw.next.GetAccount(ctx, user_id)
+}
+
+func (mw *webhookMiddleware) GetUserDetails(ctx context.Context, user_id int) (entities.Details, error) {
+	return mw.next.GetUserDetails(ctx, user_id)
+}
+
+func (mw *webhookMiddleware) GetAccounts(ctx context.Context, user_ids []int) (map[int]entities.Account, error) {
+	return mw.next.GetAccounts(ctx, user_ids)
+}
+
+func (mw *webhookMiddleware) GetUsersDetails(ctx context.Context, user_ids []int) (map[int]entities.Details, error) {
+	return mw.next.GetUsersDetails(ctx, user_ids)
+}
+
+func (mw *webhookMiddleware) UpdateAccount(ctx context.Context, user_id int, email string, pwd string, age int) (bool, error) {
+	res, err := mw.next.UpdateAccount(ctx, user_id, email, pwd, age)
+	if err == nil && res {
+		mw.publisher.Publish(ctx, webhook.UserUpdated, webhook.UserData{UserId: user_id, Email: email, Age: age})
+	}
+	return res, err
+}
+
+func (mw *webhookMiddleware) SetUserDetails(ctx context.Context, user_id int, details entities.Details) (bool, error) {
+	res, err := mw.next.SetUserDetails(ctx, user_id, details)
+	if err == nil && res {
+		mw.publisher.Publish(ctx, webhook.UserUpdated, webhook.UserData{UserId: user_id, Profile: &details})
+	}
+	return res, err
+}
+
+func (mw *webhookMiddleware) PatchUserDetails(ctx context.Context, user_id int, patch entities.DetailsPatch) (entities.Details, error) {
+	res, err := mw.next.PatchUserDetails(ctx, user_id, patch)
+	if err == nil {
+		mw.publisher.Publish(ctx, webhook.UserUpdated, webhook.UserData{UserId: user_id, Profile: &res})
+	}
+	return res, err
+}
+
+func (mw *webhookMiddleware) DeleteUserDetails(ctx context.Context, user_id int) (bool, error) {
+	res, err := mw.next.DeleteUserDetails(ctx, user_id)
+	if err == nil && res {
+		mw.publisher.Publish(ctx, webhook.UserUpdated, webhook.UserData{UserId: user_id, ProfileDeleted: true})
+	}
+	return res, err
+}
+
+func (mw *webhookMiddleware) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	return mw.next.SearchUsers(ctx, email, limit)
+}
@@ -0,0 +1,130 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/service"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWebhookMiddleware(t *testing.T) {
+	details := service.GenenerateDetails()
+
+	test_cases := []struct {
+		test_name  string
+		prepare    func(repository_mock *service.RepoMock)
+		act        func(ctx context.Context, http_service service.HttpService) error
+		event_type string
+		data       webhook.UserData
+	}{
+		{
+			test_name: "user created",
+			prepare: func(repository_mock *service.RepoMock) {
+				repository_mock.On("CreateUser", mock.Anything, mock.Anything).Return(1, nil)
+			},
+			act: func(ctx context.Context, http_service service.HttpService) error {
+				_, err := http_service.CreateUser(ctx, "user@email.com", "qwerty1", 23, details)
+				return err
+			},
+			event_type: webhook.UserCreated,
+			data:       webhook.UserData{UserId: 1, Email: "user@email.com", Age: 23, Profile: &details},
+		},
+		{
+			test_name: "user not created",
+			prepare: func(repository_mock *service.RepoMock) {
+				repository_mock.On("CreateUser", mock.Anything, mock.Anything).Return(-1, status.Error(codes.FailedPrecondition, "Missing field 'email'"))
+			},
+			act: func(ctx context.Context, http_service service.HttpService) error {
+				_, err := http_service.CreateUser(ctx, "", "qwerty1", 23, details)
+				return err
+			},
+		},
+		{
+			test_name: "account updated",
+			prepare: func(repository_mock *service.RepoMock) {
+				repository_mock.On("UpdateAccount", mock.Anything, mock.Anything).Return(true, nil)
+			},
+			act: func(ctx context.Context, http_service service.HttpService) error {
+				_, err := http_service.UpdateAccount(ctx, 1, "new@email.com", "qwerty1", 24)
+				return err
+			},
+			event_type: webhook.UserUpdated,
+			data:       webhook.UserData{UserId: 1, Email: "new@email.com", Age: 24},
+		},
+		{
+			test_name: "profile deleted",
+			prepare: func(repository_mock *service.RepoMock) {
+				repository_mock.On("DeleteUserDetails", mock.Anything, 1).Return(true, nil)
+			},
+			act: func(ctx context.Context, http_service service.HttpService) error {
+				_, err := http_service.DeleteUserDetails(ctx, 1)
+				return err
+			},
+			event_type: webhook.UserUpdated,
+			data:       webhook.UserData{UserId: 1, ProfileDeleted: true},
+		},
+		{
+			test_name: "user deleted",
+			prepare: func(repository_mock *service.RepoMock) {
+				repository_mock.On("DeleteUser", mock.Anything, 1).Return(true, nil)
+			},
+			act: func(ctx context.Context, http_service service.HttpService) error {
+				_, err := http_service.DeleteUser(ctx, 1)
+				return err
+			},
+			event_type: webhook.UserDeleted,
+			data:       webhook.UserData{UserId: 1},
+		},
+		{
+			test_name: "user not deleted",
+			prepare: func(repository_mock *service.RepoMock) {
+				repository_mock.On("DeleteUser", mock.Anything, 2).Return(false, status.Error(codes.NotFound, "User not found"))
+			},
+			act: func(ctx context.Context, http_service service.HttpService) error {
+				_, err := http_service.DeleteUser(ctx, 2)
+				return err
+			},
+		},
+		{
+			test_name: "user read",
+			prepare: func(repository_mock *service.RepoMock) {
+				repository_mock.On("GetUser", mock.Anything, 1).Return(entities.User{Email: "user@email.com"}, nil)
+			},
+			act: func(ctx context.Context, http_service service.HttpService) error {
+				_, err := http_service.GetUser(ctx, 1)
+				return err
+			},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			ctx := context.Background()
+			assert := assert.New(t)
+			repository_mock := new(service.RepoMock)
+			publisher_mock := new(service.PublisherMock)
+			http_service := service.WebhookMiddleware(publisher_mock)(service.NewHttpService(repository_mock, service.InitLogger()))
+			tc.prepare(repository_mock)
+			publisher_mock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return()
+
+			// act
+			tc.act(ctx, http_service)
+
+			// assert
+			if tc.event_type == "" {
+				publisher_mock.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			publisher_mock.AssertNumberOfCalls(t, "Publish", 1)
+			assert.Equal(tc.event_type, publisher_mock.Calls[0].Arguments.String(1))
+			assert.Equal(tc.data, publisher_mock.Calls[0].Arguments.Get(2))
+		})
+	}
+}
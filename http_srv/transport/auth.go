@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/mauricioww/user_microsrv/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const authenticateMethod = "/gateway.UserGateway/Authenticate"
+
+// verifyToken accepts the "Bearer <jwt>" value of an Authorization header
+// or metadata key, signed the way the service signs /auth tokens.
+func verifyToken(authorization string) error {
+	header_token := strings.Split(authorization, "Bearer ")
+	if len(header_token) != 2 {
+		return errors.NewUnauthorizedError()
+	}
+
+	token, err := jwt.Parse(header_token[1], func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte("this_is_a_secret_shhh"), nil
+	})
+	if err != nil {
+		return errors.NewUnauthorizedError().Wrap(err)
+	}
+	if _, ok := token.Claims.(jwt.MapClaims); !ok || !token.Valid {
+		return errors.NewUnauthorizedError()
+	}
+	return nil
+}
+
+// GatewayAuthInterceptor is authMiddleware for the gateway: every method
+// but Authenticate needs an "authorization" metadata entry.
+func GatewayAuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if info.FullMethod != authenticateMethod {
+			var authorization string
+			if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+				authorization = md.Get("authorization")[0]
+			}
+			if err := verifyToken(authorization); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,164 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestBodies(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(),
+		transport.WithGraphQL(srv_mock), transport.WithMaxBodyBytes(128))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	large := `{"email": "user@email.com", "password": "qwerty123", "age": 23, "profile": {"country": "MX", "city": "` + strings.Repeat("a", 100) + `"}}`
+
+	test_cases := []struct {
+		test_name      string
+		method         string
+		path           string
+		content_type   string
+		body           io.Reader
+		httpStatus     int
+		reason         string
+		invalid_params []transport.InvalidParam
+	}{
+		{
+			test_name:    "unknown field",
+			method:       "PUT",
+			path:         "/v2/users/1",
+			content_type: "application/json",
+			body:         strings.NewReader(`{"email": "user@email.com", "password": "qwerty123", "age": 23, "nickname": "user"}`),
+			httpStatus:   400,
+			reason:       errors.ReasonInvalidArgument,
+			invalid_params: []transport.InvalidParam{
+				{Name: "nickname", Reason: "is not a known field"},
+			},
+		},
+		{
+			test_name:    "unknown field in msgpack",
+			method:       "PUT",
+			path:         "/v2/users/1/profile",
+			content_type: "application/msgpack",
+			// {"country": "MX", "state": "NL"}
+			body:       strings.NewReader("\x82\xa7country\xa2MX\xa5state\xa2NL"),
+			httpStatus: 400,
+			reason:     errors.ReasonInvalidArgument,
+			invalid_params: []transport.InvalidParam{
+				{Name: "state", Reason: "is not a known field"},
+			},
+		},
+		{
+			test_name:    "wrong type",
+			method:       "PUT",
+			path:         "/v2/users/1",
+			content_type: "application/json",
+			body:         strings.NewReader(`{"email": "user@email.com", "password": "qwerty123", "age": "23"}`),
+			httpStatus:   400,
+			reason:       errors.ReasonInvalidArgument,
+			invalid_params: []transport.InvalidParam{
+				{Name: "age", Reason: "must be an integer"},
+			},
+		},
+		{
+			test_name:    "trailing data",
+			method:       "POST",
+			path:         "/v2/sessions",
+			content_type: "application/json",
+			body:         strings.NewReader(`{"email": "user@email.com", "password": "qwerty123"} {}`),
+			httpStatus:   400,
+			reason:       errors.ReasonInvalidArgument,
+			invalid_params: []transport.InvalidParam{
+				{Name: "body", Reason: "must hold a single value"},
+			},
+		},
+		{
+			test_name:  "missing content type",
+			method:     "POST",
+			path:       "/users",
+			body:       strings.NewReader(`{"email": "user@email.com", "password": "qwerty123"}`),
+			httpStatus: 415,
+			reason:     errors.ReasonUnsupportedMediaType,
+		},
+		{
+			test_name:  "missing content type without body",
+			method:     "PUT",
+			path:       "/v2/users/1/profile",
+			httpStatus: 415,
+			reason:     errors.ReasonUnsupportedMediaType,
+		},
+		{
+			test_name:    "graphql content type",
+			method:       "POST",
+			path:         transport.GraphQLPath,
+			content_type: "text/plain",
+			body:         strings.NewReader(`{"query": "{ user(id: 1) { email } }"}`),
+			httpStatus:   415,
+			reason:       errors.ReasonUnsupportedMediaType,
+		},
+		{
+			test_name:    "too large",
+			method:       "POST",
+			path:         "/v2/users",
+			content_type: "application/json",
+			body:         strings.NewReader(large),
+			httpStatus:   413,
+			reason:       errors.ReasonPayloadTooLarge,
+		},
+		{
+			test_name:    "too large without length",
+			method:       "POST",
+			path:         "/v2/users",
+			content_type: "application/json",
+			body:         io.MultiReader(strings.NewReader(large)),
+			httpStatus:   413,
+			reason:       errors.ReasonPayloadTooLarge,
+		},
+		{
+			test_name:    "graphql too large",
+			method:       "POST",
+			path:         transport.GraphQLPath,
+			content_type: "application/json",
+			body:         io.MultiReader(strings.NewReader(`{"query": "` + strings.Repeat(" ", 128) + `{ user(id: 1) { email } }"}`)),
+			httpStatus:   413,
+			reason:       errors.ReasonPayloadTooLarge,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, tc.body)
+			if tc.content_type != "" {
+				req.Header.Set("Content-Type", tc.content_type)
+			}
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+			var body transport.Problem
+			json.NewDecoder(res.Body).Decode(&body)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode)
+			assert.Equal(transport.ProblemContentType, res.Header.Get("Content-Type"))
+			assert.Equal(tc.reason, body.Reason)
+			assert.Equal(tc.invalid_params, body.InvalidParams)
+		})
+	}
+
+	assert.Empty(t, srv_mock.Calls)
+}
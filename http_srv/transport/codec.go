@@ -0,0 +1,339 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	gokit_http "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	JSONContentType     = "application/json"
+	ProtobufContentType = "application/x-protobuf"
+	MsgpackContentType  = "application/msgpack"
+)
+
+// codec reads and writes the request and response types in one media type.
+type codec struct {
+	media_type string
+	// invalid describes a body that does not decode
+	invalid   string
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+}
+
+// codecs are in the order the server prefers them when a client accepts
+// several equally.
+var codecs = []codec{
+	{
+		media_type: JSONContentType,
+		invalid:    "must be a valid JSON object",
+		marshal:    json.Marshal,
+		unmarshal:  unmarshalJSON,
+	},
+	{
+		media_type: ProtobufContentType,
+		invalid:    "must be a valid protobuf message",
+		marshal:    marshalProto,
+		unmarshal:  unmarshalProto,
+	},
+	{
+		media_type: MsgpackContentType,
+		invalid:    "must be a valid MessagePack map",
+		marshal:    marshalMsgpack,
+		unmarshal:  unmarshalMsgpack,
+	},
+}
+
+// codecsKey holds the codecs of the route serving a request, when
+// contentMiddleware narrowed them down.
+type codecsKey struct{}
+
+// only is the codecs for media_types, in the order of codecs.
+func only(media_types ...string) []codec {
+	var res []codec
+	for _, c := range codecs {
+		if contains(media_types, c.media_type) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// codecsOf is the codecs of the route ctx belongs to, all of them unless
+// contentMiddleware said otherwise.
+func codecsOf(ctx context.Context) []codec {
+	if supported, ok := ctx.Value(codecsKey{}).([]codec); ok {
+		return supported
+	}
+	return codecs
+}
+
+func mediaTypes(supported []codec) []string {
+	res := make([]string, len(supported))
+	for i, c := range supported {
+		res[i] = c.media_type
+	}
+	return res
+}
+
+// negotiate picks among supported the codec for an Accept header: the one
+// whose most specific matching range has the highest quality. No header
+// means the first of them, JSON.
+func negotiate(accept string, supported []codec) (codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return supported[0], true
+	}
+
+	ranges := parseAccept(accept)
+	best, best_q := -1, 0.0
+	for i, c := range supported {
+		if q := quality(ranges, c.media_type); q > best_q {
+			best, best_q = i, q
+		}
+	}
+	if best < 0 {
+		return codec{}, false
+	}
+	return supported[best], true
+}
+
+type mediaRange struct {
+	media string
+	q     float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var res []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		media, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		res = append(res, mediaRange{media: media, q: q})
+	}
+	return res
+}
+
+// quality is the q of the most specific range matching media_type, so
+// "application/json;q=0, */*" rules JSON out; 0 when none matches.
+func quality(ranges []mediaRange, media_type string) float64 {
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.media == media_type:
+			s = 2
+		case strings.HasSuffix(r.media, "/*") && strings.HasPrefix(media_type, strings.TrimSuffix(r.media, "*")):
+			s = 1
+		case r.media == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// codecFor picks among supported the codec for a Content-Type header;
+// bodies without one are JSON, as they always were.
+func codecFor(content_type string, supported []codec) (codec, bool) {
+	if content_type == "" {
+		return supported[0], true
+	}
+	media, _, err := mime.ParseMediaType(content_type)
+	if err != nil {
+		return codec{}, false
+	}
+	for _, c := range supported {
+		if c.media_type == media {
+			return c, true
+		}
+	}
+	return codec{}, false
+}
+
+func hasBody(r *http.Request) bool {
+	return r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody
+}
+
+// declaresContent tells the methods whose body must name its media type;
+// v1 /auth, a GET with a body, predates the rule and keeps defaulting to
+// JSON.
+func declaresContent(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// contentMiddleware answers 406 and 415 before the request reaches an
+// endpoint, so nothing is changed for a response the client cannot read.
+// The routes under it read and write supported alone, the first being the
+// default.
+func contentMiddleware(supported []codec) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), gokit_http.ContextKeyRequestPath, r.URL.Path)
+			if _, ok := negotiate(r.Header.Get("Accept"), supported); !ok {
+				encodeError(ctx, errors.NewNotAcceptableError(mediaTypes(supported)...), rw)
+				return
+			}
+			content_type := r.Header.Get("Content-Type")
+			if _, ok := codecFor(content_type, supported); (hasBody(r) && !ok) || (declaresContent(r.Method) && content_type == "") {
+				encodeError(ctx, errors.NewUnsupportedMediaTypeError(mediaTypes(supported)...), rw)
+				return
+			}
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), codecsKey{}, supported)))
+		})
+	}
+}
+
+// decodeBody reads the body of r into v in the format Content-Type names.
+func decodeBody(r *http.Request, v interface{}) error {
+	supported := codecsOf(r.Context())
+	c, ok := codecFor(r.Header.Get("Content-Type"), supported)
+	if !ok {
+		return errors.NewUnsupportedMediaTypeError(mediaTypes(supported)...)
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if err := c.unmarshal(body, v); err != nil {
+		var e *errors.Error
+		if stderrors.As(err, &e) {
+			return e
+		}
+		return invalidBody(c, err)
+	}
+	return nil
+}
+
+type bodyLimitKey struct{}
+
+// bodyLimitMiddleware caps request bodies at limit bytes, answering 413 up
+// front when Content-Length already tells; readBody catches the rest.
+func bodyLimitMiddleware(limit int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				ctx := context.WithValue(r.Context(), gokit_http.ContextKeyRequestPath, r.URL.Path)
+				encodeError(ctx, errors.NewPayloadTooLargeError(limit), rw)
+				return
+			}
+			r.Body = http.MaxBytesReader(rw, r.Body, limit)
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), bodyLimitKey{}, limit)))
+		})
+	}
+}
+
+// readBody reads the whole body of r. http.MaxBytesReader fails once the
+// limit has been read, which is how a body over it is told apart.
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		return body, nil
+	}
+	if limit, ok := r.Context().Value(bodyLimitKey{}).(int64); ok && int64(len(body)) >= limit {
+		return nil, errors.NewPayloadTooLargeError(limit)
+	}
+	return nil, errors.NewInvalidArgumentError(errors.FieldViolation{Field: "body", Description: "could not be read"}).Wrap(err)
+}
+
+var errTrailingData = stderrors.New("transport: data after the JSON value")
+
+// unmarshalJSON is json.Unmarshal refusing unknown fields, which are mostly
+// misspelt ones the client expects to be applied, and anything after the
+// value.
+func unmarshalJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
+// invalidBody names the field at fault when the decoder tells it, and the
+// body as a whole otherwise.
+func invalidBody(c codec, err error) error {
+	violation := errors.FieldViolation{Field: "body", Description: c.invalid}
+
+	var type_err *json.UnmarshalTypeError
+	switch {
+	case stderrors.As(err, &type_err) && type_err.Field != "":
+		violation = errors.FieldViolation{Field: type_err.Field, Description: "must be " + jsonType(type_err.Type)}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// the json package has no error type for it
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		violation = errors.FieldViolation{Field: field, Description: "is not a known field"}
+	case err == errTrailingData:
+		violation.Description = "must hold a single value"
+	}
+	return errors.NewInvalidArgumentError(violation).Wrap(err)
+}
+
+// jsonType describes t the way a JSON client knows it.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+func marshalMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	// the same field names as JSON; the embedded structs JSON nests are
+	// tagged noinline
+	enc.SetCustomStructTag("json")
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+// unmarshalMsgpack goes through the JSON form of the body: most encoders
+// write every float as a float64, which msgpack would not decode into the
+// float32 fields, while JSON numbers fit any numeric field.
+func unmarshalMsgpack(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := msgpack.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return unmarshalJSON(body, v)
+}
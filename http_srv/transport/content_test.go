@@ -0,0 +1,375 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/gatewaypb"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestContentFormats(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithOpenAPIValidation())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	city := "Monterrey"
+	details := entities.Details{Country: "MX", City: "CDMX", Height: 1.75}
+	details_pb := &gatewaypb.Details{Country: "MX", City: "CDMX", HeightM: 1.75}
+	details_json := `{"country": "MX", "city": "CDMX", "mobile_number": "", "married": false, "height_m": 1.75, "weight_kg": 0}`
+	srv_mock.On("CreateUser", mock.Anything, "user@email.com", "qwerty123", 23, details).Return(7, nil)
+	srv_mock.On("Authenticate", mock.Anything, "user@email.com", "qwerty123").Return("token", nil)
+	srv_mock.On("GetUser", mock.Anything, 7).Return(entities.User{Email: "user@email.com", Password: "qwerty123", Age: 23, Details: details}, nil)
+	srv_mock.On("UpdateUser", mock.Anything, 7, "user@email.com", "qwerty123", 24, details).Return(true, nil)
+	srv_mock.On("DeleteUser", mock.Anything, 7).Return(true, nil)
+	srv_mock.On("GetAccount", mock.Anything, 7).Return(entities.Account{Id: 7, Email: "user@email.com", Age: 23}, nil)
+	srv_mock.On("UpdateAccount", mock.Anything, 7, "user@email.com", "qwerty123", 24).Return(true, nil)
+	srv_mock.On("GetUserDetails", mock.Anything, 7).Return(details, nil)
+	srv_mock.On("SetUserDetails", mock.Anything, 7, details).Return(true, nil)
+	srv_mock.On("PatchUserDetails", mock.Anything, 7, entities.DetailsPatch{City: &city}).
+		Return(entities.Details{Country: "MX", City: city, Height: 1.75}, nil)
+	srv_mock.On("DeleteUserDetails", mock.Anything, 7).Return(true, nil)
+
+	patched_json := `{"country": "MX", "city": "Monterrey", "mobile_number": "", "married": false, "height_m": 1.75, "weight_kg": 0}`
+	patched_pb := &gatewaypb.Details{Country: "MX", City: "Monterrey", HeightM: 1.75}
+
+	test_cases := []struct {
+		test_name  string
+		method     string
+		paths      []string
+		body       string
+		body_pb    proto.Message
+		httpStatus int
+		res        string
+		res_pb     proto.Message
+	}{
+		{
+			test_name:  "create user",
+			method:     "POST",
+			paths:      []string{"/users", "/v1/users"},
+			body:       `{"email": "user@email.com", "password": "qwerty123", "age": 23, "information": ` + details_json + `}`,
+			body_pb:    &gatewaypb.CreateUserRequest{Email: "user@email.com", Password: "qwerty123", Age: 23, Details: details_pb},
+			httpStatus: 200,
+			res:        `{"user_id": 7, "email": "user@email.com", "password": "qwerty123", "age": 23, "information": ` + details_json + `}`,
+			res_pb:     &gatewaypb.User{Id: 7, Email: "user@email.com", Age: 23, Details: details_pb},
+		},
+		{
+			test_name:  "authenticate",
+			method:     "GET",
+			paths:      []string{"/auth", "/v1/auth"},
+			body:       `{"email": "user@email.com", "password": "qwerty123"}`,
+			body_pb:    &gatewaypb.AuthenticateRequest{Email: "user@email.com", Password: "qwerty123"},
+			httpStatus: 200,
+			res:        `{"token": "token"}`,
+			res_pb:     &gatewaypb.AuthenticateResponse{Token: "token"},
+		},
+		{
+			test_name:  "get user",
+			method:     "GET",
+			paths:      []string{"/users/7", "/v1/users/7"},
+			httpStatus: 200,
+			res:        `{"user_id": 7, "email": "user@email.com", "password": "qwerty123", "age": 23, "information": ` + details_json + `}`,
+			res_pb:     &gatewaypb.User{Id: 7, Email: "user@email.com", Age: 23, Details: details_pb},
+		},
+		{
+			test_name:  "update user",
+			method:     "PUT",
+			paths:      []string{"/users/7", "/v1/users/7"},
+			body:       `{"email": "user@email.com", "password": "qwerty123", "age": 24, "information": ` + details_json + `}`,
+			body_pb:    &gatewaypb.UpdateUserRequest{Email: "user@email.com", Password: "qwerty123", Age: 24, Details: details_pb},
+			httpStatus: 200,
+			res:        `{"success": true}`,
+			res_pb:     &gatewaypb.UpdateUserResponse{Success: true},
+		},
+		{
+			test_name:  "delete user",
+			method:     "DELETE",
+			paths:      []string{"/users/7", "/v1/users/7"},
+			httpStatus: 200,
+			res:        `{"success": true}`,
+			res_pb:     &gatewaypb.DeleteUserResponse{Success: true},
+		},
+		{
+			test_name:  "get details",
+			method:     "GET",
+			paths:      []string{"/users/7/details", "/v1/users/7/details", "/v2/users/7/profile"},
+			httpStatus: 200,
+			res:        details_json,
+			res_pb:     details_pb,
+		},
+		{
+			test_name:  "set details",
+			method:     "PUT",
+			paths:      []string{"/users/7/details", "/v1/users/7/details", "/v2/users/7/profile"},
+			body:       details_json,
+			body_pb:    details_pb,
+			httpStatus: 200,
+			res:        details_json,
+			res_pb:     details_pb,
+		},
+		{
+			test_name:  "patch details",
+			method:     "PATCH",
+			paths:      []string{"/users/7/details", "/v1/users/7/details", "/v2/users/7/profile"},
+			body:       `{"city": "Monterrey"}`,
+			body_pb:    &gatewaypb.DetailsPatch{City: proto.String("Monterrey")},
+			httpStatus: 200,
+			res:        patched_json,
+			res_pb:     patched_pb,
+		},
+		{
+			test_name:  "delete details",
+			method:     "DELETE",
+			paths:      []string{"/users/7/details", "/v1/users/7/details", "/v2/users/7/profile"},
+			httpStatus: 204,
+		},
+		{
+			test_name:  "create session",
+			method:     "POST",
+			paths:      []string{"/v2/sessions"},
+			body:       `{"email": "user@email.com", "password": "qwerty123"}`,
+			body_pb:    &gatewaypb.AuthenticateRequest{Email: "user@email.com", Password: "qwerty123"},
+			httpStatus: 201,
+			res:        `{"token": "token"}`,
+			res_pb:     &gatewaypb.AuthenticateResponse{Token: "token"},
+		},
+		{
+			test_name:  "create account",
+			method:     "POST",
+			paths:      []string{"/v2/users"},
+			body:       `{"email": "user@email.com", "password": "qwerty123", "age": 23, "profile": ` + details_json + `}`,
+			body_pb:    &gatewaypb.CreateUserRequest{Email: "user@email.com", Password: "qwerty123", Age: 23, Details: details_pb},
+			httpStatus: 201,
+			res:        `{"id": 7, "email": "user@email.com", "age": 23}`,
+			res_pb:     &gatewaypb.Account{Id: 7, Email: "user@email.com", Age: 23},
+		},
+		{
+			test_name:  "get account",
+			method:     "GET",
+			paths:      []string{"/v2/users/7"},
+			httpStatus: 200,
+			res:        `{"id": 7, "email": "user@email.com", "age": 23}`,
+			res_pb:     &gatewaypb.Account{Id: 7, Email: "user@email.com", Age: 23},
+		},
+		{
+			test_name:  "update account",
+			method:     "PUT",
+			paths:      []string{"/v2/users/7"},
+			body:       `{"email": "user@email.com", "password": "qwerty123", "age": 24}`,
+			body_pb:    &gatewaypb.UpdateAccountRequest{Email: "user@email.com", Password: "qwerty123", Age: 24},
+			httpStatus: 200,
+			res:        `{"id": 7, "email": "user@email.com", "age": 24}`,
+			res_pb:     &gatewaypb.Account{Id: 7, Email: "user@email.com", Age: 24},
+		},
+		{
+			test_name:  "delete account",
+			method:     "DELETE",
+			paths:      []string{"/v2/users/7"},
+			httpStatus: 204,
+		},
+	}
+
+	for _, media_type := range []string{transport.JSONContentType, transport.ProtobufContentType, transport.MsgpackContentType} {
+		for _, tc := range test_cases {
+			for _, path := range tc.paths {
+				t.Run(media_type+" "+tc.test_name+" "+path, func(t *testing.T) {
+					// prepare
+					assert := assert.New(t)
+					body := encodeBody(t, media_type, tc.body, tc.body_pb)
+					req, _ := http.NewRequest(tc.method, server.URL+path, bytes.NewReader(body))
+					req.Header.Set("Accept", media_type)
+					if tc.body != "" {
+						req.Header.Set("Content-Type", media_type)
+					}
+
+					// act
+					res, _ := http.DefaultClient.Do(req)
+					res_body, _ := ioutil.ReadAll(res.Body)
+
+					// assert
+					assert.Equal(tc.httpStatus, res.StatusCode, string(res_body))
+					assert.Contains(res.Header.Values("Vary"), "Accept")
+					if tc.httpStatus == http.StatusNoContent {
+						assert.Empty(res_body)
+						assert.Empty(res.Header.Get("Content-Type"))
+						return
+					}
+					assert.Equal(media_type, res.Header.Get("Content-Type"))
+					switch media_type {
+					case transport.JSONContentType:
+						assert.JSONEq(tc.res, string(res_body))
+					case transport.ProtobufContentType:
+						got := tc.res_pb.ProtoReflect().New().Interface()
+						assert.NoError(proto.Unmarshal(res_body, got))
+						assert.True(proto.Equal(tc.res_pb, got), "got %v", got)
+					case transport.MsgpackContentType:
+						var got interface{}
+						assert.NoError(msgpack.Unmarshal(res_body, &got))
+						got_json, _ := json.Marshal(got)
+						assert.JSONEq(tc.res, string(got_json))
+					}
+				})
+			}
+		}
+	}
+}
+
+// encodeBody writes the JSON body of a case, or its message, in media_type.
+func encodeBody(t *testing.T, media_type string, body string, body_pb proto.Message) []byte {
+	if body == "" {
+		return nil
+	}
+
+	switch media_type {
+	case transport.ProtobufContentType:
+		res, err := proto.Marshal(body_pb)
+		assert.NoError(t, err)
+		return res
+	case transport.MsgpackContentType:
+		var v interface{}
+		assert.NoError(t, json.Unmarshal([]byte(body), &v))
+		res, err := msgpack.Marshal(v)
+		assert.NoError(t, err)
+		return res
+	default:
+		return []byte(body)
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithOpenAPIValidation())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("GetAccount", mock.Anything, 7).Return(entities.Account{Id: 7, Email: "user@email.com", Age: 23}, nil)
+	srv_mock.On("GetAccount", mock.Anything, 404).Return(entities.Account{}, errors.NewUserNotFoundError())
+
+	test_cases := []struct {
+		test_name    string
+		method       string
+		path         string
+		accept       string
+		content_type string
+		body         string
+		httpStatus   int
+		res_type     string
+		reason       string
+	}{
+		{
+			test_name:  "no accept",
+			method:     "GET",
+			path:       "/v2/users/7",
+			httpStatus: 200,
+			res_type:   transport.JSONContentType,
+		},
+		{
+			test_name:  "anything",
+			method:     "GET",
+			path:       "/v2/users/7",
+			accept:     "*/*",
+			httpStatus: 200,
+			res_type:   transport.JSONContentType,
+		},
+		{
+			test_name:  "preferred by quality",
+			method:     "GET",
+			path:       "/v2/users/7",
+			accept:     "application/json;q=0.5, application/msgpack",
+			httpStatus: 200,
+			res_type:   transport.MsgpackContentType,
+		},
+		{
+			test_name:  "json ruled out",
+			method:     "GET",
+			path:       "/v2/users/7",
+			accept:     "application/json;q=0, application/*",
+			httpStatus: 200,
+			res_type:   transport.ProtobufContentType,
+		},
+		{
+			test_name:  "not acceptable",
+			method:     "GET",
+			path:       "/users/7",
+			accept:     "application/xml",
+			httpStatus: 406,
+			res_type:   transport.ProblemContentType,
+			reason:     errors.ReasonNotAcceptable,
+		},
+		{
+			test_name:    "unsupported media type",
+			method:       "PUT",
+			path:         "/v2/users/7",
+			content_type: "application/xml",
+			body:         `<account/>`,
+			httpStatus:   415,
+			res_type:     transport.ProblemContentType,
+			reason:       errors.ReasonUnsupportedMediaType,
+		},
+		{
+			test_name:    "invalid message",
+			method:       "PUT",
+			path:         "/v2/users/7",
+			content_type: transport.ProtobufContentType,
+			body:         "\xff\xff",
+			httpStatus:   400,
+			res_type:     transport.ProblemContentType,
+			reason:       errors.ReasonInvalidArgument,
+		},
+		{
+			test_name:  "problems stay json",
+			method:     "GET",
+			path:       "/v2/users/404",
+			accept:     transport.ProtobufContentType,
+			httpStatus: 404,
+			res_type:   transport.ProblemContentType,
+			reason:     errors.ReasonUserNotFound,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, bytes.NewReader([]byte(tc.body)))
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			if tc.content_type != "" {
+				req.Header.Set("Content-Type", tc.content_type)
+			}
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(res.Body)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode, string(body))
+			assert.Equal(tc.res_type, res.Header.Get("Content-Type"))
+			if tc.reason != "" {
+				var p transport.Problem
+				assert.NoError(json.Unmarshal(body, &p))
+				assert.Equal(tc.reason, p.Reason)
+			}
+		})
+	}
+
+	srv_mock.AssertNotCalled(t, "UpdateAccount", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -16,7 +16,7 @@ type (
 	}
 
 	UpdateUserRequest struct {
-		UserId           int
+		UserId           int    `json:"-"`
 		Email            string `json:"email"`
 		Password         string `json:"password"`
 		Age              int    `json:"age"`
@@ -24,10 +24,74 @@ type (
 	}
 
 	GetUserRequest struct {
-		UserId int
+		UserId int `json:"-"`
 	}
 
 	DeleteUserRequest struct {
-		UserId int
+		UserId int `json:"-"`
+	}
+
+	CreateSessionRequest struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	CreateAccountRequest struct {
+		Email    string           `json:"email"`
+		Password string           `json:"password"`
+		Age      int              `json:"age"`
+		Profile  entities.Details `json:"profile"`
+	}
+
+	GetAccountRequest struct {
+		UserId int `json:"-"`
+	}
+
+	UpdateAccountRequest struct {
+		UserId   int    `json:"-"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Age      int    `json:"age"`
+	}
+
+	DeleteAccountRequest struct {
+		UserId int `json:"-"`
+	}
+
+	GetProfileRequest struct {
+		UserId int `json:"-"`
+	}
+
+	SetProfileRequest struct {
+		UserId int `json:"-"`
+		entities.Details
+	}
+
+	PatchProfileRequest struct {
+		UserId int `json:"-"`
+		entities.DetailsPatch
+	}
+
+	DeleteProfileRequest struct {
+		UserId int `json:"-"`
+	}
+
+	CreateWebhookRequest struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+
+	GetWebhookRequest struct {
+		Id string `json:"-"`
+	}
+
+	DeleteWebhookRequest struct {
+		Id string `json:"-"`
+	}
+
+	ListDeliveriesRequest struct {
+		WebhookId string `json:"-"`
+		Status    string `json:"-"`
 	}
 )
@@ -0,0 +1,165 @@
+package transport_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDetailsRoutes(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithOpenAPIValidation())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	city, married := "Monterrey", true
+	details := entities.Details{Country: "MX", City: "CDMX"}
+	srv_mock.On("GetUserDetails", mock.Anything, 1).Return(details, nil)
+	srv_mock.On("GetUserDetails", mock.Anything, 404).Return(entities.Details{}, errors.NewUserNotFoundError())
+	srv_mock.On("SetUserDetails", mock.Anything, 1, entities.Details{Country: "US", MobileNumber: "5512345678"}).Return(true, nil)
+	srv_mock.On("PatchUserDetails", mock.Anything, 1, entities.DetailsPatch{City: &city, Married: &married}).
+		Return(entities.Details{Country: "MX", City: city, Married: married}, nil)
+	srv_mock.On("PatchUserDetails", mock.Anything, 1, entities.DetailsPatch{}).Return(details, nil)
+	srv_mock.On("DeleteUserDetails", mock.Anything, 1).Return(true, nil)
+	srv_mock.On("UpdateAccount", mock.Anything, 1, "new@email.com", "qwerty123", 30).Return(true, nil)
+
+	test_cases := []struct {
+		test_name  string
+		method     string
+		path       string
+		body       string
+		httpStatus int
+		res        string
+	}{
+		{
+			test_name:  "get details",
+			method:     "GET",
+			path:       "/users/1/details",
+			httpStatus: 200,
+			res:        `{"country": "MX", "city": "CDMX", "mobile_number": "", "married": false, "height_m": 0, "weight_kg": 0}`,
+		},
+		{
+			test_name:  "missing details",
+			method:     "GET",
+			path:       "/v1/users/404/details",
+			httpStatus: 404,
+		},
+		{
+			test_name:  "replace details",
+			method:     "PUT",
+			path:       "/users/1/details",
+			body:       `{"country": "US", "mobile_number": "5512345678"}`,
+			httpStatus: 200,
+			res:        `{"country": "US", "city": "", "mobile_number": "5512345678", "married": false, "height_m": 0, "weight_kg": 0}`,
+		},
+		{
+			test_name:  "replace without country",
+			method:     "PUT",
+			path:       "/users/1/details",
+			body:       `{"city": "CDMX"}`,
+			httpStatus: 400,
+		},
+		{
+			test_name:  "patch details",
+			method:     "PATCH",
+			path:       "/users/1/details",
+			body:       `{"city": "Monterrey", "married": true, "country": null}`,
+			httpStatus: 200,
+			res:        `{"country": "MX", "city": "Monterrey", "mobile_number": "", "married": true, "height_m": 0, "weight_kg": 0}`,
+		},
+		{
+			test_name:  "empty patch",
+			method:     "PATCH",
+			path:       "/v2/users/1/profile",
+			body:       `{}`,
+			httpStatus: 200,
+			res:        `{"country": "MX", "city": "CDMX", "mobile_number": "", "married": false, "height_m": 0, "weight_kg": 0}`,
+		},
+		{
+			test_name:  "invalid patch",
+			method:     "PATCH",
+			path:       "/users/1/details",
+			body:       `{"country": "Mexico"}`,
+			httpStatus: 400,
+		},
+		{
+			test_name:  "delete details",
+			method:     "DELETE",
+			path:       "/users/1/details",
+			httpStatus: 204,
+		},
+		{
+			test_name:  "delete profile",
+			method:     "DELETE",
+			path:       "/v2/users/1/profile",
+			httpStatus: 204,
+		},
+		{
+			test_name:  "update account alone",
+			method:     "PUT",
+			path:       "/users/1",
+			body:       `{"email": "new@email.com", "password": "qwerty123", "age": 30}`,
+			httpStatus: 200,
+			res:        `{"success": true}`,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(res.Body)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode, string(body))
+			if tc.res != "" {
+				assert.JSONEq(tc.res, string(body))
+			}
+		})
+	}
+
+	// none of the routes above touch both services
+	srv_mock.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	srv_mock.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+	srv_mock.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything)
+}
+
+func TestDetailsSuccessor(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("GetUserDetails", mock.Anything, 1).Return(entities.Details{Country: "MX"}, nil)
+
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	res, _ := http.Get(server.URL + "/v1/users/1/details")
+
+	// assert
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal(`</v2/users/1/profile>; rel="successor-version"`, res.Header.Get("Link"))
+}
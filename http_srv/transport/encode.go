@@ -1,6 +1,12 @@
 package transport
 
-import "github.com/mauricioww/user_microsrv/http_srv/entities"
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+)
 
 type (
 	CreateUserResponse struct {
@@ -8,7 +14,7 @@ type (
 		Email            string `json:"email"`
 		Password         string `json:"password"`
 		Age              int    `json:"age"`
-		entities.Details `json:"information"`
+		entities.Details `json:"information" msgpack:"information,noinline"`
 	}
 
 	AuthenticateResponse struct {
@@ -24,10 +30,100 @@ type (
 		Email            string `json:"email"`
 		Password         string `json:"password"`
 		Age              int    `json:"age"`
-		entities.Details `json:"information"`
+		entities.Details `json:"information" msgpack:"information,noinline"`
 	}
 
 	DeleteUserResponse struct {
 		Success bool `json:"success"`
 	}
+
+	CreateSessionResponse struct {
+		Token string `json:"token"`
+	}
+
+	// AccountResponse is a /v2 user: the account alone, never its password.
+	AccountResponse struct {
+		Id    int    `json:"id"`
+		Email string `json:"email"`
+		Age   int    `json:"age"`
+	}
+
+	CreateAccountResponse struct {
+		AccountResponse
+	}
+
+	DeleteAccountResponse struct{}
+
+	ProfileResponse struct {
+		entities.Details
+	}
+
+	DeleteProfileResponse struct{}
+
+	// WebhookResponse is a subscription, never its secret.
+	WebhookResponse struct {
+		Id        string    `json:"id"`
+		URL       string    `json:"url"`
+		Events    []string  `json:"events"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	CreateWebhookResponse struct {
+		WebhookResponse
+	}
+
+	ListWebhooksResponse struct {
+		Webhooks []WebhookResponse `json:"webhooks"`
+	}
+
+	DeleteWebhookResponse struct{}
+
+	DeliveryResponse struct {
+		Id             string     `json:"id"`
+		EventId        string     `json:"event_id"`
+		EventType      string     `json:"event_type"`
+		Status         string     `json:"status"`
+		Attempts       int        `json:"attempts"`
+		LastStatusCode int        `json:"last_status_code,omitempty"`
+		LastError      string     `json:"last_error,omitempty"`
+		NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+		CreatedAt      time.Time  `json:"created_at"`
+		DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	}
+
+	ListDeliveriesResponse struct {
+		Deliveries []DeliveryResponse `json:"deliveries"`
+	}
 )
+
+func (CreateSessionResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (CreateAccountResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r CreateAccountResponse) Headers() http.Header {
+	return http.Header{"Location": {"/v2/users/" + strconv.Itoa(r.Id)}}
+}
+
+func (DeleteAccountResponse) StatusCode() int {
+	return http.StatusNoContent
+}
+
+func (DeleteProfileResponse) StatusCode() int {
+	return http.StatusNoContent
+}
+
+func (CreateWebhookResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r CreateWebhookResponse) Headers() http.Header {
+	return http.Header{"Location": {"/v2/webhooks/" + r.Id}}
+}
+
+func (DeleteWebhookResponse) StatusCode() int {
+	return http.StatusNoContent
+}
@@ -13,6 +13,17 @@ type HttpEndpoints struct {
 	UpdateUser   endpoint.Endpoint
 	GetUser      endpoint.Endpoint
 	DeleteUser   endpoint.Endpoint
+
+	// the /v2 resources
+	CreateSession endpoint.Endpoint
+	CreateAccount endpoint.Endpoint
+	GetAccount    endpoint.Endpoint
+	UpdateAccount endpoint.Endpoint
+	DeleteAccount endpoint.Endpoint
+	GetProfile    endpoint.Endpoint
+	SetProfile    endpoint.Endpoint
+	PatchProfile  endpoint.Endpoint
+	DeleteProfile endpoint.Endpoint
 }
 
 func MakeHttpEndpoints(http_srv service.HttpService) HttpEndpoints {
@@ -22,6 +33,16 @@ func MakeHttpEndpoints(http_srv service.HttpService) HttpEndpoints {
 		UpdateUser:   makeUpdateUserEndpoint(http_srv),
 		GetUser:      makeGetUserEndpoint(http_srv),
 		DeleteUser:   makeDeleteUserEndpont(http_srv),
+
+		CreateSession: makeCreateSessionEndpoint(http_srv),
+		CreateAccount: makeCreateAccountEndpoint(http_srv),
+		GetAccount:    makeGetAccountEndpoint(http_srv),
+		UpdateAccount: makeUpdateAccountEndpoint(http_srv),
+		DeleteAccount: makeDeleteAccountEndpoint(http_srv),
+		GetProfile:    makeGetProfileEndpoint(http_srv),
+		SetProfile:    makeSetProfileEndpoint(http_srv),
+		PatchProfile:  makePatchProfileEndpoint(http_srv),
+		DeleteProfile: makeDeleteProfileEndpoint(http_srv),
 	}
 }
 
@@ -41,9 +62,14 @@ func makeAuthenticateEndpoint(http_srv service.HttpService) endpoint.Endpoint {
 	}
 }
 
+// makeUpdateUserEndpoint leaves the details alone when the request has none.
 func makeUpdateUserEndpoint(http_srv service.HttpService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(UpdateUserRequest)
+		if !req.hasDetails() {
+			res, err := http_srv.UpdateAccount(ctx, req.UserId, req.Email, req.Password, req.Age)
+			return UpdateUserResponse{Success: res}, err
+		}
 		res, err := http_srv.UpdateUser(ctx, req.UserId, req.Email, req.Password, req.Age, req.Details)
 		return UpdateUserResponse{Success: res}, err
 	}
@@ -64,3 +90,75 @@ func makeDeleteUserEndpont(http_srv service.HttpService) endpoint.Endpoint {
 		return DeleteUserResponse{Success: res}, err
 	}
 }
+
+func makeCreateSessionEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateSessionRequest)
+		res, err := http_srv.Authenticate(ctx, req.Email, req.Password)
+		return CreateSessionResponse{Token: res}, err
+	}
+}
+
+func makeCreateAccountEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateAccountRequest)
+		res, err := http_srv.CreateUser(ctx, req.Email, req.Password, req.Age, req.Profile)
+		return CreateAccountResponse{AccountResponse{Id: res, Email: req.Email, Age: req.Age}}, err
+	}
+}
+
+func makeGetAccountEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetAccountRequest)
+		res, err := http_srv.GetAccount(ctx, req.UserId)
+		return AccountResponse{Id: req.UserId, Email: res.Email, Age: res.Age}, err
+	}
+}
+
+func makeUpdateAccountEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateAccountRequest)
+		_, err := http_srv.UpdateAccount(ctx, req.UserId, req.Email, req.Password, req.Age)
+		return AccountResponse{Id: req.UserId, Email: req.Email, Age: req.Age}, err
+	}
+}
+
+func makeDeleteAccountEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteAccountRequest)
+		_, err := http_srv.DeleteUser(ctx, req.UserId)
+		return DeleteAccountResponse{}, err
+	}
+}
+
+func makeGetProfileEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetProfileRequest)
+		res, err := http_srv.GetUserDetails(ctx, req.UserId)
+		return ProfileResponse{res}, err
+	}
+}
+
+func makeSetProfileEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SetProfileRequest)
+		_, err := http_srv.SetUserDetails(ctx, req.UserId, req.Details)
+		return ProfileResponse{req.Details}, err
+	}
+}
+
+func makePatchProfileEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(PatchProfileRequest)
+		res, err := http_srv.PatchUserDetails(ctx, req.UserId, req.DetailsPatch)
+		return ProfileResponse{res}, err
+	}
+}
+
+func makeDeleteProfileEndpoint(http_srv service.HttpService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteProfileRequest)
+		_, err := http_srv.DeleteUserDetails(ctx, req.UserId)
+		return DeleteProfileResponse{}, err
+	}
+}
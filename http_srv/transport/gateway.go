@@ -0,0 +1,245 @@
+package transport
+
+import (
+	"context"
+	stderrors "errors"
+
+	grpc_gokit "github.com/go-kit/kit/transport/grpc"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/gatewaypb"
+	"github.com/mauricioww/user_microsrv/validation"
+)
+
+type gatewayServer struct {
+	createUser   grpc_gokit.Handler
+	authenticate grpc_gokit.Handler
+	updateUser   grpc_gokit.Handler
+	getUser      grpc_gokit.Handler
+	deleteUser   grpc_gokit.Handler
+
+	gatewaypb.UnimplementedUserGatewayServer
+}
+
+// NewGatewayServer serves the HTTP endpoints over gRPC, so both APIs share
+// one service and its composition of user and details.
+func NewGatewayServer(http_endpoints HttpEndpoints) gatewaypb.UserGatewayServer {
+	return &gatewayServer{
+		createUser: grpc_gokit.NewServer(
+			http_endpoints.CreateUser,
+			decodeGatewayCreateUserRequest,
+			encodeGatewayCreateUserResponse,
+		),
+
+		authenticate: grpc_gokit.NewServer(
+			http_endpoints.Authenticate,
+			decodeGatewayAuthenticateRequest,
+			encodeGatewayAuthenticateResponse,
+		),
+
+		updateUser: grpc_gokit.NewServer(
+			http_endpoints.UpdateUser,
+			decodeGatewayUpdateUserRequest,
+			encodeGatewayUpdateUserResponse,
+		),
+
+		getUser: grpc_gokit.NewServer(
+			http_endpoints.GetUser,
+			decodeGatewayGetUserRequest,
+			encodeGatewayGetUserResponse,
+		),
+
+		deleteUser: grpc_gokit.NewServer(
+			http_endpoints.DeleteUser,
+			decodeGatewayDeleteUserRequest,
+			encodeGatewayDeleteUserResponse,
+		),
+	}
+}
+
+func decodeGatewayCreateUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	create_pb, ok := request.(*gatewaypb.CreateUserRequest)
+
+	if !ok {
+		return nil, stderrors.New("No proto message 'CreateUserRequest'")
+	}
+
+	req := CreateUserRequest{
+		Email:    create_pb.GetEmail(),
+		Password: create_pb.GetPassword(),
+		Age:      int(create_pb.GetAge()),
+		Details:  detailsFromPb(create_pb.GetDetails()),
+	}
+
+	return req, validateAccount("details", req.Email, req.Password, req.Age, req.Details)
+}
+
+func encodeGatewayCreateUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(CreateUserResponse)
+
+	return &gatewaypb.User{
+		Id:      int32(res.Id),
+		Email:   res.Email,
+		Age:     uint32(res.Age),
+		Details: detailsToPb(res.Details),
+	}, nil
+}
+
+func decodeGatewayAuthenticateRequest(_ context.Context, request interface{}) (interface{}, error) {
+	auth_pb, ok := request.(*gatewaypb.AuthenticateRequest)
+
+	if !ok {
+		return nil, stderrors.New("No proto message 'AuthenticateRequest'")
+	}
+
+	req := AuthenticateRequest{
+		Email:    auth_pb.GetEmail(),
+		Password: auth_pb.GetPassword(),
+	}
+
+	return req, validation.Validate(
+		validation.Check("email", req.Email, validation.Required),
+		validation.Check("password", req.Password, validation.Required),
+	)
+}
+
+func encodeGatewayAuthenticateResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(AuthenticateResponse)
+
+	return &gatewaypb.AuthenticateResponse{Token: res.Token}, nil
+}
+
+func decodeGatewayUpdateUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	update_pb, ok := request.(*gatewaypb.UpdateUserRequest)
+
+	if !ok {
+		return nil, stderrors.New("No proto message 'UpdateUserRequest'")
+	}
+
+	req := UpdateUserRequest{
+		UserId:   int(update_pb.GetId()),
+		Email:    update_pb.GetEmail(),
+		Password: update_pb.GetPassword(),
+		Age:      int(update_pb.GetAge()),
+		Details:  detailsFromPb(update_pb.GetDetails()),
+	}
+
+	return req, validateUpdate("details", req)
+}
+
+func encodeGatewayUpdateUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(UpdateUserResponse)
+
+	return &gatewaypb.UpdateUserResponse{Success: res.Success}, nil
+}
+
+func decodeGatewayGetUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	get_pb, ok := request.(*gatewaypb.GetUserRequest)
+
+	if !ok {
+		return nil, stderrors.New("No proto message 'GetUserRequest'")
+	}
+
+	return GetUserRequest{UserId: int(get_pb.GetId())}, nil
+}
+
+// encodeGatewayGetUserResponse leaves the stored password out; the HTTP
+// response only keeps it for existing clients.
+func encodeGatewayGetUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(GetUserResponse)
+
+	return &gatewaypb.User{
+		Id:      int32(res.Id),
+		Email:   res.Email,
+		Age:     uint32(res.Age),
+		Details: detailsToPb(res.Details),
+	}, nil
+}
+
+func decodeGatewayDeleteUserRequest(_ context.Context, request interface{}) (interface{}, error) {
+	delete_pb, ok := request.(*gatewaypb.DeleteUserRequest)
+
+	if !ok {
+		return nil, stderrors.New("No proto message 'DeleteUserRequest'")
+	}
+
+	return DeleteUserRequest{UserId: int(delete_pb.GetId())}, nil
+}
+
+func encodeGatewayDeleteUserResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(DeleteUserResponse)
+
+	return &gatewaypb.DeleteUserResponse{Success: res.Success}, nil
+}
+
+func detailsFromPb(d *gatewaypb.Details) entities.Details {
+	return entities.Details{
+		Country:      d.GetCountry(),
+		City:         d.GetCity(),
+		MobileNumber: d.GetMobileNumber(),
+		Married:      d.GetMarried(),
+		Height:       d.GetHeightM(),
+		Weight:       d.GetWeightKg(),
+	}
+}
+
+func detailsToPb(d entities.Details) *gatewaypb.Details {
+	return &gatewaypb.Details{
+		Country:      d.Country,
+		City:         d.City,
+		MobileNumber: d.MobileNumber,
+		Married:      d.Married,
+		HeightM:      d.Height,
+		WeightKg:     d.Weight,
+	}
+}
+
+func (g *gatewayServer) CreateUser(ctx context.Context, req *gatewaypb.CreateUserRequest) (*gatewaypb.User, error) {
+	_, res, err := g.createUser.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, errors.Translate(err)
+	}
+
+	return res.(*gatewaypb.User), nil
+}
+
+func (g *gatewayServer) Authenticate(ctx context.Context, req *gatewaypb.AuthenticateRequest) (*gatewaypb.AuthenticateResponse, error) {
+	_, res, err := g.authenticate.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, errors.Translate(err)
+	}
+
+	return res.(*gatewaypb.AuthenticateResponse), nil
+}
+
+func (g *gatewayServer) UpdateUser(ctx context.Context, req *gatewaypb.UpdateUserRequest) (*gatewaypb.UpdateUserResponse, error) {
+	_, res, err := g.updateUser.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, errors.Translate(err)
+	}
+
+	return res.(*gatewaypb.UpdateUserResponse), nil
+}
+
+func (g *gatewayServer) GetUser(ctx context.Context, req *gatewaypb.GetUserRequest) (*gatewaypb.User, error) {
+	_, res, err := g.getUser.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, errors.Translate(err)
+	}
+
+	return res.(*gatewaypb.User), nil
+}
+
+func (g *gatewayServer) DeleteUser(ctx context.Context, req *gatewaypb.DeleteUserRequest) (*gatewaypb.DeleteUserResponse, error) {
+	_, res, err := g.deleteUser.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, errors.Translate(err)
+	}
+
+	return res.(*gatewaypb.DeleteUserResponse), nil
+}
@@ -0,0 +1,268 @@
+package transport_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/gatewaypb"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func signedToken(secret string, expires time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "1",
+		"email":   "user@email.com",
+		"exp":     expires.Unix(),
+	})
+	res, _ := token.SignedString([]byte(secret))
+	return res
+}
+
+func newGatewayClient(srv_mock *transport.ServiceMock) (gatewaypb.UserGatewayClient, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(transport.GatewayAuthInterceptor()))
+	gatewaypb.RegisterUserGatewayServer(server, transport.NewGatewayServer(transport.MakeHttpEndpoints(srv_mock)))
+	go server.Serve(listener)
+
+	conn, _ := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+
+	return gatewaypb.NewUserGatewayClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestGatewayAuth(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	client, stop := newGatewayClient(srv_mock)
+	defer stop()
+
+	srv_mock.On("GetUser", mock.Anything, 1).Return(entities.User{Email: "user@email.com", Age: 20}, nil)
+
+	test_cases := []struct {
+		test_name     string
+		authorization string
+		code          codes.Code
+	}{
+		{
+			test_name:     "valid token",
+			authorization: "Bearer " + signedToken("this_is_a_secret_shhh", time.Now().Add(time.Minute)),
+			code:          codes.OK,
+		},
+		{
+			test_name: "missing token",
+			code:      codes.Unauthenticated,
+		},
+		{
+			test_name:     "expired token",
+			authorization: "Bearer " + signedToken("this_is_a_secret_shhh", time.Now().Add(-time.Minute)),
+			code:          codes.Unauthenticated,
+		},
+		{
+			test_name:     "foreign signature",
+			authorization: "Bearer " + signedToken("another_secret", time.Now().Add(time.Minute)),
+			code:          codes.Unauthenticated,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			ctx := context.Background()
+			if tc.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tc.authorization)
+			}
+
+			// act
+			_, err := client.GetUser(ctx, &gatewaypb.GetUserRequest{Id: 1})
+
+			// assert
+			assert.Equal(tc.code, status.Code(err))
+			if tc.code == codes.Unauthenticated {
+				assert.Equal(errors.ReasonUnauthorized, errors.FromError(err).Reason)
+			}
+		})
+	}
+}
+
+func TestGatewayAuthenticateIsOpen(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	client, stop := newGatewayClient(srv_mock)
+	defer stop()
+
+	// prepare
+	assert := assert.New(t)
+	srv_mock.On("Authenticate", mock.Anything, "user@email.com", "qwerty123").Return("token", nil)
+
+	// act
+	res, err := client.Authenticate(context.Background(), &gatewaypb.AuthenticateRequest{Email: "user@email.com", Password: "qwerty123"})
+
+	// assert
+	assert.Nil(err)
+	assert.Equal("token", res.GetToken())
+}
+
+func TestGatewayComposesUser(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	client, stop := newGatewayClient(srv_mock)
+	defer stop()
+
+	details := entities.Details{Country: "MX", City: "CDMX", MobileNumber: "5512345678", Married: true, Height: 1.75, Weight: 76}
+	srv_mock.On("CreateUser", mock.Anything, "user@email.com", "qwerty123", 20, details).Return(1, nil)
+	srv_mock.On("GetUser", mock.Anything, 1).Return(entities.User{Email: "user@email.com", Password: "qwerty123", Age: 20, Details: details}, nil)
+	srv_mock.On("GetUser", mock.Anything, 2).Return(entities.User{}, errors.NewUserNotFoundError())
+
+	expected := &gatewaypb.User{
+		Id:    1,
+		Email: "user@email.com",
+		Age:   20,
+		Details: &gatewaypb.Details{
+			Country:      "MX",
+			City:         "CDMX",
+			MobileNumber: "5512345678",
+			Married:      true,
+			HeightM:      1.75,
+			WeightKg:     76,
+		},
+	}
+
+	// prepare
+	assert := assert.New(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+signedToken("this_is_a_secret_shhh", time.Now().Add(time.Minute)))
+
+	// act
+	created, create_err := client.CreateUser(ctx, &gatewaypb.CreateUserRequest{
+		Email:    "user@email.com",
+		Password: "qwerty123",
+		Age:      20,
+		Details:  expected.Details,
+	})
+	found, get_err := client.GetUser(ctx, &gatewaypb.GetUserRequest{Id: 1})
+	_, missing_err := client.GetUser(ctx, &gatewaypb.GetUserRequest{Id: 2})
+
+	// assert
+	assert.Nil(create_err)
+	assert.Equal(expected.String(), created.String())
+	assert.Nil(get_err)
+	assert.Equal(expected.String(), found.String())
+	assert.Equal(codes.NotFound, status.Code(missing_err))
+	assert.Equal(errors.ReasonUserNotFound, errors.FromError(missing_err).Reason)
+}
+
+func TestGatewayUpdateWithoutDetails(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	client, stop := newGatewayClient(srv_mock)
+	defer stop()
+
+	srv_mock.On("UpdateAccount", mock.Anything, 1, "user@email.com", "qwerty123", 21).Return(true, nil)
+
+	// prepare
+	assert := assert.New(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+signedToken("this_is_a_secret_shhh", time.Now().Add(time.Minute)))
+
+	// act
+	res, err := client.UpdateUser(ctx, &gatewaypb.UpdateUserRequest{Id: 1, Email: "user@email.com", Password: "qwerty123", Age: 21})
+
+	// assert
+	assert.Nil(err)
+	assert.True(res.GetSuccess())
+	srv_mock.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGatewayValidation(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	client, stop := newGatewayClient(srv_mock)
+	defer stop()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+signedToken("this_is_a_secret_shhh", time.Now().Add(time.Minute)))
+
+	// act
+	_, err := client.CreateUser(ctx, &gatewaypb.CreateUserRequest{
+		Email:    "not-an-email",
+		Password: "qwerty123",
+		Age:      20,
+		Details:  &gatewaypb.Details{Country: "MX", HeightM: 9},
+	})
+
+	// assert
+	assert.Equal(codes.FailedPrecondition, status.Code(err))
+	var fields []string
+	for _, v := range errors.FromError(err).Violations {
+		fields = append(fields, v.Field)
+	}
+	assert.Equal([]string{"email", "details.height_m"}, fields)
+	assert.Empty(srv_mock.Calls)
+}
+
+func TestHttpAuthentication(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithAuthentication())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("GetUser", mock.Anything, 1).Return(entities.User{Email: "user@email.com", Age: 20}, nil)
+
+	test_cases := []struct {
+		test_name     string
+		authorization string
+		httpStatus    int
+	}{
+		{
+			test_name:     "valid token",
+			authorization: "Bearer " + signedToken("this_is_a_secret_shhh", time.Now().Add(time.Minute)),
+			httpStatus:    http.StatusOK,
+		},
+		{
+			test_name:  "missing token",
+			httpStatus: http.StatusUnauthorized,
+		},
+		{
+			test_name:     "expired token",
+			authorization: "Bearer " + signedToken("this_is_a_secret_shhh", time.Now().Add(-time.Minute)),
+			httpStatus:    http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest("GET", server.URL+"/users/1", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			// act
+			res, err := http.DefaultClient.Do(req)
+
+			// assert
+			assert.Nil(err)
+			assert.Equal(tc.httpStatus, res.StatusCode)
+			if tc.httpStatus == http.StatusUnauthorized {
+				assert.Equal(transport.ProblemContentType, res.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,444 @@
+package transport
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	gokit_http "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/dataloader"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/service"
+	"github.com/mauricioww/user_microsrv/recovery"
+	"github.com/mauricioww/user_microsrv/validation"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	GraphQLPath = "/graphql"
+
+	// graphqlBatch bounds the ids of a users query, the resolvers running at
+	// once and so the size of a batch.
+	graphqlBatch = 100
+	graphqlWait  = 2 * time.Millisecond
+)
+
+//go:embed schema.graphql
+var graphql_schema string
+
+type (
+	graphqlParams struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	graphqlRequestKey struct{}
+
+	// graphqlRequest is what the resolvers share during one request.
+	graphqlRequest struct {
+		auth     error
+		accounts *dataloader.Loader
+		details  *dataloader.Loader
+	}
+
+	graphqlResolver struct {
+		http_srv service.HttpService
+	}
+
+	userResolver struct {
+		account entities.Account
+	}
+
+	detailsResolver struct {
+		details entities.Details
+	}
+
+	userInput struct {
+		Email    string
+		Password string
+		Age      int32
+		Details  detailsInput
+	}
+
+	detailsInput struct {
+		Country      string
+		City         *string
+		MobileNumber *string
+		Married      *bool
+		HeightM      *float64
+		WeightKg     *float64
+	}
+
+	// graphqlError exposes the typed error the way a problem would, under
+	// the GraphQL "extensions" member.
+	graphqlError struct {
+		problem Problem
+	}
+
+	graphqlLogger struct {
+		logger log.Logger
+	}
+
+	graphqlPanicHandler struct{}
+)
+
+// WithGraphQL serves GraphQLPath over http_srv, which the endpoints do not
+// expose on their own. WithAuthentication applies to every field but the
+// authenticate mutation.
+func WithGraphQL(http_srv service.HttpService) ServerOption {
+	return func(o *serverOptions) {
+		o.graphql = http_srv
+	}
+}
+
+func graphqlHandler(http_srv service.HttpService, auth bool, logger log.Logger) http.Handler {
+	schema := graphql.MustParseSchema(graphql_schema, &graphqlResolver{http_srv: http_srv},
+		graphql.MaxParallelism(graphqlBatch),
+		graphql.MaxDepth(10),
+		graphql.Logger(graphqlLogger{logger: logger}),
+		graphql.PanicHandler(graphqlPanicHandler{}),
+	)
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), gokit_http.ContextKeyRequestPath, r.URL.Path)
+
+		if media, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); media != JSONContentType {
+			encodeError(ctx, errors.NewUnsupportedMediaTypeError(JSONContentType), rw)
+			return
+		}
+		body, err := readBody(r)
+		if err != nil {
+			encodeError(ctx, err, rw)
+			return
+		}
+		// unknown members are let through: clients send extensions the
+		// server may ignore
+		var params graphqlParams
+		if err := json.Unmarshal(body, &params); err != nil {
+			encodeError(ctx, badBody(err), rw)
+			return
+		}
+
+		req := &graphqlRequest{}
+		if auth {
+			req.auth = verifyToken(r.Header.Get("Authorization"))
+		}
+		req.accounts = dataloader.New(ctx, func(ctx context.Context, keys []int) []dataloader.Result {
+			accounts, err := http_srv.GetAccounts(ctx, keys)
+			return results(keys, err, func(id int) (interface{}, bool) {
+				account, ok := accounts[id]
+				return account, ok
+			})
+		}, graphqlWait, graphqlBatch)
+		req.details = dataloader.New(ctx, func(ctx context.Context, keys []int) []dataloader.Result {
+			details, err := http_srv.GetUsersDetails(ctx, keys)
+			return results(keys, err, func(id int) (interface{}, bool) {
+				d, ok := details[id]
+				return d, ok
+			})
+		}, graphqlWait, graphqlBatch)
+
+		ctx = context.WithValue(ctx, graphqlRequestKey{}, req)
+		json.NewEncoder(rw).Encode(schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+	})
+}
+
+// results lines a batch call up with its keys: every key gets err when the
+// call failed, otherwise what found has for it or NotFound.
+func results(keys []int, err error, found func(id int) (interface{}, bool)) []dataloader.Result {
+	res := make([]dataloader.Result, len(keys))
+	for i, key := range keys {
+		if err != nil {
+			res[i].Err = err
+		} else if value, ok := found(key); ok {
+			res[i].Value = value
+		} else {
+			res[i].Err = errors.NewUserNotFoundError()
+		}
+	}
+	return res
+}
+
+func requestOf(ctx context.Context) *graphqlRequest {
+	return ctx.Value(graphqlRequestKey{}).(*graphqlRequest)
+}
+
+// authorized is authMiddleware for a single field.
+func authorized(ctx context.Context) error {
+	if err := requestOf(ctx).auth; err != nil {
+		return gqlError(ctx, err)
+	}
+	return nil
+}
+
+func (r *graphqlResolver) User(ctx context.Context, args struct{ Id graphql.ID }) (*userResolver, error) {
+	if err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	id, err := graphqlId("id", args.Id)
+	if err != nil {
+		return nil, gqlError(ctx, err)
+	}
+
+	account, err := requestOf(ctx).accounts.Load(ctx, id)
+	if errors.Code(err) == codes.NotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, gqlError(ctx, err)
+	}
+	return &userResolver{account: account.(entities.Account)}, nil
+}
+
+func (r *graphqlResolver) Users(ctx context.Context, args struct{ Ids []graphql.ID }) ([]*userResolver, error) {
+	if err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	if len(args.Ids) > graphqlBatch {
+		return nil, gqlError(ctx, errors.NewInvalidArgumentError(errors.FieldViolation{
+			Field:       "ids",
+			Description: "must have at most " + strconv.Itoa(graphqlBatch) + " items",
+		}))
+	}
+
+	ids := make([]int, len(args.Ids))
+	for i, raw := range args.Ids {
+		id, err := graphqlId("ids."+strconv.Itoa(i), raw)
+		if err != nil {
+			return nil, gqlError(ctx, err)
+		}
+		ids[i] = id
+	}
+
+	res := make([]*userResolver, len(ids))
+	for i, account := range requestOf(ctx).accounts.LoadMany(ctx, ids) {
+		if errors.Code(account.Err) == codes.NotFound {
+			continue
+		} else if account.Err != nil {
+			return nil, gqlError(ctx, account.Err)
+		}
+		res[i] = &userResolver{account: account.Value.(entities.Account)}
+	}
+	return res, nil
+}
+
+func (r *graphqlResolver) Search(ctx context.Context, args struct {
+	Email string
+	Limit *int32
+}) ([]*userResolver, error) {
+	if err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	var limit int
+	if args.Limit != nil {
+		limit = int(*args.Limit)
+	}
+	if err := validation.Validate(
+		validation.Check("email", args.Email, validation.Required, validation.MinLength(validation.MinSearchLength)),
+		validation.Check("limit", limit, validation.Optional(validation.Positive)),
+	); err != nil {
+		return nil, gqlError(ctx, err)
+	}
+
+	accounts, err := r.http_srv.SearchUsers(ctx, args.Email, limit)
+	if err != nil {
+		return nil, gqlError(ctx, err)
+	}
+
+	res := make([]*userResolver, len(accounts))
+	for i, account := range accounts {
+		requestOf(ctx).accounts.Prime(account.Id, account)
+		res[i] = &userResolver{account: account}
+	}
+	return res, nil
+}
+
+func (r *graphqlResolver) Authenticate(ctx context.Context, args struct {
+	Email    string
+	Password string
+}) (string, error) {
+	if err := validation.Validate(
+		validation.Check("email", args.Email, validation.Required),
+		validation.Check("password", args.Password, validation.Required),
+	); err != nil {
+		return "", gqlError(ctx, err)
+	}
+
+	token, err := r.http_srv.Authenticate(ctx, args.Email, args.Password)
+	if err != nil {
+		return "", gqlError(ctx, err)
+	}
+	return token, nil
+}
+
+func (r *graphqlResolver) CreateUser(ctx context.Context, args struct{ Input userInput }) (*userResolver, error) {
+	if err := authorized(ctx); err != nil {
+		return nil, err
+	}
+	details := args.Input.Details.entity()
+	if err := validateAccount("details", args.Input.Email, args.Input.Password, int(args.Input.Age), details); err != nil {
+		return nil, gqlError(ctx, err)
+	}
+
+	id, err := r.http_srv.CreateUser(ctx, args.Input.Email, args.Input.Password, int(args.Input.Age), details)
+	if err != nil {
+		return nil, gqlError(ctx, err)
+	}
+
+	account := entities.Account{Id: id, Email: args.Input.Email, Age: int(args.Input.Age)}
+	requestOf(ctx).accounts.Prime(id, account)
+	requestOf(ctx).details.Prime(id, details)
+	return &userResolver{account: account}, nil
+}
+
+func (r *graphqlResolver) UpdateUser(ctx context.Context, args struct {
+	Id    graphql.ID
+	Input userInput
+}) (bool, error) {
+	if err := authorized(ctx); err != nil {
+		return false, err
+	}
+	id, err := graphqlId("id", args.Id)
+	if err != nil {
+		return false, gqlError(ctx, err)
+	}
+	details := args.Input.Details.entity()
+	if err := validateAccount("details", args.Input.Email, args.Input.Password, int(args.Input.Age), details); err != nil {
+		return false, gqlError(ctx, err)
+	}
+
+	res, err := r.http_srv.UpdateUser(ctx, id, args.Input.Email, args.Input.Password, int(args.Input.Age), details)
+	if err != nil {
+		return false, gqlError(ctx, err)
+	}
+	return res, nil
+}
+
+func (r *graphqlResolver) DeleteUser(ctx context.Context, args struct{ Id graphql.ID }) (bool, error) {
+	if err := authorized(ctx); err != nil {
+		return false, err
+	}
+	id, err := graphqlId("id", args.Id)
+	if err != nil {
+		return false, gqlError(ctx, err)
+	}
+
+	res, err := r.http_srv.DeleteUser(ctx, id)
+	if err != nil {
+		return false, gqlError(ctx, err)
+	}
+	return res, nil
+}
+
+func (u *userResolver) Id() graphql.ID {
+	return graphql.ID(strconv.Itoa(u.account.Id))
+}
+
+func (u *userResolver) Email() string {
+	return u.account.Email
+}
+
+func (u *userResolver) Age() int32 {
+	return int32(u.account.Age)
+}
+
+// Details goes through the request's loader, so asking for the details of
+// many users costs one GetUsersDetails call rather than one round trip each.
+func (u *userResolver) Details(ctx context.Context) (*detailsResolver, error) {
+	details, err := requestOf(ctx).details.Load(ctx, u.account.Id)
+	if errors.Code(err) == codes.NotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, gqlError(ctx, err)
+	}
+	return &detailsResolver{details: details.(entities.Details)}, nil
+}
+
+func (d *detailsResolver) Country() string {
+	return d.details.Country
+}
+
+func (d *detailsResolver) City() string {
+	return d.details.City
+}
+
+func (d *detailsResolver) MobileNumber() string {
+	return d.details.MobileNumber
+}
+
+func (d *detailsResolver) Married() bool {
+	return d.details.Married
+}
+
+func (d *detailsResolver) HeightM() float64 {
+	return float64(d.details.Height)
+}
+
+func (d *detailsResolver) WeightKg() float64 {
+	return float64(d.details.Weight)
+}
+
+func (d detailsInput) entity() entities.Details {
+	res := entities.Details{Country: d.Country}
+	if d.City != nil {
+		res.City = *d.City
+	}
+	if d.MobileNumber != nil {
+		res.MobileNumber = *d.MobileNumber
+	}
+	if d.Married != nil {
+		res.Married = *d.Married
+	}
+	if d.HeightM != nil {
+		res.Height = float32(*d.HeightM)
+	}
+	if d.WeightKg != nil {
+		res.Weight = float32(*d.WeightKg)
+	}
+	return res
+}
+
+func graphqlId(field string, id graphql.ID) (int, error) {
+	res, err := strconv.Atoi(string(id))
+	if err != nil {
+		return 0, errors.NewInvalidArgumentError(errors.FieldViolation{Field: field, Description: "must be an integer"}).Wrap(err)
+	}
+	return res, nil
+}
+
+func gqlError(ctx context.Context, err error) error {
+	return &graphqlError{problem: NewProblem(ctx, err)}
+}
+
+func (e *graphqlError) Error() string {
+	return e.problem.Detail
+}
+
+func (e *graphqlError) Extensions() map[string]interface{} {
+	res := map[string]interface{}{
+		"reason": e.problem.Reason,
+		"status": e.problem.Status,
+	}
+	if len(e.problem.InvalidParams) > 0 {
+		res["invalid_params"] = e.problem.InvalidParams
+	}
+	return res
+}
+
+func (l graphqlLogger) LogPanic(ctx context.Context, value interface{}) {
+	recovery.Log(l.logger, ctx, GraphQLPath, value)
+}
+
+// MakePanicError keeps the panic value, logged by graphqlLogger, out of the
+// response.
+func (graphqlPanicHandler) MakePanicError(ctx context.Context, value interface{}) *gqlerrors.QueryError {
+	return gqlerrors.Errorf("%s", errors.NewInternalError().Message)
+}
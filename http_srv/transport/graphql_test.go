@@ -0,0 +1,324 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Path       []interface{}          `json:"path"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(url string, authorization string, query string, variables map[string]interface{}) (*http.Response, graphqlResponse) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	req, _ := http.NewRequest("POST", url+transport.GraphQLPath, strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	var res graphqlResponse
+	http_res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, res
+	}
+	defer http_res.Body.Close()
+	json.NewDecoder(http_res.Body).Decode(&res)
+	return http_res, res
+}
+
+func newGraphQLServer(srv_mock *transport.ServiceMock, options ...transport.ServerOption) *httptest.Server {
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	options = append(options, transport.WithGraphQL(srv_mock))
+	return httptest.NewServer(transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), options...))
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	details := entities.Details{Country: "MX", City: "CDMX", Height: 1.75}
+
+	test_cases := []struct {
+		test_name string
+		query     string
+		variables map[string]interface{}
+		data      string
+	}{
+		{
+			test_name: "user with some details",
+			query:     `query($id: ID!) { user(id: $id) { id email details { country heightM } } }`,
+			variables: map[string]interface{}{"id": "1"},
+			data:      `{"user": {"id": "1", "email": "first@domain.com", "details": {"country": "MX", "heightM": 1.75}}}`,
+		},
+		{
+			test_name: "missing user",
+			query:     `{ user(id: "404") { id } }`,
+			data:      `{"user": null}`,
+		},
+		{
+			test_name: "users in order",
+			query:     `{ users(ids: ["2", "404", "1"]) { id age } }`,
+			data:      `{"users": [{"id": "2", "age": 32}, null, {"id": "1", "age": 23}]}`,
+		},
+		{
+			test_name: "search",
+			query:     `{ search(email: "domain", limit: 5) { email details { city } } }`,
+			data:      `{"search": [{"email": "first@domain.com", "details": {"city": "CDMX"}}, {"email": "second@domain.com", "details": {"city": "CDMX"}}]}`,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			srv_mock := new(transport.ServiceMock)
+			server := newGraphQLServer(srv_mock)
+			defer server.Close()
+
+			srv_mock.On("GetAccounts", mock.Anything, mock.Anything).Return(map[int]entities.Account{
+				1: {Id: 1, Email: "first@domain.com", Age: 23},
+				2: {Id: 2, Email: "second@domain.com", Age: 32},
+			}, nil)
+			srv_mock.On("GetUsersDetails", mock.Anything, mock.Anything).Return(map[int]entities.Details{1: details, 2: details}, nil)
+			srv_mock.On("SearchUsers", mock.Anything, "domain", 5).Return([]entities.Account{
+				{Id: 1, Email: "first@domain.com", Age: 23},
+				{Id: 2, Email: "second@domain.com", Age: 32},
+			}, nil)
+
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			http_res, res := postGraphQL(server.URL, "", tc.query, tc.variables)
+
+			// assert
+			assert.Equal(http.StatusOK, http_res.StatusCode)
+			assert.Empty(res.Errors)
+			assert.JSONEq(tc.data, string(res.Data))
+		})
+	}
+}
+
+func TestGraphQLBatchesDetails(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	server := newGraphQLServer(srv_mock)
+	defer server.Close()
+
+	ids := []string{"1", "2", "3", "1", "2", "3"}
+	accounts := map[int]entities.Account{}
+	details := map[int]entities.Details{}
+	for i := 1; i <= 3; i++ {
+		accounts[i] = entities.Account{Id: i, Email: "user@domain.com", Age: 20}
+		details[i] = entities.Details{Country: "MX"}
+	}
+	srv_mock.On("GetAccounts", mock.Anything, []int{1, 2, 3}).Return(accounts, nil)
+	srv_mock.On("GetUsersDetails", mock.Anything, mock.Anything).Return(details, nil)
+
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	_, res := postGraphQL(server.URL, "", `query($ids: [ID!]!) { users(ids: $ids) { id details { country } } }`, map[string]interface{}{"ids": ids})
+
+	// assert
+	assert.Empty(res.Errors)
+	assert.Contains(string(res.Data), `{"id":"3","details":{"country":"MX"}}`)
+	srv_mock.AssertNumberOfCalls(t, "GetAccounts", 1)
+	srv_mock.AssertNumberOfCalls(t, "GetUsersDetails", 1)
+	assert.ElementsMatch([]int{1, 2, 3}, srv_mock.Calls[1].Arguments.Get(1))
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	server := newGraphQLServer(srv_mock)
+	defer server.Close()
+
+	details := entities.Details{Country: "MX", City: "CDMX", MobileNumber: "5512345678"}
+	srv_mock.On("CreateUser", mock.Anything, "user@domain.com", "qwerty123", 20, details).Return(5, nil)
+	srv_mock.On("UpdateUser", mock.Anything, 5, "user@domain.com", "qwerty123", 20, details).Return(true, nil)
+	srv_mock.On("DeleteUser", mock.Anything, 5).Return(true, nil)
+	srv_mock.On("Authenticate", mock.Anything, "user@domain.com", "qwerty123").Return("token", nil)
+	srv_mock.On("Authenticate", mock.Anything, "a@b", "qwerty123").Return("short_token", nil)
+
+	input := map[string]interface{}{
+		"email":    "user@domain.com",
+		"password": "qwerty123",
+		"age":      20,
+		"details":  map[string]interface{}{"country": "MX", "city": "CDMX", "mobileNumber": "5512345678"},
+	}
+
+	test_cases := []struct {
+		test_name string
+		query     string
+		variables map[string]interface{}
+		data      string
+	}{
+		{
+			test_name: "create user",
+			query:     `mutation($input: UserInput!) { createUser(input: $input) { id email details { city mobileNumber } } }`,
+			variables: map[string]interface{}{"input": input},
+			data:      `{"createUser": {"id": "5", "email": "user@domain.com", "details": {"city": "CDMX", "mobileNumber": "5512345678"}}}`,
+		},
+		{
+			test_name: "update user",
+			query:     `mutation($input: UserInput!) { updateUser(id: "5", input: $input) }`,
+			variables: map[string]interface{}{"input": input},
+			data:      `{"updateUser": true}`,
+		},
+		{
+			test_name: "delete user",
+			query:     `mutation { deleteUser(id: "5") }`,
+			data:      `{"deleteUser": true}`,
+		},
+		{
+			test_name: "authenticate",
+			query:     `mutation { authenticate(email: "user@domain.com", password: "qwerty123") }`,
+			data:      `{"authenticate": "token"}`,
+		},
+		{
+			test_name: "authenticate with a short email",
+			query:     `mutation { authenticate(email: "a@b", password: "qwerty123") }`,
+			data:      `{"authenticate": "short_token"}`,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			_, res := postGraphQL(server.URL, "", tc.query, tc.variables)
+
+			// assert
+			assert.Empty(res.Errors)
+			assert.JSONEq(tc.data, string(res.Data))
+		})
+	}
+
+	// the created details come from the mutation, not from details_srv
+	srv_mock.AssertNotCalled(t, "GetUserDetails", mock.Anything, mock.Anything)
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	server := newGraphQLServer(srv_mock, transport.WithAuthentication())
+	defer server.Close()
+
+	srv_mock.On("GetAccounts", mock.Anything, []int{1}).Return(map[int]entities.Account{1: {Id: 1, Email: "user@domain.com", Age: 20}}, nil)
+	srv_mock.On("GetUsersDetails", mock.Anything, []int{1}).Return(map[int]entities.Details(nil), errors.NewUnavailableError(time.Second))
+	srv_mock.On("Authenticate", mock.Anything, "user@domain.com", "qwerty123").Return("token", nil)
+
+	token := "Bearer " + signedToken("this_is_a_secret_shhh", time.Now().Add(time.Minute))
+
+	test_cases := []struct {
+		test_name     string
+		authorization string
+		query         string
+		data          string
+		reason        string
+		status        float64
+		invalid       interface{}
+	}{
+		{
+			test_name: "missing token",
+			query:     `{ user(id: "1") { id } }`,
+			data:      `{"user": null}`,
+			reason:    errors.ReasonUnauthorized,
+			status:    401,
+		},
+		{
+			test_name: "authenticate without token",
+			query:     `mutation { authenticate(email: "user@domain.com", password: "qwerty123") }`,
+			data:      `{"authenticate": "token"}`,
+		},
+		{
+			test_name:     "invalid id",
+			authorization: token,
+			query:         `{ user(id: "one") { id } }`,
+			data:          `{"user": null}`,
+			reason:        errors.ReasonInvalidArgument,
+			status:        400,
+			invalid:       []interface{}{map[string]interface{}{"name": "id", "reason": "must be an integer"}},
+		},
+		{
+			test_name:     "invalid input",
+			authorization: token,
+			query:         `mutation { createUser(input: {email: "user", password: "qwerty123", age: 20, details: {country: "MX"}}) { id } }`,
+			data:          `null`,
+			reason:        errors.ReasonInvalidArgument,
+			status:        400,
+			invalid:       []interface{}{map[string]interface{}{"name": "email", "reason": "must be a valid email address"}},
+		},
+		{
+			test_name:     "search too short",
+			authorization: token,
+			query:         `{ search(email: "do") { id } }`,
+			data:          `null`,
+			reason:        errors.ReasonInvalidArgument,
+			status:        400,
+			invalid:       []interface{}{map[string]interface{}{"name": "email", "reason": "must be at least 3 characters"}},
+		},
+		{
+			test_name:     "failing details keep the account",
+			authorization: token,
+			query:         `{ user(id: "1") { email details { country } } }`,
+			data:          `{"user": {"email": "user@domain.com", "details": null}}`,
+			reason:        errors.ReasonUnavailable,
+			status:        503,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			http_res, res := postGraphQL(server.URL, tc.authorization, tc.query, nil)
+
+			// assert
+			assert.Equal(http.StatusOK, http_res.StatusCode)
+			assert.JSONEq(tc.data, string(res.Data))
+			if tc.reason == "" {
+				assert.Empty(res.Errors)
+				return
+			}
+			if assert.Len(res.Errors, 1) {
+				assert.Equal(tc.reason, res.Errors[0].Extensions["reason"])
+				assert.Equal(tc.status, res.Errors[0].Extensions["status"])
+				assert.Equal(tc.invalid, res.Errors[0].Extensions["invalid_params"])
+			}
+		})
+	}
+}
+
+func TestGraphQLBadRequest(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	server := newGraphQLServer(srv_mock)
+	defer server.Close()
+
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	res, err := http.Post(server.URL+transport.GraphQLPath, "application/json", strings.NewReader(`{"query": `))
+
+	// assert
+	assert.Nil(err)
+	assert.Equal(http.StatusBadRequest, res.StatusCode)
+	assert.Equal(transport.ProblemContentType, res.Header.Get("Content-Type"))
+}
@@ -0,0 +1,369 @@
+package transport
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+	"github.com/mauricioww/user_microsrv/validation"
+)
+
+const OpenAPIPath = "/openapi.json"
+
+var (
+	//go:embed docs.html
+	docs_html []byte
+	//go:embed docs.js
+	docs_js []byte
+	// swagger-ui-dist, vendored so the docs need no CDN
+	//go:embed swagger-ui/swagger-ui-bundle.js
+	swagger_ui_js []byte
+	//go:embed swagger-ui/swagger-ui.css
+	swagger_ui_css []byte
+)
+
+// OpenAPI describes the HTTP API. Body schemas are derived from the request
+// and response types by the same rules encoding/json follows, so the
+// document cannot drift from what the decoders accept; only the constraints
+// enforced by the validation package are added by hand.
+func OpenAPI() *openapi3.T {
+	create_user := schemaOf(reflect.TypeOf(CreateUserRequest{}))
+	constrainAccount(create_user, "information")
+	update_user := schemaOf(reflect.TypeOf(UpdateUserRequest{}))
+	constrainCredentials(update_user)
+	constrainDetails(update_user.Properties["information"].Value)
+	update_user.Properties["information"].Value.Description = "Left as they are when missing"
+	authenticate := schemaOf(reflect.TypeOf(AuthenticateRequest{}))
+	authenticate.Required = []string{"email", "password"}
+	create_session := schemaOf(reflect.TypeOf(CreateSessionRequest{}))
+	create_session.Required = []string{"email", "password"}
+	create_account := schemaOf(reflect.TypeOf(CreateAccountRequest{}))
+	constrainAccount(create_account, "profile")
+	update_account := schemaOf(reflect.TypeOf(UpdateAccountRequest{}))
+	constrainCredentials(update_account)
+	profile := schemaOf(reflect.TypeOf(SetProfileRequest{}))
+	constrainDetails(profile)
+	profile_patch := schemaOf(reflect.TypeOf(PatchProfileRequest{}))
+	constrainDetails(profile_patch)
+	profile_patch.Required = nil
+	profile_patch.Description = "Fields left out or null keep their value"
+	for _, p := range profile_patch.Properties {
+		p.Value.Nullable = true
+	}
+	create_webhook := schemaOf(reflect.TypeOf(CreateWebhookRequest{}))
+	constrainWebhook(create_webhook)
+	problem := schemaOf(reflect.TypeOf(Problem{}))
+	problem.Required = []string{"type", "title", "status"}
+
+	spec := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "user_microsrv HTTP API",
+			Version:     "2.0.0",
+			Description: "Accounts and their details. The JSON bodies documented here can also be sent and asked for, through Content-Type and Accept, as application/x-protobuf (the gateway.proto messages) or application/msgpack. Errors are always RFC 7807 problems in JSON.",
+		},
+		Components: openapi3.Components{
+			Schemas: openapi3.Schemas{
+				"CreateUserRequest":     openapi3.NewSchemaRef("", create_user),
+				"CreateUserResponse":    openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(CreateUserResponse{}))),
+				"AuthenticateRequest":   openapi3.NewSchemaRef("", authenticate),
+				"AuthenticateResponse":  openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(AuthenticateResponse{}))),
+				"UpdateUserRequest":     openapi3.NewSchemaRef("", update_user),
+				"UpdateUserResponse":    openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(UpdateUserResponse{}))),
+				"GetUserResponse":       openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(GetUserResponse{}))),
+				"DeleteUserResponse":    openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(DeleteUserResponse{}))),
+				"CreateSessionRequest":  openapi3.NewSchemaRef("", create_session),
+				"CreateSessionResponse": openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(CreateSessionResponse{}))),
+				"CreateAccountRequest":  openapi3.NewSchemaRef("", create_account),
+				"UpdateAccountRequest":  openapi3.NewSchemaRef("", update_account),
+				"Account":               openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(AccountResponse{}))),
+				"Profile":               openapi3.NewSchemaRef("", profile),
+				"ProfilePatch":          openapi3.NewSchemaRef("", profile_patch),
+				"CreateWebhookRequest":  openapi3.NewSchemaRef("", create_webhook),
+				"Webhook":               openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(WebhookResponse{}))),
+				"WebhookList":           openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(ListWebhooksResponse{}))),
+				"DeliveryList":          openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(ListDeliveriesResponse{}))),
+				"Problem":               openapi3.NewSchemaRef("", problem),
+			},
+			Parameters: openapi3.ParametersMap{
+				"UserId": &openapi3.ParameterRef{Value: openapi3.NewPathParameter("id").
+					WithDescription("User id").
+					WithSchema(openapi3.NewIntegerSchema())},
+				"WebhookId": &openapi3.ParameterRef{Value: openapi3.NewPathParameter("id").
+					WithDescription("Webhook subscription id").
+					WithSchema(openapi3.NewStringSchema())},
+			},
+		},
+		Paths: openapi3.Paths{
+			"/v2/sessions": &openapi3.PathItem{
+				Post: operation("createSession", "Exchange credentials for a JWT", "CreateSessionRequest", http.StatusCreated, "CreateSessionResponse", 400, 401),
+			},
+			"/v2/users": &openapi3.PathItem{
+				Post: withLocation(operation("createAccount", "Create a user with their profile", "CreateAccountRequest", http.StatusCreated, "Account", 400)),
+			},
+			"/v2/users/{id}": &openapi3.PathItem{
+				Parameters: openapi3.Parameters{{Ref: "#/components/parameters/UserId"}},
+				Get:        operation("getAccount", "Get the account of a user", "", http.StatusOK, "Account", 400, 404),
+				Put:        operation("updateAccount", "Replace the account of a user, keeping their profile", "UpdateAccountRequest", http.StatusOK, "Account", 400, 404),
+				Delete:     operation("deleteAccount", "Delete a user and their profile", "", http.StatusNoContent, "", 400, 404),
+			},
+			"/v2/users/{id}/profile": &openapi3.PathItem{
+				Parameters: openapi3.Parameters{{Ref: "#/components/parameters/UserId"}},
+				Get:        operation("getProfile", "Get the profile of a user", "", http.StatusOK, "Profile", 400, 404),
+				Put:        operation("setProfile", "Replace the profile of a user, keeping their account", "Profile", http.StatusOK, "Profile", 400),
+				Patch:      operation("patchProfile", "Replace some fields of the profile of a user", "ProfilePatch", http.StatusOK, "Profile", 400, 404),
+				Delete:     operation("deleteProfile", "Delete the profile of a user, keeping their account", "", http.StatusNoContent, "", 400, 404),
+			},
+			"/v2/webhooks": &openapi3.PathItem{
+				Post: withLocation(operation("createWebhook", "Subscribe a URL to user events", "CreateWebhookRequest", http.StatusCreated, "Webhook", 400)),
+				Get:  operation("listWebhooks", "List the webhook subscriptions", "", http.StatusOK, "WebhookList"),
+			},
+			"/v2/webhooks/{id}": &openapi3.PathItem{
+				Parameters: openapi3.Parameters{{Ref: "#/components/parameters/WebhookId"}},
+				Get:        operation("getWebhook", "Get a webhook subscription", "", http.StatusOK, "Webhook", 404),
+				Delete:     operation("deleteWebhook", "Unsubscribe, dropping the delivery log", "", http.StatusNoContent, "", 404),
+			},
+			"/v2/webhooks/{id}/deliveries": &openapi3.PathItem{
+				Parameters: openapi3.Parameters{{Ref: "#/components/parameters/WebhookId"}},
+				Get:        withStatusFilter(operation("listDeliveries", "Get the delivery log of a subscription, newest first", "", http.StatusOK, "DeliveryList", 400, 404)),
+			},
+		},
+	}
+	for _, prefix := range []string{"", "/v1"} {
+		for path, item := range v1Paths(prefix) {
+			spec.Paths[path] = item
+		}
+	}
+
+	if err := openapi3.NewLoader().ResolveRefsIn(spec, nil); err != nil {
+		panic("transport: invalid OpenAPI document: " + err.Error())
+	}
+	return spec
+}
+
+// v1Paths describes the deprecated routes, served both under prefix and
+// without it; operation ids are prefixed too so they stay unique.
+func v1Paths(prefix string) openapi3.Paths {
+	id := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return strings.TrimPrefix(prefix, "/") + strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	paths := openapi3.Paths{
+		prefix + "/users": &openapi3.PathItem{
+			Post: operation(id("createUser"), "Create a user with their details", "CreateUserRequest", http.StatusOK, "CreateUserResponse", 400),
+		},
+		prefix + "/users/{id}": &openapi3.PathItem{
+			Parameters: openapi3.Parameters{{Ref: "#/components/parameters/UserId"}},
+			Get:        operation(id("getUser"), "Get a user with their details", "", http.StatusOK, "GetUserResponse", 400, 404),
+			Put:        operation(id("updateUser"), "Replace a user and, when sent, their details", "UpdateUserRequest", http.StatusOK, "UpdateUserResponse", 400, 404),
+			Delete:     operation(id("deleteUser"), "Delete a user and their details", "", http.StatusOK, "DeleteUserResponse", 400, 404),
+		},
+		prefix + "/users/{id}/details": &openapi3.PathItem{
+			Parameters: openapi3.Parameters{{Ref: "#/components/parameters/UserId"}},
+			Get:        operation(id("getDetails"), "Get the details of a user", "", http.StatusOK, "Profile", 400, 404),
+			Put:        operation(id("setDetails"), "Replace the details of a user", "Profile", http.StatusOK, "Profile", 400),
+			Patch:      operation(id("patchDetails"), "Replace some fields of the details of a user", "ProfilePatch", http.StatusOK, "Profile", 400, 404),
+			Delete:     operation(id("deleteDetails"), "Delete the details of a user", "", http.StatusNoContent, "", 400, 404),
+		},
+		prefix + "/auth": &openapi3.PathItem{
+			// a body on GET is kept for existing clients
+			Get: operation(id("authenticate"), "Exchange credentials for a JWT", "AuthenticateRequest", http.StatusOK, "AuthenticateResponse", 400, 401),
+		},
+	}
+	for _, item := range paths {
+		for _, op := range item.Operations() {
+			op.Deprecated = true
+		}
+	}
+	return paths
+}
+
+// operation answers status with a response body, or with none when
+// response is empty.
+func operation(id string, summary string, request string, status int, response string, problems ...int) *openapi3.Operation {
+	op := openapi3.NewOperation()
+	op.OperationID = id
+	op.Summary = summary
+
+	if request != "" {
+		op.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+			WithRequired(true).
+			WithJSONSchemaRef(ref(request))}
+		problems = append(problems, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+	}
+
+	ok := openapi3.NewResponse().WithDescription(http.StatusText(status))
+	if response != "" {
+		ok.WithJSONSchemaRef(ref(response))
+	}
+	op.AddResponse(status, ok)
+
+	problem := openapi3.NewContentWithSchemaRef(ref("Problem"), []string{ProblemContentType})
+	for _, code := range problems {
+		op.AddResponse(code, openapi3.NewResponse().WithDescription(http.StatusText(code)).WithContent(problem))
+	}
+	op.AddResponse(0, openapi3.NewResponse().WithDescription("Error").WithContent(problem))
+
+	return op
+}
+
+// withLocation documents the Location header of a created resource.
+func withLocation(op *openapi3.Operation) *openapi3.Operation {
+	op.Responses.Get(http.StatusCreated).Value.Headers = openapi3.Headers{
+		"Location": &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "Path of the new resource",
+			Schema:      openapi3.NewSchemaRef("", openapi3.NewStringSchema()),
+		}}},
+	}
+	return op
+}
+
+// withStatusFilter documents the status a delivery log can be filtered by.
+func withStatusFilter(op *openapi3.Operation) *openapi3.Operation {
+	op.AddParameter(openapi3.NewQueryParameter("status").
+		WithDescription("Only the deliveries in this status").
+		WithSchema(openapi3.NewStringSchema().WithEnum(webhook.StatusPending, webhook.StatusDelivered, webhook.StatusDead)))
+	return op
+}
+
+func ref(schema string) *openapi3.SchemaRef {
+	return openapi3.NewSchemaRef("#/components/schemas/"+schema, nil)
+}
+
+// constrainAccount mirrors the rules validateAccount applies.
+func constrainAccount(s *openapi3.Schema, details_field string) {
+	constrainCredentials(s)
+	s.Required = append(s.Required, details_field)
+	constrainDetails(s.Properties[details_field].Value)
+}
+
+// constrainCredentials mirrors accountChecks.
+func constrainCredentials(s *openapi3.Schema) {
+	s.Required = []string{"email", "password", "age"}
+	s.Properties["email"].Value.
+		WithFormat("email").
+		WithMaxLength(validation.MaxEmailLength)
+	s.Properties["password"].Value.
+		WithMinLength(validation.MinPasswordLength).
+		WithMaxLength(validation.MaxPasswordLength).
+		Description = "At least one letter and one digit"
+	s.Properties["age"].Value.
+		WithMin(validation.MinAge).
+		WithMax(validation.MaxAge)
+}
+
+// constrainDetails mirrors detailsChecks.
+func constrainDetails(details *openapi3.Schema) {
+	details.Required = []string{"country"}
+	details.Properties["country"].Value.
+		WithPattern("^[A-Z]{2}$").
+		Description = "ISO 3166-1 alpha-2 code"
+	details.Properties["city"].Value.
+		WithMaxLength(100)
+	details.Properties["mobile_number"].Value.
+		WithPattern(`^(\+?[0-9]{7,15})?$`).
+		Description = "E.164 number, empty when unknown"
+	details.Properties["height_m"].Value.
+		Description = fmt.Sprintf("Meters between %v and %v, 0 when unknown", validation.MinHeight, validation.MaxHeight)
+	details.Properties["weight_kg"].Value.
+		Description = fmt.Sprintf("Kilograms between %v and %v, 0 when unknown", validation.MinWeight, validation.MaxWeight)
+}
+
+// constrainWebhook mirrors decodeCreateWebhookRequest.
+func constrainWebhook(s *openapi3.Schema) {
+	s.Required = []string{"url", "events", "secret"}
+	s.Properties["url"].Value.
+		WithFormat("uri").
+		Description = "Absolute http or https URL the events are POSTed to"
+	events := s.Properties["events"].Value
+	events.MinItems = 1
+	enum := make([]interface{}, len(webhook.EventTypes))
+	for i, e := range webhook.EventTypes {
+		enum[i] = e
+	}
+	events.Items.Value.WithEnum(enum...)
+	s.Properties["secret"].Value.
+		WithMinLength(webhook.MinSecretLength).
+		Description = "Keys the HMAC-SHA256 signature of every delivery; never returned"
+}
+
+// schemaOf builds the schema encoding/json would produce for t: fields
+// named by their json tag, "-" skipped, and embedded structs flattened
+// unless the tag names them, as with the "information" details.
+func schemaOf(t reflect.Type) *openapi3.Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return openapi3.NewDateTimeSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return openapi3.NewStringSchema()
+	case reflect.Bool:
+		return openapi3.NewBoolSchema()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return openapi3.NewIntegerSchema()
+	case reflect.Float32:
+		return openapi3.NewFloat64Schema().WithFormat("float")
+	case reflect.Float64:
+		return openapi3.NewFloat64Schema().WithFormat("double")
+	case reflect.Slice, reflect.Array:
+		return openapi3.NewArraySchema().WithItems(schemaOf(t.Elem()))
+	case reflect.Struct:
+		s := openapi3.NewObjectSchema()
+		addFields(s, t)
+		return s
+	}
+	return openapi3.NewSchema()
+}
+
+func addFields(s *openapi3.Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addFields(s, f.Type)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.WithPropertyRef(name, openapi3.NewSchemaRef("", schemaOf(f.Type)))
+	}
+}
+
+func specHandler(spec *openapi3.T) http.Handler {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		panic("transport: cannot encode OpenAPI document: " + err.Error())
+	}
+	return docsHandler("application/json", body)
+}
+
+// docsHandler serves a static document; it only changes with a release.
+func docsHandler(content_type string, body []byte) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", content_type)
+		rw.Write(body)
+	})
+}
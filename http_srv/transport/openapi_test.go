@@ -0,0 +1,249 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	res, _ := http.Get(server.URL + transport.OpenAPIPath)
+	body, _ := ioutil.ReadAll(res.Body)
+	spec, err := openapi3.NewLoader().LoadFromData(body)
+
+	// assert
+	assert.Nil(err)
+	assert.Equal("application/json", res.Header.Get("Content-Type"))
+	assert.Nil(spec.Validate(context.Background()))
+
+	create := spec.Components.Schemas["CreateUserRequest"].Value
+	assert.ElementsMatch([]string{"email", "password", "age", "information"}, keys(create.Properties))
+	assert.Contains(create.Properties["information"].Value.Properties, "height_m")
+	assert.Contains(create.Properties["information"].Value.Properties, "weight_kg")
+	assert.NotContains(spec.Components.Schemas["UpdateUserRequest"].Value.Properties, "UserId")
+	assert.NotNil(spec.Paths.Find("/users/{id}").Put)
+	assert.True(spec.Paths.Find("/v1/users/{id}").Put.Deprecated)
+	assert.Equal("v1UpdateUser", spec.Paths.Find("/v1/users/{id}").Put.OperationID)
+	assert.False(spec.Paths.Find("/v2/users/{id}").Put.Deprecated)
+	assert.NotContains(spec.Components.Schemas["Account"].Value.Properties, "password")
+}
+
+// Every transport type must encode to JSON its own schema accepts.
+func TestOpenAPISchemasMatchTypes(t *testing.T) {
+	details := entities.Details{Country: "MX", City: "CDMX", MobileNumber: "5512345678", Height: 1.75, Weight: 76}
+
+	test_cases := []struct {
+		schema string
+		value  interface{}
+	}{
+		{schema: "CreateUserRequest", value: transport.CreateUserRequest{Email: "user@email.com", Password: "qwerty123", Age: 23, Details: details}},
+		{schema: "CreateUserResponse", value: transport.CreateUserResponse{Id: 1, Email: "user@email.com", Age: 23, Details: details}},
+		{schema: "AuthenticateRequest", value: transport.AuthenticateRequest{Email: "user@email.com", Password: "qwerty"}},
+		{schema: "AuthenticateResponse", value: transport.AuthenticateResponse{Token: "jwt"}},
+		{schema: "UpdateUserRequest", value: transport.UpdateUserRequest{UserId: 1, Email: "user@email.com", Password: "qwerty123", Age: 23, Details: details}},
+		{schema: "UpdateUserResponse", value: transport.UpdateUserResponse{Success: true}},
+		{schema: "GetUserResponse", value: transport.GetUserResponse{Id: 1, Email: "user@email.com", Age: 23, Details: details}},
+		{schema: "DeleteUserResponse", value: transport.DeleteUserResponse{Success: true}},
+		{schema: "CreateSessionRequest", value: transport.CreateSessionRequest{Email: "user@email.com", Password: "qwerty"}},
+		{schema: "CreateSessionResponse", value: transport.CreateSessionResponse{Token: "jwt"}},
+		{schema: "CreateAccountRequest", value: transport.CreateAccountRequest{Email: "user@email.com", Password: "qwerty123", Age: 23, Profile: details}},
+		{schema: "UpdateAccountRequest", value: transport.UpdateAccountRequest{UserId: 1, Email: "user@email.com", Password: "qwerty123", Age: 23}},
+		{schema: "Account", value: transport.CreateAccountResponse{AccountResponse: transport.AccountResponse{Id: 1, Email: "user@email.com", Age: 23}}},
+		{schema: "Profile", value: transport.ProfileResponse{Details: details}},
+		{schema: "Problem", value: transport.Problem{Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "Invalid request", Instance: "/users/abc",
+			Reason: errors.ReasonInvalidArgument, RequestId: "req-1", InvalidParams: []transport.InvalidParam{{Name: "id", Reason: "must be an integer"}}}},
+	}
+
+	spec := transport.OpenAPI()
+	for _, tc := range test_cases {
+		t.Run(tc.schema, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			body, _ := json.Marshal(tc.value)
+			var doc map[string]interface{}
+			json.Unmarshal(body, &doc)
+
+			// act
+			err := spec.Components.Schemas[tc.schema].Value.VisitJSON(doc)
+
+			// assert
+			assert.Nil(err)
+			assert.ElementsMatch(keys(spec.Components.Schemas[tc.schema].Value.Properties), keysOf(doc))
+		})
+	}
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithOpenAPIValidation())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("CreateUser", mock.Anything, "user@email.com", "qwerty123", 23, entities.Details{Country: "MX"}).Return(7, nil)
+	srv_mock.On("GetUser", mock.Anything, 7).Return(entities.User{}, errors.NewUserNotFoundError())
+
+	test_cases := []struct {
+		test_name      string
+		method         string
+		path           string
+		body           string
+		httpStatus     int
+		invalid_params []transport.InvalidParam
+	}{
+		{
+			test_name:  "valid request",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"email": "user@email.com", "password": "qwerty123", "age": 23, "information": {"country": "MX"}}`,
+			httpStatus: 200,
+		},
+		{
+			test_name:  "error response",
+			method:     "GET",
+			path:       "/users/7",
+			httpStatus: 404,
+		},
+		{
+			test_name:  "schema violations",
+			method:     "POST",
+			path:       "/users",
+			body:       `{"email": "user@email.com", "password": 123, "age": 200, "information": {}}`,
+			httpStatus: 400,
+			invalid_params: []transport.InvalidParam{
+				{Name: "password", Reason: `Field must be set to string or not be present`},
+				{Name: "age", Reason: "number must be at most 150"},
+				{Name: "information.country", Reason: `property "country" is missing`},
+			},
+		},
+		{
+			test_name:  "path parameter",
+			method:     "GET",
+			path:       "/users/abc",
+			httpStatus: 400,
+		},
+		{
+			test_name:  "outside the document",
+			method:     "GET",
+			path:       "/docs",
+			httpStatus: 200,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(res.Body)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode, string(body))
+			if tc.invalid_params != nil {
+				var problem transport.Problem
+				json.Unmarshal(body, &problem)
+				assert.ElementsMatch(tc.invalid_params, problem.InvalidParams)
+			}
+		})
+	}
+}
+
+func TestDocs(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	test_cases := []struct {
+		test_name    string
+		path         string
+		content_type string
+		res          string
+	}{
+		{
+			test_name:    "page",
+			path:         "/docs",
+			content_type: "text/html; charset=utf-8",
+			res:          "swagger-ui",
+		},
+		{
+			test_name:    "bundle",
+			path:         "/docs/swagger-ui-bundle.js",
+			content_type: "text/javascript; charset=utf-8",
+			res:          "SwaggerUIBundle",
+		},
+		{
+			test_name:    "stylesheet",
+			path:         "/docs/swagger-ui.css",
+			content_type: "text/css; charset=utf-8",
+			res:          ".swagger-ui",
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			res, _ := http.Get(server.URL + tc.path)
+			body, _ := ioutil.ReadAll(res.Body)
+
+			// assert
+			assert.Equal(200, res.StatusCode)
+			assert.Equal(tc.content_type, res.Header.Get("Content-Type"))
+			assert.Contains(string(body), tc.res)
+			if tc.path == "/docs" {
+				assert.NotContains(string(body), "https://", "the page loads nothing from elsewhere")
+			}
+		})
+	}
+}
+
+func keys(m openapi3.Schemas) []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
+
+func keysOf(m map[string]interface{}) []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
@@ -0,0 +1,153 @@
+package transport
+
+import (
+	"bytes"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/logging"
+)
+
+// openAPIMiddleware checks every request and response of an operation in
+// spec against it. Requests that do not match answer 400 with one invalid
+// param per violation; responses that do not match are logged and replaced
+// by a 500, so a contract drift fails loudly outside production.
+func openAPIMiddleware(spec *openapi3.T, logger log.Logger) mux.MiddlewareFunc {
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		panic("transport: invalid OpenAPI document: " + err.Error())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			route, params, err := router.FindRoute(r)
+			if err != nil {
+				// not part of the API, such as the docs themselves
+				next.ServeHTTP(rw, r)
+				return
+			}
+			if !jsonExchange(r) {
+				// the document only describes the JSON bodies
+				next.ServeHTTP(rw, r)
+				return
+			}
+
+			ctx := r.Context()
+			in := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: params,
+				Route:      route,
+				Options:    &openapi3filter.Options{MultiError: true},
+			}
+			if err := openapi3filter.ValidateRequest(ctx, in); err != nil {
+				encodeError(ctx, errors.NewInvalidArgumentError(openAPIViolations(err)...).Wrap(err), rw)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: rw, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			out := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: in,
+				Status:                 rec.status,
+				Header:                 rw.Header(),
+				Options:                &openapi3filter.Options{MultiError: true, IncludeResponseStatus: true},
+			}
+			out.SetBodyBytes(rec.body.Bytes())
+			if err := openapi3filter.ValidateResponse(ctx, out); err != nil {
+				level.Error(logger).Log("request_id", logging.RequestID(ctx), "route", route.Path, "openapi_response", err)
+				rw.Header().Del("Retry-After")
+				encodeError(ctx, errors.NewInternalError().Wrap(err), rw)
+				return
+			}
+
+			rw.WriteHeader(rec.status)
+			rw.Write(rec.body.Bytes())
+		})
+	}
+}
+
+// responseRecorder holds the response back until it has been checked;
+// headers go straight to the real writer.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func openAPIViolations(err error) []errors.FieldViolation {
+	var multi openapi3.MultiError
+	if stderrors.As(err, &multi) {
+		var res []errors.FieldViolation
+		for _, e := range multi {
+			res = append(res, openAPIViolations(e)...)
+		}
+		return res
+	}
+
+	var req *openapi3filter.RequestError
+	if stderrors.As(err, &req) {
+		if req.Parameter != nil {
+			return []errors.FieldViolation{{Field: req.Parameter.Name, Description: reason(req.Err, req.Reason)}}
+		}
+		if req.Err != nil {
+			if v := openAPIViolations(req.Err); len(v) > 0 {
+				return v
+			}
+		}
+		return []errors.FieldViolation{{Field: "body", Description: reason(req.Err, req.Reason)}}
+	}
+
+	var schema *openapi3.SchemaError
+	if stderrors.As(err, &schema) {
+		field := strings.Join(schema.JSONPointer(), ".")
+		if field == "" {
+			field = "body"
+		}
+		return []errors.FieldViolation{{Field: field, Description: schema.Reason}}
+	}
+
+	return nil
+}
+
+func reason(err error, fallback string) string {
+	var schema *openapi3.SchemaError
+	if stderrors.As(err, &schema) {
+		return schema.Reason
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return fallback
+}
+
+// jsonExchange tells whether both the request body and the response are
+// JSON; the other formats, and their 406 and 415, are left to the routes,
+// which may support fewer of them.
+func jsonExchange(r *http.Request) bool {
+	accepted, ok := negotiate(r.Header.Get("Accept"), codecs)
+	if !ok || accepted.media_type != JSONContentType {
+		return false
+	}
+	if !hasBody(r) {
+		return true
+	}
+	sent, ok := codecFor(r.Header.Get("Content-Type"), codecs)
+	return ok && sent.media_type == JSONContentType
+}
@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gokit_http "github.com/go-kit/kit/transport/http"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/logging"
+)
+
+const ProblemContentType = "application/problem+json"
+
+type (
+	// Problem is the RFC 7807 body of every error response. Reason and
+	// InvalidParams are extension members carrying the typed error details.
+	Problem struct {
+		Type          string         `json:"type"`
+		Title         string         `json:"title"`
+		Status        int            `json:"status"`
+		Detail        string         `json:"detail,omitempty"`
+		Instance      string         `json:"instance,omitempty"`
+		Reason        string         `json:"reason,omitempty"`
+		RequestId     string         `json:"request_id,omitempty"`
+		InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+	}
+
+	InvalidParam struct {
+		Name   string `json:"name"`
+		Reason string `json:"reason"`
+	}
+)
+
+// httpStatus holds the statuses of the HTTP only reasons, which no gRPC
+// code maps to.
+var httpStatus = map[string]int{
+	errors.ReasonNotAcceptable:        http.StatusNotAcceptable,
+	errors.ReasonUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	errors.ReasonPayloadTooLarge:      http.StatusRequestEntityTooLarge,
+}
+
+// NewProblem describes err, keeping the reason and field violations it
+// carried across the gRPC hop.
+func NewProblem(ctx context.Context, err error) Problem {
+	e := errors.Translate(err)
+	code := errors.ResolveHttp(e.Code)
+	if status, ok := httpStatus[e.Reason]; ok {
+		code = status
+	}
+
+	p := Problem{
+		Type:      "about:blank",
+		Title:     title(code),
+		Status:    code,
+		Detail:    e.Message,
+		Reason:    e.Reason,
+		RequestId: logging.RequestID(ctx),
+	}
+	if e.Reason != errors.ReasonUnknown {
+		p.Type = "urn:user-microsrv:problem:" + strings.ToLower(e.Reason)
+	}
+	if path, ok := ctx.Value(gokit_http.ContextKeyRequestPath).(string); ok {
+		p.Instance = path
+	}
+	for _, v := range e.Violations {
+		p.InvalidParams = append(p.InvalidParams, InvalidParam{Name: v.Field, Reason: v.Description})
+	}
+	return p
+}
+
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	p := NewProblem(ctx, err)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	if e := errors.Translate(err); e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func title(code int) string {
+	if code == 499 {
+		return "Client Closed Request"
+	}
+	return http.StatusText(code)
+}
@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/gatewaypb"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalProto writes a response as its gateway.proto message. Users go out
+// as gatewaypb.User, which unlike the v1 JSON never carries the password.
+func marshalProto(v interface{}) ([]byte, error) {
+	var m proto.Message
+
+	switch v := v.(type) {
+	case CreateUserResponse:
+		m = &gatewaypb.User{Id: int32(v.Id), Email: v.Email, Age: uint32(v.Age), Details: detailsToPb(v.Details)}
+	case GetUserResponse:
+		m = &gatewaypb.User{Id: int32(v.Id), Email: v.Email, Age: uint32(v.Age), Details: detailsToPb(v.Details)}
+	case AuthenticateResponse:
+		m = &gatewaypb.AuthenticateResponse{Token: v.Token}
+	case UpdateUserResponse:
+		m = &gatewaypb.UpdateUserResponse{Success: v.Success}
+	case DeleteUserResponse:
+		m = &gatewaypb.DeleteUserResponse{Success: v.Success}
+	case CreateSessionResponse:
+		m = &gatewaypb.AuthenticateResponse{Token: v.Token}
+	case AccountResponse:
+		m = accountToPb(v)
+	case CreateAccountResponse:
+		m = accountToPb(v.AccountResponse)
+	case ProfileResponse:
+		m = detailsToPb(v.Details)
+	default:
+		// webhookCodecs keep the webhook resources, which have no message in
+		// gateway.proto, from getting here
+		return nil, errors.NewNotAcceptableError(JSONContentType, MsgpackContentType)
+	}
+
+	return proto.Marshal(m)
+}
+
+// unmarshalProto reads a request body from its gateway.proto message. Ids
+// come from the path, so those in the messages are ignored.
+func unmarshalProto(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *CreateUserRequest:
+		var m gatewaypb.CreateUserRequest
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = CreateUserRequest{Email: m.GetEmail(), Password: m.GetPassword(), Age: int(m.GetAge()), Details: detailsFromPb(m.GetDetails())}
+	case *AuthenticateRequest:
+		var m gatewaypb.AuthenticateRequest
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = AuthenticateRequest{Email: m.GetEmail(), Password: m.GetPassword()}
+	case *UpdateUserRequest:
+		var m gatewaypb.UpdateUserRequest
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = UpdateUserRequest{Email: m.GetEmail(), Password: m.GetPassword(), Age: int(m.GetAge()), Details: detailsFromPb(m.GetDetails())}
+	case *CreateSessionRequest:
+		var m gatewaypb.AuthenticateRequest
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = CreateSessionRequest{Email: m.GetEmail(), Password: m.GetPassword()}
+	case *CreateAccountRequest:
+		var m gatewaypb.CreateUserRequest
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = CreateAccountRequest{Email: m.GetEmail(), Password: m.GetPassword(), Age: int(m.GetAge()), Profile: detailsFromPb(m.GetDetails())}
+	case *UpdateAccountRequest:
+		var m gatewaypb.UpdateAccountRequest
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = UpdateAccountRequest{Email: m.GetEmail(), Password: m.GetPassword(), Age: int(m.GetAge())}
+	case *SetProfileRequest:
+		var m gatewaypb.Details
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = SetProfileRequest{Details: detailsFromPb(&m)}
+	case *PatchProfileRequest:
+		var m gatewaypb.DetailsPatch
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		*v = PatchProfileRequest{DetailsPatch: entities.DetailsPatch{
+			Country:      m.Country,
+			City:         m.City,
+			MobileNumber: m.MobileNumber,
+			Married:      m.Married,
+			Height:       m.HeightM,
+			Weight:       m.WeightKg,
+		}}
+	default:
+		return errors.NewUnsupportedMediaTypeError(JSONContentType, MsgpackContentType)
+	}
+
+	return nil
+}
+
+func accountToPb(a AccountResponse) *gatewaypb.Account {
+	return &gatewaypb.Account{Id: int32(a.Id), Email: a.Email, Age: uint32(a.Age)}
+}
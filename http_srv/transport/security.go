@@ -0,0 +1,199 @@
+package transport
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/logging"
+)
+
+type (
+	// CORSConfig lets browser clients on other origins call the API.
+	CORSConfig struct {
+		// AllowedOrigins are matched exactly; "*" allows any origin.
+		AllowedOrigins   []string
+		AllowedMethods   []string
+		AllowedHeaders   []string
+		AllowCredentials bool
+		// MaxAge is how long browsers may cache a preflight answer.
+		MaxAge time.Duration
+	}
+
+	// SecurityConfig sets the headers hardening every response.
+	SecurityConfig struct {
+		// HSTSMaxAge is left at zero where the API is served over plain
+		// HTTP, as browsers would then refuse it.
+		HSTSMaxAge            time.Duration
+		HSTSIncludeSubdomains bool
+		// FrameOptions is DENY unless set, SAMEORIGIN being the other
+		// sensible value.
+		FrameOptions string
+		// DocsCSP replaces DefaultDocsCSP on the docs page.
+		DocsCSP string
+	}
+)
+
+const (
+	// apiCSP suits the API itself: no response is meant to be rendered.
+	apiCSP = "default-src 'none'; frame-ancestors 'none'"
+
+	// DefaultDocsCSP lets the docs page load the embedded Swagger UI and the
+	// document from this server, and nothing else.
+	DefaultDocsCSP = "default-src 'none'; script-src 'self'; style-src 'self'; " +
+		"img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+)
+
+// ErrCredentialsForAnyOrigin is returned by CORSConfig.Validate: letting any
+// origin read responses made with the user's cookies or tokens would hand
+// them to every site the user visits.
+var ErrCredentialsForAnyOrigin = stderrors.New("cors: credentials cannot be allowed for any origin")
+
+// exposedHeaders are the response headers, besides the CORS safelisted
+// ones, that browser clients may read.
+var exposedHeaders = []string{"Location", "Retry-After", "Deprecation", "Sunset", "Link", logging.RequestIDHeader}
+
+// WithCORS answers preflight requests and marks the responses to allowed
+// origins; without it browsers keep other origins from reading them.
+func WithCORS(c CORSConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.cors = &c
+	}
+}
+
+// WithSecurityHeaders adds HSTS, nosniff, frame options and a CSP to every
+// response.
+func WithSecurityHeaders(c SecurityConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.security = &c
+	}
+}
+
+// Validate refuses the combinations browsers would be unsafe with.
+func (c CORSConfig) Validate() error {
+	if c.AllowCredentials && c.allowsAnyOrigin() {
+		return ErrCredentialsForAnyOrigin
+	}
+	return nil
+}
+
+// corsHandler wraps the router rather than being one of its middlewares:
+// the router answers OPTIONS with a 405 before any middleware would run.
+func corsHandler(c CORSConfig, next http.Handler) http.Handler {
+	methods := strings.Join(c.AllowedMethods, ", ")
+	headers := strings.Join(c.AllowedHeaders, ", ")
+	exposed := strings.Join(exposedHeaders, ", ")
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		rw.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			rw.Header().Add("Vary", "Access-Control-Request-Method")
+			rw.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		// credentials only ever go to origins listed by name, even if
+		// Validate was skipped
+		allowed := c.allowsOrigin(origin)
+		if allowed {
+			if c.allowsAnyOrigin() {
+				rw.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				rw.Header().Set("Access-Control-Allow-Origin", origin)
+				if c.AllowCredentials {
+					rw.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+		}
+
+		if !preflight {
+			if allowed {
+				rw.Header().Set("Access-Control-Expose-Headers", exposed)
+			}
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		// a refused preflight gets no CORS headers, which the browser
+		// reports to the page
+		if allowed && contains(c.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+			rw.Header().Set("Access-Control-Allow-Methods", methods)
+			rw.Header().Set("Access-Control-Allow-Headers", headers)
+			if c.MaxAge > 0 {
+				rw.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+			}
+		} else {
+			rw.Header().Del("Access-Control-Allow-Origin")
+			rw.Header().Del("Access-Control-Allow-Credentials")
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c CORSConfig) allowsAnyOrigin() bool {
+	return contains(c.AllowedOrigins, "*")
+}
+
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	return c.allowsAnyOrigin() || contains(c.AllowedOrigins, origin)
+}
+
+// securityHandler sets the headers before the router runs, so 404s and
+// 405s carry them too; the docs routes then loosen the CSP with docsCSP.
+func securityHandler(c SecurityConfig, next http.Handler) http.Handler {
+	hsts := ""
+	if c.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(c.HSTSMaxAge.Seconds()))
+		if c.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+	frame_options := c.FrameOptions
+	if frame_options == "" {
+		frame_options = "DENY"
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if hsts != "" {
+			rw.Header().Set("Strict-Transport-Security", hsts)
+		}
+		rw.Header().Set("X-Content-Type-Options", "nosniff")
+		rw.Header().Set("X-Frame-Options", frame_options)
+		rw.Header().Set("Referrer-Policy", "no-referrer")
+		rw.Header().Set("Content-Security-Policy", apiCSP)
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// docsCSP serves the docs page under the policy it needs in place of the
+// API one; without WithSecurityHeaders it sets nothing.
+func docsCSP(o serverOptions, next http.Handler) http.Handler {
+	if o.security == nil {
+		return next
+	}
+	csp := o.security.DocsCSP
+	if csp == "" {
+		csp = DefaultDocsCSP
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Security-Policy", csp)
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
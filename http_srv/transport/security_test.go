@@ -0,0 +1,293 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCORS(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	cors := transport.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithCORS(cors))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("GetAccount", mock.Anything, 1).Return(entities.Account{Id: 1, Email: "user@email.com", Age: 23}, nil)
+
+	test_cases := []struct {
+		test_name  string
+		method     string
+		origin     string
+		req_method string
+		httpStatus int
+		headers    map[string]string
+	}{
+		{
+			test_name:  "same origin",
+			method:     "GET",
+			httpStatus: 200,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			test_name:  "allowed origin",
+			method:     "GET",
+			origin:     "https://app.example.com",
+			httpStatus: 200,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "Location, Retry-After, Deprecation, Sunset, Link, X-Request-ID",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			test_name:  "other origin",
+			method:     "GET",
+			origin:     "https://evil.example.com",
+			httpStatus: 200,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			test_name:  "preflight",
+			method:     "OPTIONS",
+			origin:     "https://app.example.com",
+			req_method: "PUT",
+			httpStatus: 204,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers":     "Accept, Authorization, Content-Type",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			test_name:  "preflight from other origin",
+			method:     "OPTIONS",
+			origin:     "https://evil.example.com",
+			req_method: "PUT",
+			httpStatus: 204,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			test_name:  "preflight of other method",
+			method:     "OPTIONS",
+			origin:     "https://app.example.com",
+			req_method: "TRACE",
+			httpStatus: 204,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+"/v2/users/1", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.req_method != "" {
+				req.Header.Set("Access-Control-Request-Method", tc.req_method)
+			}
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode)
+			for k, v := range tc.headers {
+				assert.Equal(v, res.Header.Get(k), k)
+			}
+		})
+	}
+
+	// preflights never reach the endpoints
+	srv_mock.AssertNumberOfCalls(t, "GetAccount", 3)
+}
+
+func TestAnyOrigin(t *testing.T) {
+	endpoints := transport.MakeHttpEndpoints(new(transport.ServiceMock))
+	cors := transport.CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithCORS(cors))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	// prepare
+	assert := assert.New(t)
+	req, _ := http.NewRequest("OPTIONS", server.URL+"/v2/users/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+
+	// act
+	res, _ := http.DefaultClient.Do(req)
+
+	// assert
+	assert.Equal(http.StatusNoContent, res.StatusCode)
+	assert.Equal("*", res.Header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(res.Header.Get("Access-Control-Allow-Credentials"))
+	assert.Empty(res.Header.Get("Access-Control-Max-Age"))
+}
+
+func TestCORSValidate(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		cors      transport.CORSConfig
+		err       error
+	}{
+		{
+			test_name: "listed origins with credentials",
+			cors:      transport.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true},
+		},
+		{
+			test_name: "any origin without credentials",
+			cors:      transport.CORSConfig{AllowedOrigins: []string{"*"}},
+		},
+		{
+			test_name: "any origin with credentials",
+			cors:      transport.CORSConfig{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true},
+			err:       transport.ErrCredentialsForAnyOrigin,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			err := tc.cors.Validate()
+
+			// assert
+			assert.Equal(tc.err, err)
+		})
+	}
+}
+
+func TestAnyOriginNeverGetsCredentials(t *testing.T) {
+	endpoints := transport.MakeHttpEndpoints(new(transport.ServiceMock))
+	cors := transport.CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowCredentials: true}
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithCORS(cors))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	// prepare
+	assert := assert.New(t)
+	req, _ := http.NewRequest("OPTIONS", server.URL+"/v2/users/1", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+
+	// act
+	res, _ := http.DefaultClient.Do(req)
+
+	// assert
+	assert.Equal(http.StatusNoContent, res.StatusCode)
+	assert.Equal("*", res.Header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(res.Header.Get("Access-Control-Allow-Credentials"))
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	security := transport.SecurityConfig{HSTSMaxAge: 365 * 24 * time.Hour, HSTSIncludeSubdomains: true}
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithSecurityHeaders(security))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("GetAccount", mock.Anything, 1).Return(entities.Account{Id: 1, Email: "user@email.com", Age: 23}, nil)
+
+	test_cases := []struct {
+		test_name  string
+		path       string
+		httpStatus int
+		csp        string
+	}{
+		{
+			test_name:  "api",
+			path:       "/v2/users/1",
+			httpStatus: 200,
+			csp:        "default-src 'none'; frame-ancestors 'none'",
+		},
+		{
+			test_name:  "not found",
+			path:       "/v3/users",
+			httpStatus: 404,
+			csp:        "default-src 'none'; frame-ancestors 'none'",
+		},
+		{
+			test_name:  "docs",
+			path:       "/docs",
+			httpStatus: 200,
+			csp:        transport.DefaultDocsCSP,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act
+			res, _ := http.Get(server.URL + tc.path)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode)
+			assert.Equal("max-age=31536000; includeSubDomains", res.Header.Get("Strict-Transport-Security"))
+			assert.Equal("nosniff", res.Header.Get("X-Content-Type-Options"))
+			assert.Equal("DENY", res.Header.Get("X-Frame-Options"))
+			assert.Equal("no-referrer", res.Header.Get("Referrer-Policy"))
+			assert.Equal(tc.csp, res.Header.Get("Content-Security-Policy"))
+		})
+	}
+}
+
+func TestSecurityHeadersWithoutHSTS(t *testing.T) {
+	endpoints := transport.MakeHttpEndpoints(new(transport.ServiceMock))
+	security := transport.SecurityConfig{FrameOptions: "SAMEORIGIN", DocsCSP: "default-src 'self'"}
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithSecurityHeaders(security))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	res, _ := http.Get(server.URL + "/docs")
+
+	// assert
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Empty(res.Header.Get("Strict-Transport-Security"))
+	assert.Equal("SAMEORIGIN", res.Header.Get("X-Frame-Options"))
+	assert.Equal("default-src 'self'", res.Header.Get("Content-Security-Policy"))
+}
@@ -2,64 +2,222 @@ package transport
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	gokit_http "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
 	"github.com/mauricioww/user_microsrv/errors"
-	"google.golang.org/grpc/status"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/service"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/mauricioww/user_microsrv/recovery"
+	"github.com/mauricioww/user_microsrv/tracing"
+	"github.com/mauricioww/user_microsrv/validation"
 )
 
-func NewHTTPServer(ctx context.Context, http_endpoints HttpEndpoints) http.Handler {
+type (
+	ServerOption func(*serverOptions)
+
+	serverOptions struct {
+		validate bool
+		auth     bool
+		graphql  service.HttpService
+		sunset   time.Time
+		max_body int64
+		cors     *CORSConfig
+		security *SecurityConfig
+		webhooks *webhook.Dispatcher
+	}
+)
+
+// DefaultMaxBodyBytes caps request bodies unless WithMaxBodyBytes says
+// otherwise; the largest user is well under a kilobyte.
+const DefaultMaxBodyBytes = 1 << 20
+
+// WithOpenAPIValidation checks requests and responses against the OpenAPI
+// document; meant for development and staging, not production traffic.
+func WithOpenAPIValidation() ServerOption {
+	return func(o *serverOptions) {
+		o.validate = true
+	}
+}
+
+// WithAuthentication requires a token from /auth or /v2/sessions on every
+// users route, as GatewayAuthInterceptor does on the gateway.
+func WithAuthentication() ServerOption {
+	return func(o *serverOptions) {
+		o.auth = true
+	}
+}
+
+// WithV1Sunset announces when the v1 routes are removed.
+func WithV1Sunset(sunset time.Time) ServerOption {
+	return func(o *serverOptions) {
+		o.sunset = sunset
+	}
+}
+
+// WithMaxBodyBytes answers requests with a larger body with a 413; zero
+// lifts the limit.
+func WithMaxBodyBytes(limit int64) ServerOption {
+	return func(o *serverOptions) {
+		o.max_body = limit
+	}
+}
+
+func NewHTTPServer(ctx context.Context, http_endpoints HttpEndpoints, logger log.Logger, options ...ServerOption) http.Handler {
+	o := serverOptions{max_body: DefaultMaxBodyBytes}
+	for _, option := range options {
+		option(&o)
+	}
+
+	spec := OpenAPI()
 	root := mux.NewRouter()
-	root.Use(middleware)
+	root.Use(logging.HttpMiddleware, recoveryMiddleware(logger), tracing.Middleware, middleware)
+	if o.max_body > 0 {
+		root.Use(bodyLimitMiddleware(o.max_body))
+	}
+	if o.validate {
+		root.Use(openAPIMiddleware(spec, logger))
+	}
+
+	root.Methods("GET").Path(OpenAPIPath).Handler(specHandler(spec))
+	root.Methods("GET").Path("/docs").Handler(docsCSP(o, docsHandler("text/html; charset=utf-8", docs_html)))
+	root.Methods("GET").Path("/docs/init.js").Handler(docsHandler("text/javascript; charset=utf-8", docs_js))
+	root.Methods("GET").Path("/docs/swagger-ui-bundle.js").Handler(docsHandler("text/javascript; charset=utf-8", swagger_ui_js))
+	root.Methods("GET").Path("/docs/swagger-ui.css").Handler(docsHandler("text/css; charset=utf-8", swagger_ui_css))
+
+	opts := []gokit_http.ServerOption{
+		gokit_http.ServerBefore(gokit_http.PopulateRequestContext),
+		gokit_http.ServerErrorEncoder(encodeError),
+	}
+
+	if o.graphql != nil {
+		root.Methods("POST").Path(GraphQLPath).Handler(graphqlHandler(o.graphql, o.auth, logger))
+	}
 
-	user_router := root.PathPrefix("/users").Subrouter()
-	// user_router.Use(authMiddleware)
+	v2Routes(root.PathPrefix("/v2").Subrouter(), http_endpoints, o, logger, opts)
+	v1Routes(root.PathPrefix("/v1").Subrouter(), http_endpoints, o, logger, opts)
+	// the unversioned paths predate /v1 and stay for the clients using them
+	v1Routes(root.NewRoute().Subrouter(), http_endpoints, o, logger, opts)
 
-	opt := gokit_http.ServerOption(gokit_http.ServerErrorEncoder(encodeError))
+	var handler http.Handler = root
+	if o.cors != nil {
+		handler = corsHandler(*o.cors, handler)
+	}
+	if o.security != nil {
+		handler = securityHandler(*o.security, handler)
+	}
+	return handler
+}
+
+// v1Routes are the original routes, superseded by /v2: the details travel
+// inside every user and /auth takes its credentials in the body of a GET.
+func v1Routes(router *mux.Router, http_endpoints HttpEndpoints, o serverOptions, logger log.Logger, opts []gokit_http.ServerOption) {
+	router.Use(deprecationMiddleware(o.sunset), contentMiddleware(codecs))
+
+	user_router := router.PathPrefix("/users").Subrouter()
+	if o.auth {
+		user_router.Use(authMiddleware(logger))
+	}
 
 	user_router.Methods("GET").Path("/{id}").Handler(gokit_http.NewServer(
 		http_endpoints.GetUser,
 		decodeGetUserRequest,
 		encodeResponse,
-		opt,
+		opts...,
 	))
 
 	user_router.Methods("POST").Handler(gokit_http.NewServer(
 		http_endpoints.CreateUser,
 		decodeCreateUserRequest,
 		encodeResponse,
-		opt,
+		opts...,
 	))
 
 	user_router.Methods("PUT").Path("/{id}").Handler(gokit_http.NewServer(
 		http_endpoints.UpdateUser,
 		decodeUpdateUserRequest,
 		encodeResponse,
-		opt,
+		opts...,
 	))
 
 	user_router.Methods("DELETE").Path("/{id}").Handler(gokit_http.NewServer(
 		http_endpoints.DeleteUser,
 		decodeDeleteUserRequest,
 		encodeResponse,
-		opt,
+		opts...,
+	))
+
+	// the details alone, as /v2 serves them under /profile
+	user_router.Methods("GET").Path("/{id}/details").Handler(gokit_http.NewServer(
+		http_endpoints.GetProfile,
+		decodeGetProfileRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("PUT").Path("/{id}/details").Handler(gokit_http.NewServer(
+		http_endpoints.SetProfile,
+		decodeSetProfileRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("PATCH").Path("/{id}/details").Handler(gokit_http.NewServer(
+		http_endpoints.PatchProfile,
+		decodePatchProfileRequest,
+		encodeResponse,
+		opts...,
 	))
 
-	root.Methods("GET").Path("/auth").Handler(gokit_http.NewServer(
+	user_router.Methods("DELETE").Path("/{id}/details").Handler(gokit_http.NewServer(
+		http_endpoints.DeleteProfile,
+		decodeDeleteProfileRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	router.Methods("GET").Path("/auth").Handler(gokit_http.NewServer(
 		http_endpoints.Authenticate,
 		decodeAuthenticateRequest,
 		encodeResponse,
-		opt,
+		opts...,
 	))
+}
 
-	return root
+// deprecationMiddleware announces that a route is deprecated and where its
+// replacement lives, and from sunset on when it goes away (RFC 8594).
+func deprecationMiddleware(sunset time.Time) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Deprecation", "true")
+			if !sunset.IsZero() {
+				rw.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			rw.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successorPath(r.URL.Path)))
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// successorPath maps a v1 path to its /v2 counterpart.
+func successorPath(path string) string {
+	path = strings.TrimPrefix(path, "/v1")
+	if path == "/auth" {
+		return "/v2/sessions"
+	}
+	if strings.HasSuffix(path, "/details") {
+		path = strings.TrimSuffix(path, "/details") + "/profile"
+	}
+	return "/v2" + path
 }
 
 func middleware(next http.Handler) http.Handler {
@@ -69,54 +227,60 @@ func middleware(next http.Handler) http.Handler {
 	})
 }
 
-func authMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		header_token := strings.Split(r.Header.Get("Authorization"), "Bearer ")
-
-		if len(header_token) != 2 {
-			rw.WriteHeader(http.StatusUnauthorized)
-			res := map[string]string{"error": "No Auth Token!"}
-			json.NewEncoder(rw).Encode(res)
-
-		} else {
-			jwt_token := header_token[1]
-			token, err := jwt.Parse(jwt_token, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("Unexpected signing method %v", token.Header["algo"])
+// recoveryMiddleware answers a panicking handler with a 500 problem instead
+// of dropping the connection. http.ErrAbortHandler is how handlers abort on
+// purpose, so it is left to the server.
+func recoveryMiddleware(logger log.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if p := recover(); p != nil {
+					if p == http.ErrAbortHandler {
+						panic(p)
+					}
+					recovery.Log(logger, r.Context(), r.Method+" "+r.URL.Path, p)
+					ctx := context.WithValue(r.Context(), gokit_http.ContextKeyRequestPath, r.URL.Path)
+					encodeError(ctx, errors.NewInternalError(), rw)
 				}
-				return []byte("this_is_a_secret_shhh"), nil
-			})
-
-			if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-				fmt.Println(claims)
-				next.ServeHTTP(rw, r)
-			} else {
-				fmt.Println(err)
-				rw.WriteHeader(http.StatusUnauthorized)
-				res := map[string]string{"error": "Invalid Token!"}
-				json.NewEncoder(rw).Encode(res)
-			}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
 
-		}
-	})
+// authMiddleware rejects requests without a valid "Bearer <jwt>"
+// Authorization header, as issued by /auth and /v2/sessions.
+func authMiddleware(logger log.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if err := verifyToken(r.Header.Get("Authorization")); err != nil {
+				level.Warn(logger).Log("request_id", logging.RequestID(r.Context()), "method", "auth", "ERROR", err)
+				ctx := context.WithValue(r.Context(), gokit_http.ContextKeyRequestPath, r.URL.Path)
+				encodeError(ctx, err, rw)
+				return
+			}
+			next.ServeHTTP(rw, r)
+		})
+	}
 }
 
 func decodeCreateUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	var request CreateUserRequest
-	err := json.NewDecoder(r.Body).Decode(&request)
-	if err != nil {
+	if err := decodeBody(r, &request); err != nil {
 		return nil, err
 	}
-	return request, nil
+	return request, validateAccount("information", request.Email, request.Password, request.Age, request.Details)
 }
 
 func decodeAuthenticateRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	var request AuthenticateRequest
-	err := json.NewDecoder(r.Body).Decode(&request)
-	if err != nil {
+	if err := decodeBody(r, &request); err != nil {
 		return nil, err
 	}
-	return request, nil
+	return request, validation.Validate(
+		validation.Check("email", request.Email, validation.Required),
+		validation.Check("password", request.Password, validation.Required),
+	)
 }
 
 func decodeUpdateUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
@@ -125,15 +289,14 @@ func decodeUpdateUserRequest(ctx context.Context, r *http.Request) (interface{},
 	id, err := strconv.Atoi(id_param)
 
 	if err != nil {
-		return nil, nil
+		return nil, badId(err)
 	}
-	err = json.NewDecoder(r.Body).Decode(&request)
-	if err != nil {
+	if err := decodeBody(r, &request); err != nil {
 		return nil, err
 	}
 
 	request.UserId = id
-	return request, nil
+	return request, validateUpdate("information", request)
 }
 
 func decodeGetUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
@@ -141,7 +304,7 @@ func decodeGetUserRequest(ctx context.Context, r *http.Request) (interface{}, er
 	id, err := strconv.Atoi(id_param)
 
 	if err != nil {
-		return nil, err
+		return nil, badId(err)
 	}
 
 	request := GetUserRequest{UserId: id}
@@ -153,20 +316,136 @@ func decodeDeleteUserRequest(ctx context.Context, r *http.Request) (interface{},
 	id, err := strconv.Atoi(id_param)
 
 	if err != nil {
-		return nil, err
+		return nil, badId(err)
 	}
 
 	request := DeleteUserRequest{UserId: id}
 	return request, nil
 }
 
+// encodeResponse writes response in the format the Accept header prefers,
+// with the status and headers it asks for through gokit_http.StatusCoder
+// and gokit_http.Headerer.
 func encodeResponse(ctx context.Context, rw http.ResponseWriter, response interface{}) error {
-	return json.NewEncoder(rw).Encode(response)
+	rw.Header().Add("Vary", "Accept")
+
+	code := http.StatusOK
+	if sc, ok := response.(gokit_http.StatusCoder); ok {
+		code = sc.StatusCode()
+	}
+
+	// marshalled before any header is set, so a response that cannot be
+	// produced leaves none behind on the error
+	var body []byte
+	media_type := ""
+	if code != http.StatusNoContent {
+		accept, _ := ctx.Value(gokit_http.ContextKeyRequestAccept).(string)
+		supported := codecsOf(ctx)
+		c, ok := negotiate(accept, supported)
+		if !ok {
+			return errors.NewNotAcceptableError(mediaTypes(supported)...)
+		}
+		var err error
+		if body, err = c.marshal(response); err != nil {
+			return err
+		}
+		media_type = c.media_type
+	}
+
+	if headerer, ok := response.(gokit_http.Headerer); ok {
+		for k, values := range headerer.Headers() {
+			for _, v := range values {
+				rw.Header().Add(k, v)
+			}
+		}
+	}
+	if code == http.StatusNoContent {
+		rw.Header().Del("Content-Type")
+		rw.WriteHeader(code)
+		return nil
+	}
+
+	rw.Header().Set("Content-Type", media_type)
+	rw.WriteHeader(code)
+	_, err := rw.Write(body)
+	return err
+}
+
+// validateAccount checks an account; details_field is how the transport
+// names the details: "information" in v1 JSON, "profile" in v2 and
+// "details" in the gateway.
+func validateAccount(details_field string, email string, pwd string, age int, details entities.Details) error {
+	return validation.Validate(append(accountChecks(email, pwd, age), detailsChecks(details_field+".", details)...)...)
+}
+
+func accountChecks(email string, pwd string, age int) []validation.Field {
+	return []validation.Field{
+		validation.Check("email", email, validation.Required, validation.Email),
+		validation.Check("password", pwd, validation.Required, validation.Password),
+		validation.Check("age", age, validation.Required, validation.Age),
+	}
+}
+
+// hasDetails tells apart an update that leaves the details alone; details
+// that are sent always carry a country.
+func (r UpdateUserRequest) hasDetails() bool {
+	return r.Details != (entities.Details{})
+}
+
+// validateUpdate checks an update, whose details are optional.
+func validateUpdate(details_field string, request UpdateUserRequest) error {
+	if !request.hasDetails() {
+		return validation.Validate(accountChecks(request.Email, request.Password, request.Age)...)
+	}
+	return validateAccount(details_field, request.Email, request.Password, request.Age, request.Details)
+}
+
+// The rules of each details field, shared by whole details and patches.
+var (
+	countryRules = []validation.Rule{validation.Required, validation.CountryCode}
+	cityRules    = []validation.Rule{validation.MaxLength(100)}
+	mobileRules  = []validation.Rule{validation.Optional(validation.Phone)}
+	heightRules  = []validation.Rule{validation.Optional(validation.Height)}
+	weightRules  = []validation.Rule{validation.Optional(validation.Weight)}
+)
+
+// detailsChecks names each field after prefix, empty when the details are
+// the whole body.
+func detailsChecks(prefix string, details entities.Details) []validation.Field {
+	return []validation.Field{
+		validation.Check(prefix+"country", details.Country, countryRules...),
+		validation.Check(prefix+"city", details.City, cityRules...),
+		validation.Check(prefix+"mobile_number", details.MobileNumber, mobileRules...),
+		validation.Check(prefix+"height_m", details.Height, heightRules...),
+		validation.Check(prefix+"weight_kg", details.Weight, weightRules...),
+	}
+}
+
+// patchChecks checks the fields a patch sets by the same rules.
+func patchChecks(patch entities.DetailsPatch) []validation.Field {
+	var checks []validation.Field
+	if patch.Country != nil {
+		checks = append(checks, validation.Check("country", *patch.Country, countryRules...))
+	}
+	if patch.City != nil {
+		checks = append(checks, validation.Check("city", *patch.City, cityRules...))
+	}
+	if patch.MobileNumber != nil {
+		checks = append(checks, validation.Check("mobile_number", *patch.MobileNumber, mobileRules...))
+	}
+	if patch.Height != nil {
+		checks = append(checks, validation.Check("height_m", *patch.Height, heightRules...))
+	}
+	if patch.Weight != nil {
+		checks = append(checks, validation.Check("weight_kg", *patch.Weight, weightRules...))
+	}
+	return checks
+}
+
+func badId(err error) error {
+	return errors.NewInvalidArgumentError(errors.FieldViolation{Field: "id", Description: "must be an integer"}).Wrap(err)
 }
 
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	e, _ := status.FromError(err)
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(errors.ResolveHttp(e.Code()))
-	json.NewEncoder(w).Encode(map[string]string{"error": e.Message()})
+func badBody(err error) error {
+	return errors.NewInvalidArgumentError(errors.FieldViolation{Field: "body", Description: "must be a valid JSON object"}).Wrap(err)
 }
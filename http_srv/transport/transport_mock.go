@@ -41,6 +41,60 @@ func (s *ServiceMock) DeleteUser(ctx context.Context, user_id int) (bool, error)
 	return args.Bool(0), args.Error(1)
 }
 
+func (s *ServiceMock) GetAccount(ctx context.Context, user_id int) (entities.Account, error) {
+	args := s.Called(ctx, user_id)
+
+	return args.Get(0).(entities.Account), args.Error(1)
+}
+
+func (s *ServiceMock) GetUserDetails(ctx context.Context, user_id int) (entities.Details, error) {
+	args := s.Called(ctx, user_id)
+
+	return args.Get(0).(entities.Details), args.Error(1)
+}
+
+func (s *ServiceMock) GetAccounts(ctx context.Context, ids []int) (map[int]entities.Account, error) {
+	args := s.Called(ctx, ids)
+
+	return args.Get(0).(map[int]entities.Account), args.Error(1)
+}
+
+func (s *ServiceMock) GetUsersDetails(ctx context.Context, ids []int) (map[int]entities.Details, error) {
+	args := s.Called(ctx, ids)
+
+	return args.Get(0).(map[int]entities.Details), args.Error(1)
+}
+
+func (s *ServiceMock) UpdateAccount(ctx context.Context, user_id int, email string, pwd string, age int) (bool, error) {
+	args := s.Called(ctx, user_id, email, pwd, age)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *ServiceMock) SetUserDetails(ctx context.Context, user_id int, details entities.Details) (bool, error) {
+	args := s.Called(ctx, user_id, details)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *ServiceMock) PatchUserDetails(ctx context.Context, user_id int, patch entities.DetailsPatch) (entities.Details, error) {
+	args := s.Called(ctx, user_id, patch)
+
+	return args.Get(0).(entities.Details), args.Error(1)
+}
+
+func (s *ServiceMock) DeleteUserDetails(ctx context.Context, user_id int) (bool, error) {
+	args := s.Called(ctx, user_id)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *ServiceMock) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	args := s.Called(ctx, email, limit)
+
+	return args.Get(0).([]entities.Account), args.Error(1)
+}
+
 func GenereateDetails() entities.Details {
 	return entities.Details{
 		Country:      "Mexico",
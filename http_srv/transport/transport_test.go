@@ -2,14 +2,19 @@ package transport_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/http_srv/entities"
 	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/mauricioww/user_microsrv/logging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc/codes"
@@ -19,7 +24,7 @@ import (
 func TestCreateUser(t *testing.T) {
 	srv_mock := new(transport.ServiceMock)
 	endpoints := transport.MakeHttpEndpoints(srv_mock)
-	s := transport.NewHTTPServer(context.Background(), endpoints)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
 	server := httptest.NewServer(s)
 
 	defer server.Close()
@@ -37,13 +42,15 @@ func TestCreateUser(t *testing.T) {
 			body: `
 				{
 					"email": "example@email.com",
-					"password": "querty", 
-					"age": 25
+					"password": "querty123",
+					"age": 25,
+					"information": {"country": "MX"}
 				}`,
 			data: transport.CreateUserRequest{
 				Email:    "example@email.com",
-				Password: "querty",
+				Password: "querty123",
 				Age:      25,
+				Details:  entities.Details{Country: "MX"},
 			},
 			res:        1,
 			err:        nil,
@@ -81,6 +88,18 @@ func TestCreateUser(t *testing.T) {
 			err:        status.Error(codes.FailedPrecondition, "Missing field 'email'"),
 			httpStatus: 400,
 		},
+		{
+			test_name: "invalid fields error",
+			body: `
+				{
+					"email": "example@email",
+					"password": "querty123",
+					"age": 25,
+					"information": {"country": "Mexico", "height_m": -1.7, "weight_kg": 500}
+				}
+			`,
+			httpStatus: 400,
+		},
 	}
 
 	for _, tc := range test_cases {
@@ -102,7 +121,7 @@ func TestCreateUser(t *testing.T) {
 func TestAuthenticate(t *testing.T) {
 	srv_mock := new(transport.ServiceMock)
 	endpoints := transport.MakeHttpEndpoints(srv_mock)
-	s := transport.NewHTTPServer(context.Background(), endpoints)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
 	server := httptest.NewServer(s)
 
 	defer server.Close()
@@ -193,7 +212,7 @@ func TestAuthenticate(t *testing.T) {
 func TestUpdateUser(t *testing.T) {
 	srv_mock := new(transport.ServiceMock)
 	endpoints := transport.MakeHttpEndpoints(srv_mock)
-	s := transport.NewHTTPServer(context.Background(), endpoints)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
 	server := httptest.NewServer(s)
 
 	defer server.Close()
@@ -213,13 +232,15 @@ func TestUpdateUser(t *testing.T) {
 			body: `
 				{
 					"email": "example@email.com",
-					"password": "querty", 
-					"age": 25
+					"password": "querty123",
+					"age": 25,
+					"information": {"country": "MX"}
 				}`,
 			data: transport.UpdateUserRequest{
 				Email:    "example@email.com",
-				Password: "querty",
+				Password: "querty123",
 				Age:      25,
+				Details:  entities.Details{Country: "MX"},
 			},
 			res:        true,
 			err:        nil,
@@ -263,14 +284,16 @@ func TestUpdateUser(t *testing.T) {
 			body: `
 				{
 					"email": "example@email.com",
-					"password": "qwerty",
-					"age": 25
+					"password": "qwerty123",
+					"age": 25,
+					"information": {"country": "MX"}
 				}
 			`,
 			data: transport.UpdateUserRequest{
 				Email:    "example@email.com",
-				Password: "qwerty",
+				Password: "qwerty123",
 				Age:      25,
+				Details:  entities.Details{Country: "MX"},
 			},
 			err:        status.Error(codes.NotFound, "User not found"),
 			httpStatus: 404,
@@ -287,6 +310,7 @@ func TestUpdateUser(t *testing.T) {
 
 			uri := fmt.Sprintf("%v/users/%v", server.URL, tc.user_id)
 			req, _ := http.NewRequest("PUT", uri, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
 			res, _ := http.DefaultClient.Do(req)
 
 			// assert
@@ -298,7 +322,7 @@ func TestUpdateUser(t *testing.T) {
 func TestGetUser(t *testing.T) {
 	srv_mock := new(transport.ServiceMock)
 	endpoints := transport.MakeHttpEndpoints(srv_mock)
-	s := transport.NewHTTPServer(context.Background(), endpoints)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
 	server := httptest.NewServer(s)
 
 	defer server.Close()
@@ -348,7 +372,7 @@ func TestGetUser(t *testing.T) {
 func TestDeleteUser(t *testing.T) {
 	srv_mock := new(transport.ServiceMock)
 	endpoints := transport.MakeHttpEndpoints(srv_mock)
-	s := transport.NewHTTPServer(context.Background(), endpoints)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
 	server := httptest.NewServer(s)
 
 	defer server.Close()
@@ -392,3 +416,257 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestIDEcho(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	// prepare
+	assert := assert.New(t)
+	srv_mock.On("GetUser", mock.Anything, 1).Return(entities.User{}, status.Error(codes.NotFound, "User not found"))
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%v/users/1", server.URL), nil)
+	req.Header.Set("X-Request-ID", "req-42")
+
+	// act
+	res, _ := http.DefaultClient.Do(req)
+	var body transport.Problem
+	json.NewDecoder(res.Body).Decode(&body)
+
+	// assert
+	assert.Equal("req-42", res.Header.Get("X-Request-ID"))
+	assert.Equal("req-42", body.RequestId)
+	srv_mock.AssertCalled(t, "GetUser", mock.MatchedBy(func(ctx context.Context) bool {
+		return logging.RequestID(ctx) == "req-42"
+	}), 1)
+}
+
+func TestProblemResponse(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("GetUser", mock.Anything, 1).Return(entities.User{}, errors.NewInvalidArgumentError(
+		errors.FieldViolation{Field: "email", Description: "is required"},
+	))
+	srv_mock.On("GetUser", mock.Anything, 2).Return(entities.User{}, errors.NewUnavailableError(1500*time.Millisecond))
+	srv_mock.On("GetUser", mock.Anything, 3).Return(entities.User{}, fmt.Errorf("boom"))
+	srv_mock.On("GetUser", mock.Anything, 4).Return(entities.User{}, fmt.Errorf("get user: %w", context.DeadlineExceeded))
+	srv_mock.On("GetUser", mock.Anything, 5).Return(entities.User{}, status.Error(codes.Canceled, "context canceled"))
+	srv_mock.On("GetUser", mock.Anything, 6).Run(func(mock.Arguments) { panic("boom") })
+
+	test_cases := []struct {
+		test_name   string
+		path        string
+		httpStatus  int
+		problem     transport.Problem
+		retry_after string
+	}{
+		{
+			test_name:  "field violations",
+			path:       "/users/1",
+			httpStatus: 400,
+			problem: transport.Problem{
+				Type:          "urn:user-microsrv:problem:invalid_argument",
+				Title:         "Bad Request",
+				Status:        400,
+				Detail:        "Invalid request",
+				Instance:      "/users/1",
+				Reason:        errors.ReasonInvalidArgument,
+				InvalidParams: []transport.InvalidParam{{Name: "email", Reason: "is required"}},
+			},
+		},
+		{
+			test_name:  "retry after",
+			path:       "/users/2",
+			httpStatus: 503,
+			problem: transport.Problem{
+				Type:     "urn:user-microsrv:problem:unavailable",
+				Title:    "Service Unavailable",
+				Status:   503,
+				Detail:   "Service unavailable",
+				Instance: "/users/2",
+				Reason:   errors.ReasonUnavailable,
+			},
+			retry_after: "2",
+		},
+		{
+			test_name:  "unknown error",
+			path:       "/users/3",
+			httpStatus: 500,
+			problem: transport.Problem{
+				Type:     "urn:user-microsrv:problem:internal",
+				Title:    "Internal Server Error",
+				Status:   500,
+				Detail:   "Internal server error",
+				Instance: "/users/3",
+				Reason:   errors.ReasonInternal,
+			},
+		},
+		{
+			test_name:  "deadline exceeded",
+			path:       "/users/4",
+			httpStatus: 504,
+			problem: transport.Problem{
+				Type:     "urn:user-microsrv:problem:deadline_exceeded",
+				Title:    "Gateway Timeout",
+				Status:   504,
+				Detail:   "Request timed out",
+				Instance: "/users/4",
+				Reason:   errors.ReasonDeadlineExceeded,
+			},
+		},
+		{
+			test_name:  "canceled",
+			path:       "/users/5",
+			httpStatus: 499,
+			problem: transport.Problem{
+				Type:     "urn:user-microsrv:problem:canceled",
+				Title:    "Client Closed Request",
+				Status:   499,
+				Detail:   "Request canceled",
+				Instance: "/users/5",
+				Reason:   errors.ReasonCanceled,
+			},
+		},
+		{
+			test_name:  "panic",
+			path:       "/users/6",
+			httpStatus: 500,
+			problem: transport.Problem{
+				Type:     "urn:user-microsrv:problem:internal",
+				Title:    "Internal Server Error",
+				Status:   500,
+				Detail:   "Internal server error",
+				Instance: "/users/6",
+				Reason:   errors.ReasonInternal,
+			},
+		},
+		{
+			test_name:  "bad id",
+			path:       "/users/abc",
+			httpStatus: 400,
+			problem: transport.Problem{
+				Type:          "urn:user-microsrv:problem:invalid_argument",
+				Title:         "Bad Request",
+				Status:        400,
+				Detail:        "Invalid request",
+				Instance:      "/users/abc",
+				Reason:        errors.ReasonInvalidArgument,
+				InvalidParams: []transport.InvalidParam{{Name: "id", Reason: "must be an integer"}},
+			},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(http.MethodGet, server.URL+tc.path, nil)
+			req.Header.Set("X-Request-ID", "req-1")
+			tc.problem.RequestId = "req-1"
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+			var body transport.Problem
+			json.NewDecoder(res.Body).Decode(&body)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode)
+			assert.Equal(transport.ProblemContentType, res.Header.Get("Content-Type"))
+			assert.Equal(tc.retry_after, res.Header.Get("Retry-After"))
+			assert.Equal(tc.problem, body)
+		})
+	}
+}
+
+func TestValidationProblem(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	test_cases := []struct {
+		test_name      string
+		method         string
+		path           string
+		body           string
+		invalid_params []transport.InvalidParam
+	}{
+		{
+			test_name: "every violation reported",
+			method:    "POST",
+			path:      "/users",
+			body: `{
+				"email": "example@email",
+				"password": "querty",
+				"age": 0,
+				"information": {"country": "Mexico", "mobile_number": "55-1234", "height_m": -1.7, "weight_kg": 500}
+			}`,
+			invalid_params: []transport.InvalidParam{
+				{Name: "email", Reason: "must be a valid email address"},
+				{Name: "password", Reason: "must be between 8 and 72 characters"},
+				{Name: "age", Reason: "is required"},
+				{Name: "information.country", Reason: "must be an ISO 3166-1 alpha-2 country code"},
+				{Name: "information.mobile_number", Reason: "must be a phone number of 7 to 15 digits"},
+				{Name: "information.height_m", Reason: "must be between 0.3 and 2.8"},
+				{Name: "information.weight_kg", Reason: "must be between 1 and 350"},
+			},
+		},
+		{
+			test_name: "authenticate skips password policy",
+			method:    "GET",
+			path:      "/auth",
+			body:      `{"password": "x"}`,
+			invalid_params: []transport.InvalidParam{
+				{Name: "email", Reason: "is required"},
+			},
+		},
+		{
+			test_name: "update with bad id",
+			method:    "PUT",
+			path:      "/users/abc",
+			body:      `{}`,
+			invalid_params: []transport.InvalidParam{
+				{Name: "id", Reason: "must be an integer"},
+			},
+		},
+		{
+			test_name: "malformed body",
+			method:    "POST",
+			path:      "/users",
+			body:      `{"email": `,
+			invalid_params: []transport.InvalidParam{
+				{Name: "body", Reason: "must be a valid JSON object"},
+			},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+			var body transport.Problem
+			json.NewDecoder(res.Body).Decode(&body)
+
+			// assert
+			assert.Equal(400, res.StatusCode)
+			assert.Equal(errors.ReasonInvalidArgument, body.Reason)
+			assert.Equal(tc.invalid_params, body.InvalidParams)
+			assert.Empty(srv_mock.Calls)
+		})
+	}
+}
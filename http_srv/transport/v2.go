@@ -0,0 +1,193 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	gokit_http "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/mauricioww/user_microsrv/validation"
+)
+
+// v2Routes split a user into its account, /v2/users/{id}, and its profile,
+// /v2/users/{id}/profile, so each can be read and replaced on its own;
+// logging in creates a session instead of sending a body with a GET.
+func v2Routes(router *mux.Router, http_endpoints HttpEndpoints, o serverOptions, logger log.Logger, opts []gokit_http.ServerOption) {
+	router.Methods("POST").Path("/sessions").Handler(contentMiddleware(codecs)(gokit_http.NewServer(
+		http_endpoints.CreateSession,
+		decodeCreateSessionRequest,
+		encodeResponse,
+		opts...,
+	)))
+
+	user_router := router.PathPrefix("/users").Subrouter()
+	user_router.Use(contentMiddleware(codecs))
+	if o.auth {
+		user_router.Use(authMiddleware(logger))
+	}
+
+	user_router.Methods("POST").Handler(gokit_http.NewServer(
+		http_endpoints.CreateAccount,
+		decodeCreateAccountRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("GET").Path("/{id}").Handler(gokit_http.NewServer(
+		http_endpoints.GetAccount,
+		decodeGetAccountRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("PUT").Path("/{id}").Handler(gokit_http.NewServer(
+		http_endpoints.UpdateAccount,
+		decodeUpdateAccountRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("DELETE").Path("/{id}").Handler(gokit_http.NewServer(
+		http_endpoints.DeleteAccount,
+		decodeDeleteAccountRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("GET").Path("/{id}/profile").Handler(gokit_http.NewServer(
+		http_endpoints.GetProfile,
+		decodeGetProfileRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("PUT").Path("/{id}/profile").Handler(gokit_http.NewServer(
+		http_endpoints.SetProfile,
+		decodeSetProfileRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("PATCH").Path("/{id}/profile").Handler(gokit_http.NewServer(
+		http_endpoints.PatchProfile,
+		decodePatchProfileRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	user_router.Methods("DELETE").Path("/{id}/profile").Handler(gokit_http.NewServer(
+		http_endpoints.DeleteProfile,
+		decodeDeleteProfileRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	if o.webhooks != nil {
+		webhookRoutes(router, o.webhooks, logger, opts)
+	}
+}
+
+func decodeCreateSessionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var request CreateSessionRequest
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	return request, validation.Validate(
+		validation.Check("email", request.Email, validation.Required),
+		validation.Check("password", request.Password, validation.Required),
+	)
+}
+
+func decodeCreateAccountRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var request CreateAccountRequest
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	return request, validateAccount("profile", request.Email, request.Password, request.Age, request.Profile)
+}
+
+func decodeGetAccountRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := userId(r)
+	if err != nil {
+		return nil, err
+	}
+	return GetAccountRequest{UserId: id}, nil
+}
+
+func decodeUpdateAccountRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var request UpdateAccountRequest
+	id, err := userId(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+
+	request.UserId = id
+	return request, validation.Validate(accountChecks(request.Email, request.Password, request.Age)...)
+}
+
+func decodeDeleteAccountRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := userId(r)
+	if err != nil {
+		return nil, err
+	}
+	return DeleteAccountRequest{UserId: id}, nil
+}
+
+func decodeGetProfileRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := userId(r)
+	if err != nil {
+		return nil, err
+	}
+	return GetProfileRequest{UserId: id}, nil
+}
+
+func decodeSetProfileRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var request SetProfileRequest
+	id, err := userId(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+
+	request.UserId = id
+	return request, validation.Validate(detailsChecks("", request.Details)...)
+}
+
+// decodePatchProfileRequest takes the fields to replace; those left out, or
+// null, keep their value.
+func decodePatchProfileRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var request PatchProfileRequest
+	id, err := userId(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+
+	request.UserId = id
+	return request, validation.Validate(patchChecks(request.DetailsPatch)...)
+}
+
+func decodeDeleteProfileRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := userId(r)
+	if err != nil {
+		return nil, err
+	}
+	return DeleteProfileRequest{UserId: id}, nil
+}
+
+func userId(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, badId(err)
+	}
+	return id, nil
+}
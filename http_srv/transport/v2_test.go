@@ -0,0 +1,226 @@
+package transport_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestV2Routes(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithOpenAPIValidation())
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	profile := entities.Details{Country: "MX", City: "CDMX"}
+	srv_mock.On("Authenticate", mock.Anything, "user@email.com", "qwerty123").Return("token", nil)
+	srv_mock.On("Authenticate", mock.Anything, "user@email.com", "wrong").Return("", errors.NewUnauthorizedError())
+	srv_mock.On("CreateUser", mock.Anything, "user@email.com", "qwerty123", 23, profile).Return(7, nil)
+	srv_mock.On("GetAccount", mock.Anything, 7).Return(entities.Account{Id: 7, Email: "user@email.com", Age: 23}, nil)
+	srv_mock.On("GetAccount", mock.Anything, 404).Return(entities.Account{}, errors.NewUserNotFoundError())
+	srv_mock.On("UpdateAccount", mock.Anything, 7, "new@email.com", "qwerty123", 24).Return(true, nil)
+	srv_mock.On("DeleteUser", mock.Anything, 7).Return(true, nil)
+	srv_mock.On("GetUserDetails", mock.Anything, 7).Return(profile, nil)
+	srv_mock.On("SetUserDetails", mock.Anything, 7, entities.Details{Country: "US"}).Return(true, nil)
+
+	test_cases := []struct {
+		test_name  string
+		method     string
+		path       string
+		body       string
+		httpStatus int
+		res        string
+		location   string
+	}{
+		{
+			test_name:  "create session",
+			method:     "POST",
+			path:       "/v2/sessions",
+			body:       `{"email": "user@email.com", "password": "qwerty123"}`,
+			httpStatus: 201,
+			res:        `{"token": "token"}`,
+		},
+		{
+			test_name:  "wrong credentials",
+			method:     "POST",
+			path:       "/v2/sessions",
+			body:       `{"email": "user@email.com", "password": "wrong"}`,
+			httpStatus: 401,
+		},
+		{
+			test_name:  "create account",
+			method:     "POST",
+			path:       "/v2/users",
+			body:       `{"email": "user@email.com", "password": "qwerty123", "age": 23, "profile": {"country": "MX", "city": "CDMX"}}`,
+			httpStatus: 201,
+			res:        `{"id": 7, "email": "user@email.com", "age": 23}`,
+			location:   "/v2/users/7",
+		},
+		{
+			test_name:  "create account without profile",
+			method:     "POST",
+			path:       "/v2/users",
+			body:       `{"email": "user@email.com", "password": "qwerty123", "age": 23}`,
+			httpStatus: 400,
+		},
+		{
+			test_name:  "get account",
+			method:     "GET",
+			path:       "/v2/users/7",
+			httpStatus: 200,
+			res:        `{"id": 7, "email": "user@email.com", "age": 23}`,
+		},
+		{
+			test_name:  "missing account",
+			method:     "GET",
+			path:       "/v2/users/404",
+			httpStatus: 404,
+		},
+		{
+			test_name:  "update account",
+			method:     "PUT",
+			path:       "/v2/users/7",
+			body:       `{"email": "new@email.com", "password": "qwerty123", "age": 24}`,
+			httpStatus: 200,
+			res:        `{"id": 7, "email": "new@email.com", "age": 24}`,
+		},
+		{
+			test_name:  "delete account",
+			method:     "DELETE",
+			path:       "/v2/users/7",
+			httpStatus: 204,
+		},
+		{
+			test_name:  "get profile",
+			method:     "GET",
+			path:       "/v2/users/7/profile",
+			httpStatus: 200,
+			res:        `{"country": "MX", "city": "CDMX", "mobile_number": "", "married": false, "height_m": 0, "weight_kg": 0}`,
+		},
+		{
+			test_name:  "set profile",
+			method:     "PUT",
+			path:       "/v2/users/7/profile",
+			body:       `{"country": "US"}`,
+			httpStatus: 200,
+			res:        `{"country": "US", "city": "", "mobile_number": "", "married": false, "height_m": 0, "weight_kg": 0}`,
+		},
+		{
+			test_name:  "invalid profile",
+			method:     "PUT",
+			path:       "/v2/users/7/profile",
+			body:       `{"country": "Mexico"}`,
+			httpStatus: 400,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(res.Body)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode, string(body))
+			assert.Empty(res.Header.Get("Deprecation"))
+			assert.Equal(tc.location, res.Header.Get("Location"))
+			if tc.res != "" {
+				assert.JSONEq(tc.res, string(body))
+			}
+			if tc.httpStatus == http.StatusNoContent {
+				assert.Empty(body)
+			}
+		})
+	}
+
+	srv_mock.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestV1Deprecation(t *testing.T) {
+	srv_mock := new(transport.ServiceMock)
+	endpoints := transport.MakeHttpEndpoints(srv_mock)
+	sunset := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithV1Sunset(sunset))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	srv_mock.On("GetUser", mock.Anything, 1).Return(entities.User{Email: "user@email.com"}, nil)
+	srv_mock.On("Authenticate", mock.Anything, "user@email.com", "qwerty123").Return("token", nil)
+
+	test_cases := []struct {
+		test_name  string
+		method     string
+		path       string
+		body       string
+		httpStatus int
+		successor  string
+	}{
+		{
+			test_name:  "versioned user",
+			method:     "GET",
+			path:       "/v1/users/1",
+			httpStatus: 200,
+			successor:  "/v2/users/1",
+		},
+		{
+			test_name:  "unversioned user",
+			method:     "GET",
+			path:       "/users/1",
+			httpStatus: 200,
+			successor:  "/v2/users/1",
+		},
+		{
+			test_name:  "versioned auth",
+			method:     "GET",
+			path:       "/v1/auth",
+			body:       `{"email": "user@email.com", "password": "qwerty123"}`,
+			httpStatus: 200,
+			successor:  "/v2/sessions",
+		},
+		{
+			test_name:  "errors are marked too",
+			method:     "GET",
+			path:       "/v1/users/abc",
+			httpStatus: 400,
+			successor:  "/v2/users/abc",
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode)
+			assert.Equal("true", res.Header.Get("Deprecation"))
+			assert.Equal("Fri, 01 Jan 2027 00:00:00 GMT", res.Header.Get("Sunset"))
+			assert.Equal(`<`+tc.successor+`>; rel="successor-version"`, res.Header.Get("Link"))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	gokit_http "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+	"github.com/mauricioww/user_microsrv/validation"
+)
+
+// WithWebhooks serves the subscriptions of d under /v2/webhooks.
+func WithWebhooks(d *webhook.Dispatcher) ServerOption {
+	return func(o *serverOptions) {
+		o.webhooks = d
+	}
+}
+
+// webhookCodecs leave protobuf out: there are no messages for the webhook
+// types.
+var webhookCodecs = only(JSONContentType, MsgpackContentType)
+
+// webhookRoutes manage the subscriptions and read their delivery log; the
+// events themselves are published by service.WebhookMiddleware. They want a
+// token even without WithAuthentication, since a subscription receives
+// every user mutation.
+func webhookRoutes(router *mux.Router, d *webhook.Dispatcher, logger log.Logger, opts []gokit_http.ServerOption) {
+	webhook_router := router.PathPrefix("/webhooks").Subrouter()
+	webhook_router.Use(contentMiddleware(webhookCodecs), authMiddleware(logger))
+
+	webhook_router.Methods("POST").Handler(gokit_http.NewServer(
+		makeCreateWebhookEndpoint(d),
+		decodeCreateWebhookRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	webhook_router.Methods("GET").Path("/{id}").Handler(gokit_http.NewServer(
+		makeGetWebhookEndpoint(d),
+		decodeGetWebhookRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	webhook_router.Methods("DELETE").Path("/{id}").Handler(gokit_http.NewServer(
+		makeDeleteWebhookEndpoint(d),
+		decodeDeleteWebhookRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	webhook_router.Methods("GET").Path("/{id}/deliveries").Handler(gokit_http.NewServer(
+		makeListDeliveriesEndpoint(d),
+		decodeListDeliveriesRequest,
+		encodeResponse,
+		opts...,
+	))
+
+	// last, as without a path it matches the GETs above too
+	webhook_router.Methods("GET").Handler(gokit_http.NewServer(
+		makeListWebhooksEndpoint(d),
+		gokit_http.NopRequestDecoder,
+		encodeResponse,
+		opts...,
+	))
+}
+
+func makeCreateWebhookEndpoint(d *webhook.Dispatcher) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateWebhookRequest)
+		res, err := d.Subscribe(ctx, req.URL, req.Events, req.Secret)
+		return CreateWebhookResponse{webhookResponse(res)}, err
+	}
+}
+
+func makeListWebhooksEndpoint(d *webhook.Dispatcher) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		res, err := d.ListSubscriptions(ctx)
+		webhooks := make([]WebhookResponse, len(res))
+		for i, s := range res {
+			webhooks[i] = webhookResponse(s)
+		}
+		return ListWebhooksResponse{Webhooks: webhooks}, err
+	}
+}
+
+func makeGetWebhookEndpoint(d *webhook.Dispatcher) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetWebhookRequest)
+		res, err := d.GetSubscription(ctx, req.Id)
+		return webhookResponse(res), err
+	}
+}
+
+func makeDeleteWebhookEndpoint(d *webhook.Dispatcher) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteWebhookRequest)
+		return DeleteWebhookResponse{}, d.Unsubscribe(ctx, req.Id)
+	}
+}
+
+func makeListDeliveriesEndpoint(d *webhook.Dispatcher) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListDeliveriesRequest)
+		res, err := d.Deliveries(ctx, req.WebhookId, req.Status)
+		deliveries := make([]DeliveryResponse, len(res))
+		for i, delivery := range res {
+			deliveries[i] = deliveryResponse(delivery)
+		}
+		return ListDeliveriesResponse{Deliveries: deliveries}, err
+	}
+}
+
+// decodeCreateWebhookRequest wants an absolute http(s) URL, at least one
+// known event type and a secret long enough to key the signatures.
+func decodeCreateWebhookRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var request CreateWebhookRequest
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+
+	checks := []validation.Field{
+		validation.Check("url", request.URL, validation.Required, validation.URL),
+		validation.Check("events", len(request.Events), validation.Required),
+		validation.Check("secret", request.Secret, validation.Required, validation.MinLength(webhook.MinSecretLength)),
+	}
+	for i, e := range request.Events {
+		checks = append(checks, validation.Check("events."+strconv.Itoa(i), e, validation.OneOf(webhook.EventTypes...)))
+	}
+	return request, validation.Validate(checks...)
+}
+
+func decodeGetWebhookRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return GetWebhookRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+func decodeDeleteWebhookRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return DeleteWebhookRequest{Id: mux.Vars(r)["id"]}, nil
+}
+
+// decodeListDeliveriesRequest filters the log by the status query
+// parameter, when there is one.
+func decodeListDeliveriesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	request := ListDeliveriesRequest{WebhookId: mux.Vars(r)["id"], Status: r.URL.Query().Get("status")}
+	return request, validation.Validate(
+		validation.Check("status", request.Status, validation.Optional(validation.OneOf(webhook.StatusPending, webhook.StatusDelivered, webhook.StatusDead))),
+	)
+}
+
+func webhookResponse(s webhook.Subscription) WebhookResponse {
+	return WebhookResponse{Id: s.Id, URL: s.URL, Events: s.Events, CreatedAt: s.CreatedAt}
+}
+
+func deliveryResponse(d webhook.Delivery) DeliveryResponse {
+	res := DeliveryResponse{
+		Id:             d.Id,
+		EventId:        d.Event.Id,
+		EventType:      d.Event.Type,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+	}
+	if !d.NextAttemptAt.IsZero() {
+		res.NextAttemptAt = &d.NextAttemptAt
+	}
+	if !d.DeliveredAt.IsZero() {
+		res.DeliveredAt = &d.DeliveredAt
+	}
+	return res
+}
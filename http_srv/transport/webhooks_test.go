@@ -0,0 +1,291 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+	"github.com/mauricioww/user_microsrv/http_srv/service"
+	"github.com/mauricioww/user_microsrv/http_srv/transport"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const webhookSecret = "0123456789abcdef"
+
+// newDispatcher lets the receivers of the tests, all on loopback, be
+// subscribed.
+func newDispatcher() *webhook.Dispatcher {
+	return webhook.NewDispatcher(webhook.NewMemoryStore(100), webhook.Config{
+		MaxAttempts:  2,
+		Backoff:      time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+		Timeout:      time.Second,
+		Poll:         time.Millisecond,
+		Workers:      1,
+		AllowPrivate: true,
+	}, log.NewNopLogger())
+}
+
+// doRequest sends a valid token along unless the path is outside
+// /v2/webhooks.
+func doRequest(method string, url string, body string, accept string) (*http.Response, string) {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if strings.Contains(url, "/v2/webhooks") {
+		req.Header.Set("Authorization", "Bearer "+signedToken("this_is_a_secret_shhh", time.Now().Add(time.Minute)))
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	res, _ := http.DefaultClient.Do(req)
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	return res, string(b)
+}
+
+func TestCreateWebhook(t *testing.T) {
+	endpoints := transport.MakeHttpEndpoints(new(transport.ServiceMock))
+
+	test_cases := []struct {
+		test_name  string
+		body       string
+		accept     string
+		httpStatus int
+		fields     []string
+	}{
+		{
+			test_name:  "subscribed",
+			body:       `{"url": "https://partner.example.com/hooks", "events": ["user.created", "user.deleted"], "secret": "0123456789abcdef"}`,
+			httpStatus: 201,
+		},
+		{
+			test_name:  "invalid subscription",
+			body:       `{"url": "partner.example.com", "events": ["user.created", "user.read"], "secret": "short"}`,
+			httpStatus: 400,
+			fields:     []string{"url", "secret", "events.1"},
+		},
+		{
+			test_name:  "no events",
+			body:       `{"url": "https://partner.example.com/hooks", "events": [], "secret": "0123456789abcdef"}`,
+			httpStatus: 400,
+			fields:     []string{"events"},
+		},
+		{
+			test_name:  "protobuf not available",
+			body:       `{"url": "https://partner.example.com/hooks", "events": ["user.created"], "secret": "0123456789abcdef"}`,
+			accept:     transport.ProtobufContentType,
+			httpStatus: 406,
+		},
+		{
+			test_name:  "JSON accepted after protobuf",
+			body:       `{"url": "https://partner.example.com/hooks", "events": ["user.created", "user.deleted"], "secret": "0123456789abcdef"}`,
+			accept:     "application/x-protobuf, application/json;q=0.9",
+			httpStatus: 201,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			d := newDispatcher()
+			server := httptest.NewServer(transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithWebhooks(d)))
+			defer server.Close()
+
+			// act
+			res, body := doRequest("POST", server.URL+"/v2/webhooks", tc.body, tc.accept)
+
+			// assert
+			assert.Equal(tc.httpStatus, res.StatusCode, body)
+			assert.NotContains(body, webhookSecret)
+			subscriptions, _ := d.ListSubscriptions(context.Background())
+			if tc.httpStatus != http.StatusCreated {
+				assert.Empty(res.Header.Get("Location"))
+				assert.Empty(subscriptions)
+			}
+			if tc.httpStatus == http.StatusCreated {
+				var created transport.WebhookResponse
+				assert.Nil(json.Unmarshal([]byte(body), &created))
+				assert.Equal("/v2/webhooks/"+created.Id, res.Header.Get("Location"))
+				assert.Equal([]string{webhook.UserCreated, webhook.UserDeleted}, created.Events)
+				assert.Len(subscriptions, 1)
+			}
+			for _, field := range tc.fields {
+				assert.Contains(body, `"name":"`+field+`"`)
+			}
+		})
+	}
+}
+
+func TestWebhooksNeedToken(t *testing.T) {
+	endpoints := transport.MakeHttpEndpoints(new(transport.ServiceMock))
+	d := newDispatcher()
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithWebhooks(d))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	test_cases := []struct {
+		test_name string
+		method    string
+		path      string
+		body      string
+	}{
+		{
+			test_name: "subscribe",
+			method:    "POST",
+			path:      "/v2/webhooks",
+			body:      `{"url": "https://partner.example.com/hooks", "events": ["user.created"], "secret": "0123456789abcdef"}`,
+		},
+		{
+			test_name: "list",
+			method:    "GET",
+			path:      "/v2/webhooks",
+		},
+		{
+			test_name: "deliveries",
+			method:    "GET",
+			path:      "/v2/webhooks/1/deliveries",
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			req, _ := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			// act
+			res, _ := http.DefaultClient.Do(req)
+
+			// assert
+			assert.Equal(http.StatusUnauthorized, res.StatusCode)
+			subscriptions, _ := d.ListSubscriptions(context.Background())
+			assert.Empty(subscriptions)
+		})
+	}
+}
+
+func TestWebhookPrivateTarget(t *testing.T) {
+	endpoints := transport.MakeHttpEndpoints(new(transport.ServiceMock))
+	d := webhook.NewDispatcher(webhook.NewMemoryStore(100), webhook.Config{MaxAttempts: 1}, log.NewNopLogger())
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithWebhooks(d))
+	server := httptest.NewServer(s)
+
+	defer server.Close()
+
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	res, body := doRequest("POST", server.URL+"/v2/webhooks", `{"url": "http://169.254.169.254/latest/meta-data", "events": ["user.created"], "secret": "0123456789abcdef"}`, "")
+
+	// assert
+	assert.Equal(http.StatusBadRequest, res.StatusCode, body)
+	assert.Contains(body, `"name":"url"`)
+	subscriptions, _ := d.ListSubscriptions(context.Background())
+	assert.Empty(subscriptions)
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	endpoints := transport.MakeHttpEndpoints(new(transport.ServiceMock))
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithOpenAPIValidation(), transport.WithWebhooks(newDispatcher()))
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	res, body := doRequest("POST", server.URL+"/v2/webhooks", `{"url": "https://partner.example.com/hooks", "events": ["user.created"], "secret": "0123456789abcdef"}`, "")
+	location := res.Header.Get("Location")
+
+	// act & assert
+	res, body = doRequest("GET", server.URL+location, "", "")
+	assert.Equal(200, res.StatusCode, body)
+	assert.Contains(body, `"url":"https://partner.example.com/hooks"`)
+	assert.NotContains(body, "secret")
+
+	res, body = doRequest("GET", server.URL+"/v2/webhooks", "", "")
+	assert.Equal(200, res.StatusCode, body)
+	assert.Contains(body, location[len("/v2/webhooks/"):])
+
+	res, body = doRequest("GET", server.URL+location+"/deliveries", "", "")
+	assert.Equal(200, res.StatusCode, body)
+	assert.JSONEq(`{"deliveries": []}`, body)
+
+	res, body = doRequest("GET", server.URL+location+"/deliveries?status=lost", "", "")
+	assert.Equal(400, res.StatusCode, body)
+
+	res, _ = doRequest("DELETE", server.URL+location, "", "")
+	assert.Equal(204, res.StatusCode)
+
+	for _, path := range []string{location, location + "/deliveries"} {
+		res, body = doRequest("GET", server.URL+path, "", "")
+		assert.Equal(404, res.StatusCode, path)
+		assert.Contains(body, "WEBHOOK_NOT_FOUND")
+	}
+	res, _ = doRequest("DELETE", server.URL+location, "", "")
+	assert.Equal(404, res.StatusCode)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	received := make(chan []byte, 1)
+	var header http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		header = r.Header
+		received <- body
+	}))
+	defer receiver.Close()
+
+	d := newDispatcher()
+	srv_mock := new(transport.ServiceMock)
+	profile := entities.Details{Country: "MX", City: "CDMX"}
+	srv_mock.On("CreateUser", mock.Anything, "user@email.com", "qwerty123", 23, profile).Return(7, nil)
+	endpoints := transport.MakeHttpEndpoints(service.WebhookMiddleware(d)(srv_mock))
+	s := transport.NewHTTPServer(context.Background(), endpoints, log.NewNopLogger(), transport.WithWebhooks(d))
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	res, body := doRequest("POST", server.URL+"/v2/webhooks", `{"url": "`+receiver.URL+`", "events": ["user.created"], "secret": "0123456789abcdef"}`, "")
+	location := res.Header.Get("Location")
+
+	// act
+	res, body = doRequest("POST", server.URL+"/v2/users", `{"email": "user@email.com", "password": "qwerty123", "age": 23, "profile": {"country": "MX", "city": "CDMX"}}`, "")
+	d.Flush(context.Background())
+
+	// assert
+	assert.Equal(201, res.StatusCode, body)
+	select {
+	case delivered := <-received:
+		assert.Nil(webhook.Verify(webhookSecret, header, delivered, time.Minute, time.Now()))
+		assert.Equal(webhook.UserCreated, header.Get(webhook.EventHeader))
+		assert.Contains(string(delivered), `"data":{"user_id":7,"email":"user@email.com","age":23,"profile":{"country":"MX","city":"CDMX"`)
+	default:
+		t.Fatal("nothing delivered")
+	}
+
+	res, body = doRequest("GET", server.URL+location+"/deliveries?status=delivered", "", "")
+	assert.Equal(200, res.StatusCode, body)
+	var delivery_log transport.ListDeliveriesResponse
+	assert.Nil(json.Unmarshal([]byte(body), &delivery_log))
+	if assert.Len(delivery_log.Deliveries, 1) {
+		assert.Equal(webhook.UserCreated, delivery_log.Deliveries[0].EventType)
+		assert.Equal(200, delivery_log.Deliveries[0].LastStatusCode)
+		assert.Equal(1, delivery_log.Deliveries[0].Attempts)
+		assert.NotNil(delivery_log.Deliveries[0].DeliveredAt)
+	}
+}
@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/mauricioww/user_microsrv/logging"
+)
+
+type Config struct {
+	// MaxAttempts counts the first one; a delivery failing them all is
+	// dead.
+	MaxAttempts int
+	// Backoff doubles after every failed attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout bounds each attempt, the receiver's answer included.
+	Timeout time.Duration
+	// Poll is how often Run looks for retries that became due.
+	Poll time.Duration
+	// Workers bounds the attempts made at once.
+	Workers int
+	// AllowPrivate lets subscriptions reach loopback, private and
+	// link-local addresses, which only tests should need.
+	AllowPrivate bool
+}
+
+// Dispatcher manages the subscriptions and delivers the events published
+// to them.
+type Dispatcher struct {
+	store  Store
+	config Config
+	client *http.Client
+	logger log.Logger
+	wake   chan struct{}
+}
+
+func NewDispatcher(store Store, config Config, logger log.Logger) *Dispatcher {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	return &Dispatcher{
+		store:  store,
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: newTransport(config.AllowPrivate),
+			// a redirect is a failed attempt: following it would POST the
+			// event, and its signature, somewhere not subscribed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Subscribe refuses a URL pointing inside the network, as the deliveries
+// would otherwise let anyone subscribing probe it.
+func (d *Dispatcher) Subscribe(ctx context.Context, url string, events []string, secret string) (Subscription, error) {
+	if !d.config.AllowPrivate {
+		if err := checkTarget(ctx, url); err != nil {
+			return Subscription{}, err
+		}
+	}
+	s := Subscription{Id: newId(), URL: url, Events: events, Secret: secret, CreatedAt: time.Now().UTC()}
+	return s, d.store.AddSubscription(ctx, s)
+}
+
+func (d *Dispatcher) GetSubscription(ctx context.Context, id string) (Subscription, error) {
+	return d.store.GetSubscription(ctx, id)
+}
+
+func (d *Dispatcher) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	return d.store.ListSubscriptions(ctx)
+}
+
+// Unsubscribe drops the pending deliveries with the subscription.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id string) error {
+	return d.store.DeleteSubscription(ctx, id)
+}
+
+// Deliveries is the delivery log of a subscription, newest first and
+// filtered by status unless it is empty.
+func (d *Dispatcher) Deliveries(ctx context.Context, subscription_id string, status string) ([]Delivery, error) {
+	return d.store.ListDeliveries(ctx, subscription_id, status)
+}
+
+// Publish queues an event for every subscription wanting its type. The
+// attempts are left to Run, so a slow receiver never holds up the mutation
+// that published it.
+func (d *Dispatcher) Publish(ctx context.Context, event_type string, data interface{}) {
+	subscriptions, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		level.Error(d.logger).Log("request_id", logging.RequestID(ctx), "webhook_event", event_type, "ERROR", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	event := Event{Id: newId(), Type: event_type, CreatedAt: now, Data: data}
+	queued := false
+	for _, s := range subscriptions {
+		if !s.wants(event_type) {
+			continue
+		}
+		delivery := Delivery{Id: newId(), SubscriptionId: s.Id, Event: event, Status: StatusPending, NextAttemptAt: now, CreatedAt: now}
+		if err := d.store.AddDelivery(ctx, delivery); err != nil {
+			level.Warn(d.logger).Log("request_id", logging.RequestID(ctx), "webhook", s.Id, "webhook_event", event_type, "ERROR", err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run attempts the deliveries as they are published and the retries as
+// they become due, until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Poll)
+	defer ticker.Stop()
+
+	for {
+		d.Flush(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Flush attempts every due delivery once, Workers at a time, and returns
+// once they are all done.
+func (d *Dispatcher) Flush(ctx context.Context) {
+	due, err := d.store.DueDeliveries(ctx, time.Now())
+	if err != nil {
+		level.Error(d.logger).Log("method", "flush", "ERROR", err)
+		return
+	}
+
+	sem := make(chan struct{}, d.config.Workers)
+	var wg sync.WaitGroup
+	for _, delivery := range due {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery Delivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) {
+	s, err := d.store.GetSubscription(ctx, delivery.SubscriptionId)
+	if err != nil {
+		// unsubscribed since
+		return
+	}
+
+	status, err := d.send(ctx, s, delivery)
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = status
+	delivery.NextAttemptAt = time.Time{}
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.DeliveredAt = now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = StatusDead
+		delivery.LastError = errorClass(err, status)
+		level.Warn(d.logger).Log("webhook", s.Id, "delivery", delivery.Id, "webhook_event", delivery.Event.Type, "attempts", delivery.Attempts, "status", StatusDead, "ERROR", err)
+	default:
+		delivery.LastError = errorClass(err, status)
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		level.Debug(d.logger).Log("webhook", s.Id, "delivery", delivery.Id, "webhook_event", delivery.Event.Type, "attempts", delivery.Attempts, "ERROR", err)
+	}
+
+	if err := d.store.UpdateDelivery(ctx, delivery); err != nil {
+		level.Error(d.logger).Log("webhook", s.Id, "delivery", delivery.Id, "ERROR", err)
+	}
+}
+
+// send POSTs the event to the subscription and tells the status it got
+// back, zero when there was no answer.
+func (d *Dispatcher) send(ctx context.Context, s Subscription, delivery Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, delivery.Id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(s.Secret, timestamp, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// drained so the connection can be reused, up to a point
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook: receiver answered %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	b := d.config.Backoff << uint(attempts-1)
+	if b <= 0 || b > d.config.MaxBackoff {
+		b = d.config.MaxBackoff
+	}
+	return b
+}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/errors"
+)
+
+// Store keeps the subscriptions and their delivery log. Implementations
+// must be safe for concurrent use and return copies, never shared values.
+type Store interface {
+	AddSubscription(ctx context.Context, s Subscription) error
+	GetSubscription(ctx context.Context, id string) (Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	// DeleteSubscription drops its delivery log too.
+	DeleteSubscription(ctx context.Context, id string) error
+
+	AddDelivery(ctx context.Context, d Delivery) error
+	UpdateDelivery(ctx context.Context, d Delivery) error
+	// ListDeliveries is newest first; an empty status means any.
+	ListDeliveries(ctx context.Context, subscription_id string, status string) ([]Delivery, error)
+	// DueDeliveries are the pending ones whose next attempt is not after now.
+	DueDeliveries(ctx context.Context, now time.Time) ([]Delivery, error)
+}
+
+type memoryStore struct {
+	log_size int
+
+	mtx           sync.Mutex
+	subscriptions map[string]Subscription
+	// deliveries are oldest first per subscription
+	deliveries map[string][]Delivery
+}
+
+// NewMemoryStore keeps everything in the process, so subscriptions do not
+// survive a restart nor are shared between replicas. Each log holds up to
+// log_size deliveries; the oldest finished ones make room for new ones.
+func NewMemoryStore(log_size int) Store {
+	return &memoryStore{
+		log_size:      log_size,
+		subscriptions: make(map[string]Subscription),
+		deliveries:    make(map[string][]Delivery),
+	}
+}
+
+func (s *memoryStore) AddSubscription(_ context.Context, sub Subscription) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sub.Events = append([]string(nil), sub.Events...)
+	s.subscriptions[sub.Id] = sub
+	return nil
+}
+
+func (s *memoryStore) GetSubscription(_ context.Context, id string) (Subscription, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return Subscription{}, errors.NewWebhookNotFoundError()
+	}
+	sub.Events = append([]string(nil), sub.Events...)
+	return sub, nil
+}
+
+func (s *memoryStore) ListSubscriptions(_ context.Context) ([]Subscription, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	res := make([]Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		sub.Events = append([]string(nil), sub.Events...)
+		res = append(res, sub)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res, nil
+}
+
+func (s *memoryStore) DeleteSubscription(_ context.Context, id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.subscriptions[id]; !ok {
+		return errors.NewWebhookNotFoundError()
+	}
+	delete(s.subscriptions, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+func (s *memoryStore) AddDelivery(_ context.Context, d Delivery) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.subscriptions[d.SubscriptionId]; !ok {
+		return errors.NewWebhookNotFoundError()
+	}
+	log := append(s.deliveries[d.SubscriptionId], d)
+	for i := 0; len(log) > s.log_size && i < len(log); {
+		if log[i].Status == StatusPending {
+			i++
+			continue
+		}
+		log = append(log[:i], log[i+1:]...)
+	}
+	s.deliveries[d.SubscriptionId] = log
+	return nil
+}
+
+// UpdateDelivery ignores deliveries no longer in the log: their
+// subscription was deleted while they were being attempted.
+func (s *memoryStore) UpdateDelivery(_ context.Context, d Delivery) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	log := s.deliveries[d.SubscriptionId]
+	for i := range log {
+		if log[i].Id == d.Id {
+			log[i] = d
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListDeliveries(_ context.Context, subscription_id string, status string) ([]Delivery, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.subscriptions[subscription_id]; !ok {
+		return nil, errors.NewWebhookNotFoundError()
+	}
+	log := s.deliveries[subscription_id]
+	res := make([]Delivery, 0, len(log))
+	for i := len(log) - 1; i >= 0; i-- {
+		if status == "" || log[i].Status == status {
+			res = append(res, log[i])
+		}
+	}
+	return res, nil
+}
+
+func (s *memoryStore) DueDeliveries(_ context.Context, now time.Time) ([]Delivery, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var res []Delivery
+	for _, log := range s.deliveries {
+		for _, d := range log {
+			if d.Status == StatusPending && !d.NextAttemptAt.After(now) {
+				res = append(res, d)
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/errors"
+)
+
+// ErrBlockedAddress refuses a connection to an address a partner has no
+// business reaching through this server.
+var ErrBlockedAddress = stderrors.New("webhook: address not allowed")
+
+// The classes of failure kept as the LastError of a delivery. The cause
+// itself only goes to the server log: telling a subscriber why a connection
+// failed would let it map what the server can reach.
+const (
+	ErrorBlocked    = "blocked address"
+	ErrorTimeout    = "timeout"
+	ErrorConnection = "connection failed"
+	ErrorRedirect   = "redirected"
+	ErrorStatus     = "unexpected status"
+)
+
+// blocked is true for the loopback, private, link-local, multicast and
+// unspecified addresses, IPv4 and IPv6 alike.
+func blocked(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// checkTarget refuses a URL whose host is, or resolves to, a blocked
+// address. It is only a first line: the name may resolve elsewhere by the
+// time of a delivery, which the dialer checks again.
+func checkTarget(ctx context.Context, raw string) error {
+	violation := errors.FieldViolation{Field: "url", Description: "must not point to a loopback, private, link-local or unspecified address"}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.NewInvalidArgumentError(violation).Wrap(err)
+	}
+	host := u.Hostname()
+
+	if ip := net.ParseIP(host); ip != nil {
+		if blocked(ip) {
+			return errors.NewInvalidArgumentError(violation)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.NewInvalidArgumentError(errors.FieldViolation{Field: "url", Description: "must have a host that resolves"}).Wrap(err)
+	}
+	for _, addr := range addrs {
+		if blocked(addr.IP) {
+			return errors.NewInvalidArgumentError(violation)
+		}
+	}
+	return nil
+}
+
+// newTransport dials through control, which sees the address actually
+// connected to, after any resolution; no proxy is used as it would be the
+// one address checked.
+func newTransport(allow_private bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allow_private {
+		dialer.Control = control
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+func control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || blocked(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// errorClass is what the delivery log tells of err.
+func errorClass(err error, status int) string {
+	var net_err net.Error
+	switch {
+	case stderrors.Is(err, ErrBlockedAddress):
+		return ErrorBlocked
+	case status >= 300 && status <= 399:
+		return ErrorRedirect
+	case status != 0:
+		return ErrorStatus
+	case stderrors.Is(err, context.DeadlineExceeded),
+		stderrors.As(err, &net_err) && net_err.Timeout():
+		return ErrorTimeout
+	default:
+		return ErrorConnection
+	}
+}
@@ -0,0 +1,91 @@
+// Package webhook tells partner systems about user mutations by POSTing
+// signed events to the URLs they subscribe.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/http_srv/entities"
+)
+
+// The events a subscription can ask for.
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+)
+
+var EventTypes = []string{UserCreated, UserUpdated, UserDeleted}
+
+// MinSecretLength keeps the secrets keying the signatures from being
+// guessable.
+const MinSecretLength = 16
+
+// A delivery is pending until it is either accepted with a 2xx or has
+// failed Config.MaxAttempts times, when it is dead and kept in the log
+// without further attempts.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+type (
+	Subscription struct {
+		Id        string
+		URL       string
+		Events    []string
+		Secret    string
+		CreatedAt time.Time
+	}
+
+	// Event is also the body of every delivery.
+	Event struct {
+		Id        string      `json:"id"`
+		Type      string      `json:"type"`
+		CreatedAt time.Time   `json:"created_at"`
+		Data      interface{} `json:"data"`
+	}
+
+	// UserData is the data of the user events, carrying what the mutation
+	// set; a deletion only has the id.
+	UserData struct {
+		UserId         int               `json:"user_id"`
+		Email          string            `json:"email,omitempty"`
+		Age            int               `json:"age,omitempty"`
+		Profile        *entities.Details `json:"profile,omitempty"`
+		ProfileDeleted bool              `json:"profile_deleted,omitempty"`
+	}
+
+	// Delivery is one event on its way to one subscription, and its entry
+	// in the delivery log.
+	Delivery struct {
+		Id             string
+		SubscriptionId string
+		Event          Event
+		Status         string
+		Attempts       int
+		LastStatusCode int
+		LastError      string
+		NextAttemptAt  time.Time
+		CreatedAt      time.Time
+		DeliveredAt    time.Time
+	}
+)
+
+func (s Subscription) wants(event_type string) bool {
+	for _, e := range s.Events {
+		if e == event_type {
+			return true
+		}
+	}
+	return false
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,382 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/http_srv/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+const secret = "0123456789abcdef"
+
+// receiver answers each delivery with the next of statuses, the last one
+// once they run out, and keeps what it was sent.
+type receiver struct {
+	mtx      sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := rc.statuses[len(rc.statuses)-1]
+	if len(rc.requests) <= len(rc.statuses) {
+		status = rc.statuses[len(rc.requests)-1]
+	}
+	rw.WriteHeader(status)
+}
+
+// config lets the receivers of the tests, all on loopback, be subscribed.
+func config() webhook.Config {
+	return webhook.Config{
+		MaxAttempts:  3,
+		Backoff:      time.Millisecond,
+		MaxBackoff:   2 * time.Millisecond,
+		Timeout:      time.Second,
+		Poll:         time.Millisecond,
+		Workers:      2,
+		AllowPrivate: true,
+	}
+}
+
+func newDispatcher() *webhook.Dispatcher {
+	return webhook.NewDispatcher(webhook.NewMemoryStore(100), config(), log.NewNopLogger())
+}
+
+// settle flushes until no delivery of s is pending anymore.
+func settle(t *testing.T, d *webhook.Dispatcher, s webhook.Subscription) {
+	ctx := context.Background()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		d.Flush(ctx)
+		pending, _ := d.Deliveries(ctx, s.Id, webhook.StatusPending)
+		if len(pending) == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("deliveries still pending")
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":"1","type":"user.created"}`)
+	now := time.Unix(1700000000, 0)
+
+	test_cases := []struct {
+		test_name string
+		secret    string
+		timestamp int64
+		body      []byte
+		signature string
+		err       error
+	}{
+		{
+			test_name: "valid signature",
+			secret:    secret,
+			timestamp: now.Unix(),
+			body:      body,
+			err:       nil,
+		},
+		{
+			test_name: "other secret",
+			secret:    "fedcba9876543210",
+			timestamp: now.Unix(),
+			body:      body,
+			err:       webhook.ErrBadSignature,
+		},
+		{
+			test_name: "tampered body",
+			secret:    secret,
+			timestamp: now.Unix(),
+			body:      []byte(`{"id":"1","type":"user.deleted"}`),
+			err:       webhook.ErrBadSignature,
+		},
+		{
+			test_name: "replayed",
+			secret:    secret,
+			timestamp: now.Add(-10 * time.Minute).Unix(),
+			body:      body,
+			err:       webhook.ErrStale,
+		},
+		{
+			test_name: "no prefix",
+			secret:    secret,
+			timestamp: now.Unix(),
+			body:      body,
+			signature: "0000",
+			err:       webhook.ErrBadSignature,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			header := http.Header{}
+			header.Set(webhook.TimestampHeader, strconv.FormatInt(tc.timestamp, 10))
+			header.Set(webhook.SignatureHeader, webhook.Sign(tc.secret, tc.timestamp, tc.body))
+			if tc.signature != "" {
+				header.Set(webhook.SignatureHeader, tc.signature)
+			}
+
+			// act
+			err := webhook.Verify(secret, header, body, 5*time.Minute, now)
+
+			// assert
+			assert.Equal(tc.err, err)
+		})
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	test_cases := []struct {
+		test_name  string
+		statuses   []int
+		events     []string
+		published  string
+		requests   int
+		status     string
+		attempts   int
+		last_error string
+	}{
+		{
+			test_name: "delivered",
+			statuses:  []int{http.StatusNoContent},
+			events:    []string{webhook.UserCreated},
+			published: webhook.UserCreated,
+			requests:  1,
+			status:    webhook.StatusDelivered,
+			attempts:  1,
+		},
+		{
+			test_name: "delivered after retries",
+			statuses:  []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK},
+			events:    []string{webhook.UserCreated, webhook.UserDeleted},
+			published: webhook.UserDeleted,
+			requests:  3,
+			status:    webhook.StatusDelivered,
+			attempts:  3,
+		},
+		{
+			test_name:  "dead after max attempts",
+			statuses:   []int{http.StatusInternalServerError},
+			events:     []string{webhook.UserUpdated},
+			published:  webhook.UserUpdated,
+			requests:   3,
+			status:     webhook.StatusDead,
+			attempts:   3,
+			last_error: webhook.ErrorStatus,
+		},
+		{
+			test_name:  "redirect is a failure",
+			statuses:   []int{http.StatusFound},
+			events:     []string{webhook.UserUpdated},
+			published:  webhook.UserUpdated,
+			requests:   3,
+			status:     webhook.StatusDead,
+			attempts:   3,
+			last_error: webhook.ErrorRedirect,
+		},
+		{
+			test_name: "event not subscribed",
+			statuses:  []int{http.StatusOK},
+			events:    []string{webhook.UserDeleted},
+			published: webhook.UserCreated,
+			requests:  0,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			ctx := context.Background()
+			assert := assert.New(t)
+			rc := &receiver{statuses: tc.statuses}
+			server := httptest.NewServer(rc)
+			defer server.Close()
+			d := newDispatcher()
+			s, _ := d.Subscribe(ctx, server.URL, tc.events, secret)
+
+			// act
+			d.Publish(ctx, tc.published, webhook.UserData{UserId: 1, Email: "user@email.com"})
+			settle(t, d, s)
+
+			// assert
+			assert.Len(rc.requests, tc.requests)
+			deliveries, err := d.Deliveries(ctx, s.Id, "")
+			assert.Nil(err)
+			if tc.requests == 0 {
+				assert.Empty(deliveries)
+				return
+			}
+			assert.Len(deliveries, 1)
+			assert.Equal(tc.status, deliveries[0].Status)
+			assert.Equal(tc.attempts, deliveries[0].Attempts)
+			assert.Equal(tc.statuses[len(tc.statuses)-1], deliveries[0].LastStatusCode)
+			assert.Equal(tc.last_error, deliveries[0].LastError)
+			assert.Equal(tc.status == webhook.StatusDelivered, !deliveries[0].DeliveredAt.IsZero())
+
+			for i, r := range rc.requests {
+				assert.Equal(tc.published, r.Header.Get(webhook.EventHeader))
+				assert.Equal(deliveries[0].Id, r.Header.Get(webhook.DeliveryHeader))
+				assert.Nil(webhook.Verify(secret, r.Header, rc.bodies[i], time.Minute, time.Now()))
+
+				var event struct {
+					Id   string
+					Type string
+					Data webhook.UserData
+				}
+				assert.Nil(json.Unmarshal(rc.bodies[i], &event))
+				assert.Equal(deliveries[0].Event.Id, event.Id)
+				assert.Equal(tc.published, event.Type)
+				assert.Equal(webhook.UserData{UserId: 1, Email: "user@email.com"}, event.Data)
+			}
+		})
+	}
+}
+
+func TestSubscribeTargets(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		url       string
+		valid     bool
+	}{
+		{test_name: "public address", url: "https://93.184.216.34/hooks", valid: true},
+		{test_name: "loopback", url: "http://127.0.0.1:8080/hooks"},
+		{test_name: "loopback name", url: "http://localhost/hooks"},
+		{test_name: "ipv6 loopback", url: "http://[::1]/hooks"},
+		{test_name: "private", url: "http://10.0.0.7/hooks"},
+		{test_name: "ipv6 private", url: "http://[fd00::7]/hooks"},
+		{test_name: "link-local metadata", url: "http://169.254.169.254/latest/meta-data"},
+		{test_name: "unspecified", url: "http://0.0.0.0:9100/metrics"},
+		{test_name: "ipv4 mapped loopback", url: "http://[::ffff:127.0.0.1]/hooks"},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			c := config()
+			c.AllowPrivate = false
+			d := webhook.NewDispatcher(webhook.NewMemoryStore(100), c, log.NewNopLogger())
+
+			// act
+			_, err := d.Subscribe(context.Background(), tc.url, []string{webhook.UserCreated}, secret)
+
+			// assert
+			if tc.valid {
+				assert.Nil(err)
+				return
+			}
+			assert.ErrorIs(err, errors.NewInvalidArgumentError())
+			subscriptions, _ := d.ListSubscriptions(context.Background())
+			assert.Empty(subscriptions)
+		})
+	}
+}
+
+// TestDialBlocked stands for a name that resolved to a public address when
+// subscribed and to a private one when delivered to.
+func TestDialBlocked(t *testing.T) {
+	// prepare
+	ctx := context.Background()
+	assert := assert.New(t)
+	rc := &receiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	store := webhook.NewMemoryStore(100)
+	s := webhook.Subscription{Id: "1", URL: server.URL, Events: []string{webhook.UserCreated}, Secret: secret}
+	store.AddSubscription(ctx, s)
+	c := config()
+	c.AllowPrivate = false
+	d := webhook.NewDispatcher(store, c, log.NewNopLogger())
+
+	// act
+	d.Publish(ctx, webhook.UserCreated, webhook.UserData{UserId: 1})
+	settle(t, d, s)
+
+	// assert
+	assert.Empty(rc.requests)
+	deliveries, _ := d.Deliveries(ctx, s.Id, webhook.StatusDead)
+	if assert.Len(deliveries, 1) {
+		assert.Equal(webhook.ErrorBlocked, deliveries[0].LastError)
+		assert.Zero(deliveries[0].LastStatusCode)
+	}
+}
+
+func TestDeliveryLog(t *testing.T) {
+	// prepare
+	ctx := context.Background()
+	assert := assert.New(t)
+	rc := &receiver{statuses: []int{http.StatusOK, http.StatusGone}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	d := newDispatcher()
+	s, _ := d.Subscribe(ctx, server.URL, webhook.EventTypes, secret)
+
+	// act
+	d.Publish(ctx, webhook.UserCreated, webhook.UserData{UserId: 1})
+	settle(t, d, s)
+	d.Publish(ctx, webhook.UserDeleted, webhook.UserData{UserId: 1})
+	settle(t, d, s)
+
+	// assert
+	all, _ := d.Deliveries(ctx, s.Id, "")
+	delivered, _ := d.Deliveries(ctx, s.Id, webhook.StatusDelivered)
+	dead, _ := d.Deliveries(ctx, s.Id, webhook.StatusDead)
+	if assert.Len(all, 2) {
+		assert.Equal(webhook.UserDeleted, all[0].Event.Type, "newest first")
+	}
+	if assert.Len(delivered, 1) {
+		assert.Equal(webhook.UserCreated, delivered[0].Event.Type)
+	}
+	if assert.Len(dead, 1) {
+		assert.Equal(webhook.UserDeleted, dead[0].Event.Type)
+	}
+
+	assert.Nil(d.Unsubscribe(ctx, s.Id))
+	_, err := d.Deliveries(ctx, s.Id, "")
+	assert.NotNil(err)
+}
+
+func TestRun(t *testing.T) {
+	// prepare
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert := assert.New(t)
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer server.Close()
+	d := newDispatcher()
+	d.Subscribe(ctx, server.URL, []string{webhook.UserCreated}, secret)
+	go d.Run(ctx)
+
+	// act
+	d.Publish(ctx, webhook.UserCreated, webhook.UserData{UserId: 1})
+
+	// assert
+	select {
+	case r := <-received:
+		assert.Equal(webhook.UserCreated, r.Header.Get(webhook.EventHeader))
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing delivered")
+	}
+}
@@ -0,0 +1,214 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"google.golang.org/grpc"
+)
+
+type Config struct {
+	Timeout    time.Duration
+	DrainDelay time.Duration
+}
+
+type server struct {
+	name  string
+	serve func() error
+	stop  func(ctx context.Context) error
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Lifecycle owns the listeners and connection pools of a service. On
+// shutdown it flips readiness off, waits DrainDelay so load balancers stop
+// routing, drains every server within Timeout and then closes the
+// registered resources in the order they were added.
+type Lifecycle struct {
+	cfg     Config
+	logger  log.Logger
+	ready   int32
+	hooks   []func(ready bool)
+	servers []server
+	closers []closer
+	errs    chan error
+}
+
+func New(cfg Config, l log.Logger) *Lifecycle {
+	return &Lifecycle{
+		cfg:    cfg,
+		logger: log.With(l, "component", "lifecycle"),
+		errs:   make(chan error, 1),
+	}
+}
+
+func (lc *Lifecycle) Ready() bool {
+	return atomic.LoadInt32(&lc.ready) == 1
+}
+
+func (lc *Lifecycle) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&lc.ready, v)
+
+	for _, fn := range lc.hooks {
+		fn(ready)
+	}
+}
+
+// OnReadyChange registers fn to be called every time readiness is set, so
+// health endpoints can report a shutdown before the listeners close.
+func (lc *Lifecycle) OnReadyChange(fn func(ready bool)) {
+	lc.hooks = append(lc.hooks, fn)
+}
+
+func (lc *Lifecycle) AddGrpcServer(name string, s *grpc.Server, lis net.Listener) {
+	lc.servers = append(lc.servers, server{
+		name: name,
+		serve: func() error {
+			return s.Serve(lis)
+		},
+		stop: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				s.GracefulStop()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				s.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// HttpConfig bounds how long and how much a client may send, so a slow or
+// oversized one cannot hold a connection indefinitely.
+type HttpConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+func NewHttpServer(addr string, handler http.Handler, cfg HttpConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+func (lc *Lifecycle) AddHttpServer(name string, s *http.Server) {
+	lc.servers = append(lc.servers, server{
+		name: name,
+		serve: func() error {
+			if err := s.ListenAndServe(); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		},
+		stop: func(ctx context.Context) error {
+			if err := s.Shutdown(ctx); err != nil {
+				s.Close()
+				return err
+			}
+			return nil
+		},
+	})
+}
+
+func (lc *Lifecycle) AddCloser(name string, fn func(ctx context.Context) error) {
+	lc.closers = append(lc.closers, closer{name: name, close: fn})
+}
+
+// Run starts every server, marks the service ready and blocks until a
+// termination signal arrives or a server fails. It then shuts down and
+// returns the reason.
+func (lc *Lifecycle) Run() error {
+	for _, s := range lc.servers {
+		s := s
+		go func() {
+			level.Info(lc.logger).Log("server", s.name, "mesg", "serving")
+			if err := s.serve(); err != nil {
+				lc.fail(fmt.Errorf("%v: %w", s.name, err))
+			}
+		}()
+	}
+
+	lc.SetReady(true)
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(c)
+
+	var cause error
+	select {
+	case sig := <-c:
+		cause = fmt.Errorf("%s", sig)
+	case cause = <-lc.errs:
+	}
+
+	lc.Shutdown()
+	return cause
+}
+
+func (lc *Lifecycle) Shutdown() {
+	lc.SetReady(false)
+
+	if lc.cfg.DrainDelay > 0 {
+		level.Info(lc.logger).Log("mesg", "draining", "delay", lc.cfg.DrainDelay)
+		time.Sleep(lc.cfg.DrainDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lc.cfg.Timeout)
+	defer cancel()
+
+	for _, s := range lc.servers {
+		if err := s.stop(ctx); err != nil {
+			level.Error(lc.logger).Log("server", s.name, "shutdown", err)
+		} else {
+			level.Info(lc.logger).Log("server", s.name, "mesg", "stopped")
+		}
+	}
+
+	for _, c := range lc.closers {
+		close_ctx, close_cancel := context.WithTimeout(context.Background(), lc.cfg.Timeout)
+		if err := c.close(close_ctx); err != nil {
+			level.Error(lc.logger).Log("resource", c.name, "close", err)
+		} else {
+			level.Info(lc.logger).Log("resource", c.name, "mesg", "closed")
+		}
+		close_cancel()
+	}
+}
+
+func (lc *Lifecycle) fail(err error) {
+	select {
+	case lc.errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,127 @@
+package lifecycle_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestShutdownOrder(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	lc := lifecycle.New(lifecycle.Config{Timeout: time.Second}, log.NewNopLogger())
+	var closed []string
+	var ready_on_close bool
+
+	lc.AddCloser("mysql", func(context.Context) error {
+		ready_on_close = lc.Ready()
+		closed = append(closed, "mysql")
+		return nil
+	})
+	lc.AddCloser("grpc_conn", func(context.Context) error {
+		closed = append(closed, "grpc_conn")
+		return nil
+	})
+	lc.SetReady(true)
+
+	// act
+	lc.Shutdown()
+
+	// assert
+	assert.False(lc.Ready())
+	assert.False(ready_on_close)
+	assert.Equal([]string{"mysql", "grpc_conn"}, closed)
+}
+
+func TestHttpServerDrainsInFlightRequests(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := lis.Addr().String()
+	lis.Close()
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		rw.Write([]byte("done"))
+	})
+
+	lc := lifecycle.New(lifecycle.Config{Timeout: time.Second}, log.NewNopLogger())
+	lc.AddHttpServer("http", &http.Server{Addr: addr, Handler: handler})
+	go lc.Run()
+
+	var body []byte
+	var req_err error
+	finished := make(chan struct{})
+	go func() {
+		for i := 0; i < 50; i++ {
+			res, err := http.Get("http://" + addr)
+			if err == nil {
+				body, req_err = ioutil.ReadAll(res.Body)
+				res.Body.Close()
+				break
+			}
+			req_err = err
+			time.Sleep(10 * time.Millisecond)
+		}
+		close(finished)
+	}()
+
+	// act
+	<-started
+	lc.Shutdown()
+	<-finished
+
+	// assert
+	assert.Nil(req_err)
+	assert.Equal("done", string(body))
+}
+
+func TestRunStopsOnServerError(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	lis.Close()
+
+	lc := lifecycle.New(lifecycle.Config{Timeout: time.Second}, log.NewNopLogger())
+	lc.AddGrpcServer("grpc", grpc.NewServer(), lis)
+
+	// act
+	err := lc.Run()
+
+	// assert
+	assert.NotNil(err)
+	assert.False(lc.Ready())
+}
+
+func TestNewHttpServer(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	cfg := lifecycle.HttpConfig{
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+		MaxHeaderBytes:    1024,
+	}
+
+	// act
+	s := lifecycle.NewHttpServer("127.0.0.1:0", http.NotFoundHandler(), cfg)
+
+	// assert
+	assert.Equal("127.0.0.1:0", s.Addr)
+	assert.Equal(cfg.ReadHeaderTimeout, s.ReadHeaderTimeout)
+	assert.Equal(cfg.ReadTimeout, s.ReadTimeout)
+	assert.Equal(cfg.WriteTimeout, s.WriteTimeout)
+	assert.Equal(cfg.IdleTimeout, s.IdleTimeout)
+	assert.Equal(cfg.MaxHeaderBytes, s.MaxHeaderBytes)
+}
@@ -0,0 +1,55 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+type Startup struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Timeout    time.Duration
+}
+
+// WaitFor calls check until it succeeds, doubling the pause between
+// attempts up to MaxBackoff. It gives up after Attempts tries so a service
+// whose database never comes up exits instead of hanging.
+func WaitFor(ctx context.Context, name string, s Startup, check func(ctx context.Context) error, l log.Logger) error {
+	logger := log.With(l, "component", "startup", "dependency", name)
+	backoff := s.Backoff
+	var err error
+
+	for attempt := 1; attempt <= s.Attempts; attempt++ {
+		check_ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+		err = check(check_ctx)
+		cancel()
+
+		if err == nil {
+			level.Info(logger).Log("mesg", "dependency reachable", "attempt", attempt)
+			return nil
+		}
+
+		level.Warn(logger).Log("attempt", attempt, "err", err, "retry_in", backoff)
+
+		if attempt == s.Attempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if backoff *= 2; backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+	}
+
+	return fmt.Errorf("%v unreachable after %v attempts: %w", name, s.Attempts, err)
+}
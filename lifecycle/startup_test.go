@@ -0,0 +1,66 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/lifecycle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitFor(t *testing.T) {
+	startup := lifecycle.Startup{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Timeout: time.Second}
+	refused := errors.New("connection refused")
+
+	test_cases := []struct {
+		test_name string
+		failures  int
+		calls     int
+		err       bool
+	}{
+		{
+			test_name: "reachable at once",
+			failures:  0,
+			calls:     1,
+		},
+		{
+			test_name: "reachable after retries",
+			failures:  2,
+			calls:     3,
+		},
+		{
+			test_name: "unreachable error",
+			failures:  5,
+			calls:     3,
+			err:       true,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			calls := 0
+			check := func(context.Context) error {
+				calls++
+				if calls <= tc.failures {
+					return refused
+				}
+				return nil
+			}
+
+			// act
+			err := lifecycle.WaitFor(context.Background(), "mysql", startup, check, log.NewNopLogger())
+
+			// assert
+			assert.Equal(tc.calls, calls)
+			assert.Equal(tc.err, err != nil)
+			if tc.err {
+				assert.True(errors.Is(err, refused))
+			}
+		})
+	}
+}
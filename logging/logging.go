@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
+
+type Config struct {
+	Format string
+	Level  string
+}
+
+// New builds the process logger shared by every service: the chosen output
+// format, redacted, filtered at the configured level and tagged with the
+// service name, a UTC timestamp and the caller.
+func New(w io.Writer, service string, c Config) (log.Logger, error) {
+	var logger log.Logger
+
+	switch c.Format {
+	case FormatLogfmt, "":
+		logger = log.NewLogfmtLogger(w)
+	case FormatJSON:
+		logger = log.NewJSONLogger(w)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", c.Format)
+	}
+
+	var allowed level.Option
+	switch c.Level {
+	case "debug":
+		allowed = level.AllowDebug()
+	case "info", "":
+		allowed = level.AllowInfo()
+	case "warn":
+		allowed = level.AllowWarn()
+	case "error":
+		allowed = level.AllowError()
+	default:
+		return nil, fmt.Errorf("unknown log level %q", c.Level)
+	}
+
+	logger = log.NewSyncLogger(logger)
+	logger = NewRedactingLogger(logger)
+	logger = level.NewFilter(logger, allowed)
+	logger = log.With(
+		logger,
+		"service",
+		service,
+		"time",
+		log.DefaultTimestampUTC,
+		"caller",
+		log.DefaultCaller,
+	)
+
+	return logger, nil
+}
@@ -0,0 +1,58 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-kit/log/level"
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "HTTP_SRV", logging.Config{Format: logging.FormatJSON, Level: "warn"})
+
+	// act
+	level.Info(logger).Log("msg", "dropped")
+	level.Warn(logger).Log("msg", "kept")
+
+	// assert
+	assert.Nil(err)
+	var line map[string]interface{}
+	assert.Nil(json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal("kept", line["msg"])
+	assert.Equal("HTTP_SRV", line["service"])
+	assert.Equal("warn", line["level"])
+	assert.Contains(line["caller"], "logging_test.go")
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		config    logging.Config
+		err       string
+	}{
+		{
+			test_name: "unknown format",
+			config:    logging.Config{Format: "xml"},
+			err:       `unknown log format "xml"`,
+		},
+		{
+			test_name: "unknown level",
+			config:    logging.Config{Level: "verbose"},
+			err:       `unknown log level "verbose"`,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			_, err := logging.New(&bytes.Buffer{}, "HTTP_SRV", tc.config)
+
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched against the lower-cased log key, so "pwd_hash"
+// and "MobileNumber" are caught as well.
+var sensitiveKeys = []string{
+	"password",
+	"pwd",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"claims",
+	"email",
+	"mobile",
+	"phone",
+}
+
+var (
+	assignmentPattern = regexp.MustCompile(`(?i)\b(password|pwd|passwd|secret|token)(\s*[=:]\s*)\S+`)
+	bearerPattern     = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`)
+	jwtPattern        = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern      = regexp.MustCompile(`\+?\b\d(?:[ -]?\d){6,14}\b`)
+	datePattern       = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+type redactingLogger struct {
+	next log.Logger
+}
+
+// NewRedactingLogger masks the values of sensitive keys, and credentials,
+// emails and phone numbers found inside any other text value, before they
+// reach next.
+func NewRedactingLogger(next log.Logger) log.Logger {
+	return &redactingLogger{next: next}
+}
+
+func (l *redactingLogger) Log(keyvals ...interface{}) error {
+	clean := make([]interface{}, len(keyvals))
+	copy(clean, keyvals)
+
+	for i := 1; i < len(clean); i += 2 {
+		clean[i] = redactValue(clean[i-1], clean[i])
+	}
+
+	return l.next.Log(clean...)
+}
+
+func redactValue(key interface{}, value interface{}) interface{} {
+	if sensitiveKey(fmt.Sprint(key)) {
+		return redacted
+	}
+
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64, time.Time, time.Duration:
+		return v
+	case string:
+		return Redact(v)
+	case error:
+		if s := v.Error(); Redact(s) != s {
+			return Redact(s)
+		}
+		return v
+	default:
+		if s := fmt.Sprintf("%+v", v); Redact(s) != s {
+			return Redact(s)
+		}
+		return v
+	}
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact masks credentials, emails and phone numbers found in s.
+func Redact(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = assignmentPattern.ReplaceAllString(s, "${1}${2}"+redacted)
+	s = emailPattern.ReplaceAllString(s, redacted)
+	s = phonePattern.ReplaceAllStringFunc(s, func(m string) string {
+		if datePattern.MatchString(m) {
+			return m
+		}
+		return redacted
+	})
+	return s
+}
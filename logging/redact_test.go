@@ -0,0 +1,88 @@
+package logging_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+const jwt = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJpZCI6MX0.c2lnbmF0dXJl"
+
+type profile struct {
+	Email        string
+	MobileNumber string
+}
+
+func TestRedactingLogger(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		keyvals   []interface{}
+		secrets   []string
+	}{
+		{
+			test_name: "sensitive keys",
+			keyvals:   []interface{}{"password", "qwerty", "pwd_hash", "$2a$10$hash", "Authorization", "Bearer abc", "mobile_number", "11223344"},
+			secrets:   []string{"qwerty", "$2a$10$hash", "abc", "11223344"},
+		},
+		{
+			test_name: "email in grpc error",
+			keyvals:   []interface{}{"err_user", errors.New("rpc error: code = AlreadyExists desc = Duplicate entry 'email@domain.com'")},
+			secrets:   []string{"email@domain.com"},
+		},
+		{
+			test_name: "token in message",
+			keyvals:   []interface{}{"msg", "header Authorization: Bearer " + jwt, "raw", jwt},
+			secrets:   []string{jwt, "c2lnbmF0dXJl"},
+		},
+		{
+			test_name: "credentials in text",
+			keyvals:   []interface{}{"dsn", "user:pwd=s3cr3t token: abc123"},
+			secrets:   []string{"s3cr3t", "abc123"},
+		},
+		{
+			test_name: "phone numbers in text",
+			keyvals:   []interface{}{"msg", "call +52 55 1234 5678 or 11223344"},
+			secrets:   []string{"1234 5678", "11223344"},
+		},
+		{
+			test_name: "structs",
+			keyvals:   []interface{}{"user", profile{Email: "email@domain.com", MobileNumber: "5512345678"}},
+			secrets:   []string{"email@domain.com", "5512345678"},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			var buf bytes.Buffer
+			logger := logging.NewRedactingLogger(log.NewLogfmtLogger(&buf))
+
+			// act
+			logger.Log(tc.keyvals...)
+
+			// assert
+			assert.Contains(buf.String(), "[REDACTED]")
+			for _, s := range tc.secrets {
+				assert.NotContains(buf.String(), s)
+			}
+		})
+	}
+}
+
+func TestRedactingLoggerKeepsPlainValues(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	logger := logging.NewRedactingLogger(log.NewLogfmtLogger(&buf))
+
+	// act
+	logger.Log("method", "get_user", "user_id", 11223344, "date", "2026-10-19", "request_id", "0b5e7c1a9f3d4e21")
+
+	// assert
+	assert.Equal("method=get_user user_id=11223344 date=2026-10-19 request_id=0b5e7c1a9f3d4e21\n", buf.String())
+}
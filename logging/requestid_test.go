@@ -0,0 +1,105 @@
+package logging_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestHttpMiddleware(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		header    string
+		keep      bool
+	}{
+		{
+			test_name: "caller id kept",
+			header:    "0b5e7c1a-checkout",
+			keep:      true,
+		},
+		{
+			test_name: "missing id generated",
+		},
+		{
+			test_name: "unsafe id replaced",
+			header:    "abc\nlevel=error",
+		},
+		{
+			test_name: "oversized id replaced",
+			header:    strings.Repeat("a", 200),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			var seen string
+			handler := logging.HttpMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			req.Header.Set(logging.RequestIDHeader, tc.header)
+			rec := httptest.NewRecorder()
+
+			// act
+			handler.ServeHTTP(rec, req)
+
+			// assert
+			assert.NotEmpty(seen)
+			assert.Equal(seen, rec.Header().Get(logging.RequestIDHeader))
+			if tc.keep {
+				assert.Equal(tc.header, seen)
+			} else {
+				assert.NotEqual(tc.header, seen)
+			}
+		})
+	}
+}
+
+type userServer struct {
+	userpb.UnimplementedUserServiceServer
+	seen chan string
+}
+
+func (s userServer) GetUser(ctx context.Context, _ *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	s.seen <- logging.RequestID(ctx)
+	return &userpb.GetUserResponse{}, nil
+}
+
+func TestGrpcPropagation(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	srv := userServer{seen: make(chan string, 2)}
+	server := grpc.NewServer(grpc.UnaryInterceptor(logging.UnaryServerInterceptor()))
+	userpb.RegisterUserServiceServer(server, srv)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, _ := grpc.DialContext(context.Background(), "", grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	defer conn.Close()
+	client := userpb.NewUserServiceClient(conn)
+
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	client.GetUser(logging.NewContext(context.Background(), "req-42"), &userpb.GetUserRequest{})
+	client.GetUser(context.Background(), &userpb.GetUserRequest{})
+
+	// assert
+	assert.Equal("req-42", <-srv.seen)
+	assert.NotEmpty(<-srv.seen)
+}
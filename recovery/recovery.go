@@ -0,0 +1,38 @@
+package recovery
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/logging"
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor turns a panicking handler into an Internal status
+// and logs the panic with its stack, so one bad request cannot take the
+// server down. It covers only the interceptors after it, so it goes right
+// after the one carrying the request ID.
+func UnaryServerInterceptor(logger log.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				Log(logger, ctx, info.FullMethod, p)
+				res, err = nil, errors.NewInternalError()
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// Log reports a recovered panic value along with the current stack.
+func Log(logger log.Logger, ctx context.Context, method string, p interface{}) {
+	level.Error(logger).Log(
+		"request_id", logging.RequestID(ctx),
+		"method", method,
+		"panic", p,
+		"stack", string(debug.Stack()),
+	)
+}
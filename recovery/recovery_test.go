@@ -0,0 +1,75 @@
+package recovery_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/recovery"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		handler   grpc.UnaryHandler
+		res       interface{}
+		code      codes.Code
+		logged    bool
+	}{
+		{
+			test_name: "no panic",
+			handler: func(context.Context, interface{}) (interface{}, error) {
+				return "ok", nil
+			},
+			res:  "ok",
+			code: codes.OK,
+		},
+		{
+			test_name: "handler error",
+			handler: func(context.Context, interface{}) (interface{}, error) {
+				return nil, status.Error(codes.NotFound, "User not found")
+			},
+			code: codes.NotFound,
+		},
+		{
+			test_name: "panic",
+			handler: func(context.Context, interface{}) (interface{}, error) {
+				var m map[string]int
+				m["boom"]++
+				return "ok", nil
+			},
+			code:   codes.Internal,
+			logged: true,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			var buf bytes.Buffer
+			interceptor := recovery.UnaryServerInterceptor(log.NewLogfmtLogger(&buf))
+			info := &grpc.UnaryServerInfo{FullMethod: "/UserService/GetUser"}
+
+			// act
+			res, err := interceptor(context.Background(), nil, info, tc.handler)
+
+			// assert
+			assert.Equal(tc.res, res)
+			assert.Equal(tc.code, status.Code(err))
+			if tc.logged {
+				assert.Contains(buf.String(), "panic=")
+				assert.Contains(buf.String(), "method=/UserService/GetUser")
+				assert.Contains(buf.String(), "recovery_test.go")
+				assert.NotContains(status.Convert(err).Message(), "nil map")
+			} else {
+				assert.Empty(buf.String())
+			}
+		})
+	}
+}
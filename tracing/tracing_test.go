@@ -0,0 +1,131 @@
+package tracing_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mauricioww/user_microsrv/tracing"
+	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type userServer struct {
+	userpb.UnimplementedUserServiceServer
+}
+
+func (userServer) GetUser(context.Context, *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	return &userpb.GetUserResponse{}, nil
+}
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	if _, err := tracing.Init(context.Background(), "test", tracing.Config{Exporter: tracing.ExporterNone}); err != nil {
+		t.Fatal(err)
+	}
+	return recorder
+}
+
+func dial(t *testing.T) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(tracing.UnaryServerInterceptor()))
+	userpb.RegisterUserServiceServer(server, userServer{})
+	grpc_health_v1.RegisterHealthServer(server, grpc_health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGrpcPropagation(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+	recorder := record(t)
+	conn := dial(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "PUT /users/{id}")
+
+	// act
+	_, err := userpb.NewUserServiceClient(conn).GetUser(ctx, &userpb.GetUserRequest{Id: 1})
+	parent.End()
+
+	// assert
+	assert.Nil(err)
+	var client, server sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		switch s.SpanKind() {
+		case trace.SpanKindClient:
+			client = s
+		case trace.SpanKindServer:
+			server = s
+		}
+	}
+
+	if assert.NotNil(client) && assert.NotNil(server) {
+		assert.Equal(parent.SpanContext().SpanID(), client.Parent().SpanID())
+		assert.Equal(client.SpanContext().SpanID(), server.Parent().SpanID())
+		assert.Equal(parent.SpanContext().TraceID(), server.SpanContext().TraceID())
+		assert.True(server.Parent().IsRemote())
+	}
+}
+
+func TestHealthNotTraced(t *testing.T) {
+	// prepare
+	recorder := record(t)
+	conn := dial(t)
+
+	// act
+	_, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+
+	// assert
+	assert.Nil(t, err)
+	assert.Empty(t, recorder.Ended())
+}
+
+func TestMiddlewareSpanName(t *testing.T) {
+	// prepare
+	recorder := record(t)
+	router := mux.NewRouter()
+	router.Use(tracing.Middleware)
+	router.Methods("GET").Path("/users/{id}").HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	// act
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	// assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET /users/{id}", spans[0].Name())
+	}
+}
+
+func TestUnknownExporter(t *testing.T) {
+	_, err := tracing.Init(context.Background(), "test", tracing.Config{Exporter: "zipkin"})
+
+	assert.EqualError(t, err, `unknown trace exporter "zipkin"`)
+}
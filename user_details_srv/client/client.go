@@ -22,18 +22,18 @@ func main() {
 
 	client := detailspb.NewUserDetailsServiceClient(grpc_client)
 
-	set(client, 0)
+	set(client, 1)
 }
 
 func set(c detailspb.UserDetailsServiceClient, id int) {
 	req := &detailspb.SetUserDetailsRequest{
-		// UserId:       uint32(id),
-		// Country:      "Mexico",
-		// City:         "CDMX",
-		// MobileNumber: "0000000001",
-		// Married:      true,
-		// Height:       1.75,
-		// Weigth:       76.0,
+		UserId:       uint32(id),
+		Country:      "MX",
+		City:         "CDMX",
+		MobileNumber: "0000000001",
+		Married:      true,
+		Height:       1.75,
+		Weight:       76.0,
 	}
 
 	res, err := c.SetUserDetails(context.TODO(), req)
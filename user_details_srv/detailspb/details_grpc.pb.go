@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion7
 type UserDetailsServiceClient interface {
 	SetUserDetails(ctx context.Context, in *SetUserDetailsRequest, opts ...grpc.CallOption) (*SetUserDetailsResponse, error)
 	GetUserDetails(ctx context.Context, in *GetUserDetailsRequest, opts ...grpc.CallOption) (*GetUserDetailsResponse, error)
+	GetUsersDetails(ctx context.Context, in *GetUsersDetailsRequest, opts ...grpc.CallOption) (*GetUsersDetailsResponse, error)
 	DeleteUserDetails(ctx context.Context, in *DeleteUserDetailsRequest, opts ...grpc.CallOption) (*DeleteUserDetailsResponse, error)
 }
 
@@ -49,6 +50,15 @@ func (c *userDetailsServiceClient) GetUserDetails(ctx context.Context, in *GetUs
 	return out, nil
 }
 
+func (c *userDetailsServiceClient) GetUsersDetails(ctx context.Context, in *GetUsersDetailsRequest, opts ...grpc.CallOption) (*GetUsersDetailsResponse, error) {
+	out := new(GetUsersDetailsResponse)
+	err := c.cc.Invoke(ctx, "/UserDetailsService/GetUsersDetails", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *userDetailsServiceClient) DeleteUserDetails(ctx context.Context, in *DeleteUserDetailsRequest, opts ...grpc.CallOption) (*DeleteUserDetailsResponse, error) {
 	out := new(DeleteUserDetailsResponse)
 	err := c.cc.Invoke(ctx, "/UserDetailsService/DeleteUserDetails", in, out, opts...)
@@ -64,6 +74,7 @@ func (c *userDetailsServiceClient) DeleteUserDetails(ctx context.Context, in *De
 type UserDetailsServiceServer interface {
 	SetUserDetails(context.Context, *SetUserDetailsRequest) (*SetUserDetailsResponse, error)
 	GetUserDetails(context.Context, *GetUserDetailsRequest) (*GetUserDetailsResponse, error)
+	GetUsersDetails(context.Context, *GetUsersDetailsRequest) (*GetUsersDetailsResponse, error)
 	DeleteUserDetails(context.Context, *DeleteUserDetailsRequest) (*DeleteUserDetailsResponse, error)
 	mustEmbedUnimplementedUserDetailsServiceServer()
 }
@@ -78,6 +89,9 @@ func (UnimplementedUserDetailsServiceServer) SetUserDetails(context.Context, *Se
 func (UnimplementedUserDetailsServiceServer) GetUserDetails(context.Context, *GetUserDetailsRequest) (*GetUserDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUserDetails not implemented")
 }
+func (UnimplementedUserDetailsServiceServer) GetUsersDetails(context.Context, *GetUsersDetailsRequest) (*GetUsersDetailsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsersDetails not implemented")
+}
 func (UnimplementedUserDetailsServiceServer) DeleteUserDetails(context.Context, *DeleteUserDetailsRequest) (*DeleteUserDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserDetails not implemented")
 }
@@ -130,6 +144,24 @@ func _UserDetailsService_GetUserDetails_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserDetailsService_GetUsersDetails_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsersDetailsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDetailsServiceServer).GetUsersDetails(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/UserDetailsService/GetUsersDetails",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDetailsServiceServer).GetUsersDetails(ctx, req.(*GetUsersDetailsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _UserDetailsService_DeleteUserDetails_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteUserDetailsRequest)
 	if err := dec(in); err != nil {
@@ -163,6 +195,10 @@ var UserDetailsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetUserDetails",
 			Handler:    _UserDetailsService_GetUserDetails_Handler,
 		},
+		{
+			MethodName: "GetUsersDetails",
+			Handler:    _UserDetailsService_GetUsersDetails_Handler,
+		},
 		{
 			MethodName: "DeleteUserDetails",
 			Handler:    _UserDetailsService_DeleteUserDetails_Handler,
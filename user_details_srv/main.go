@@ -5,19 +5,31 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
 	"github.com/caarlos0/env/v6"
-	"github.com/go-kit/log"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/log/level"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/mauricioww/user_microsrv/admin"
+	"github.com/mauricioww/user_microsrv/health"
+	"github.com/mauricioww/user_microsrv/lifecycle"
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/mauricioww/user_microsrv/recovery"
+	"github.com/mauricioww/user_microsrv/tracing"
 	"github.com/mauricioww/user_microsrv/user_details_srv/detailspb"
 	"github.com/mauricioww/user_microsrv/user_details_srv/repository"
 	"github.com/mauricioww/user_microsrv/user_details_srv/service"
 	"github.com/mauricioww/user_microsrv/user_details_srv/transport"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 	"google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
@@ -27,24 +39,26 @@ func main() {
 		fmt.Printf("%+v\n", err)
 	}
 
-	var logger log.Logger
-	{
-		logger = log.NewLogfmtLogger(os.Stderr)
-		logger = log.NewSyncLogger(logger)
-		logger = log.With(
-			logger,
-			"service",
-			"GRPC_USER_DETAILS",
-			"time",
-			log.DefaultTimestampUTC,
-			"caller",
-			log.DefaultCaller,
-		)
+	logger, err := logging.New(os.Stderr, "GRPC_USER_DETAILS", logging.Config{Format: cts.LogFormat, Level: cts.LogLevel})
+	if err != nil {
+		fmt.Printf("%+v\n", err)
+		os.Exit(-1)
 	}
 	level.Info(logger).Log("mesg", "service started")
 
 	defer level.Info(logger).Log("msg", "service ended")
 
+	shutdown_tracing, err := tracing.Init(context.Background(), "user_details_srv", tracingConfig(cts))
+	if err != nil {
+		level.Error(logger).Log("exit", err)
+		os.Exit(-1)
+	}
+
+	lc := lifecycle.New(lifecycle.Config{Timeout: cts.ShutdownTimeout, DrainDelay: cts.ShutdownDelay}, logger)
+
+	monitor := health.NewMonitor(cts.HealthInterval, cts.HealthTimeout, lc.Ready, logger)
+	lc.OnReadyChange(func(bool) { monitor.Refresh() })
+
 	var db *mongo.Database
 
 	{
@@ -53,7 +67,52 @@ func main() {
 			Username: cts.DbUser,
 			Password: cts.DbPwd,
 		}
-		client_opts := options.Client().ApplyURI(mongo_uri).SetAuth(credentials)
+		client_opts := options.Client().ApplyURI(mongo_uri).SetAuth(credentials).
+			SetMaxPoolSize(cts.DbMaxPoolSize).
+			SetMinPoolSize(cts.DbMinPoolSize).
+			SetMaxConnIdleTime(cts.DbMaxConnIdleTime).
+			SetConnectTimeout(cts.DbConnectTimeout).
+			SetServerSelectionTimeout(cts.DbServerSelectionTimeout).
+			SetSocketTimeout(cts.DbSocketTimeout).
+			SetMonitor(repository.ChainCommandMonitors(
+				otelmongo.NewMonitor(),
+				repository.NewCommandMonitor(
+					kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+						Namespace: "user_details_srv",
+						Subsystem: "mongodb",
+						Name:      "command_duration_seconds",
+						Help:      "MongoDB command latency in seconds.",
+					}, []string{"operation"}),
+					kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+						Namespace: "user_details_srv",
+						Subsystem: "mongodb",
+						Name:      "errors_total",
+						Help:      "Number of failed MongoDB commands by error class.",
+					}, []string{"operation", "class"}),
+					cts.DbSlowThreshold,
+					logger,
+				),
+			)).
+			SetPoolMonitor(repository.NewPoolMonitor(
+				kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+					Namespace: "user_details_srv",
+					Subsystem: "mongodb_pool",
+					Name:      "open_connections",
+					Help:      "Connections currently open to MongoDB.",
+				}, []string{}),
+				kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+					Namespace: "user_details_srv",
+					Subsystem: "mongodb_pool",
+					Name:      "in_use_connections",
+					Help:      "Connections currently checked out of the pool.",
+				}, []string{}),
+				kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+					Namespace: "user_details_srv",
+					Subsystem: "mongodb_pool",
+					Name:      "checkout_failures_total",
+					Help:      "Connection checkouts that failed.",
+				}, []string{}),
+			))
 		client, err := mongo.Connect(context.Background(), client_opts)
 
 		if err != nil {
@@ -61,22 +120,73 @@ func main() {
 			os.Exit(-1)
 		}
 
+		ping := func(ctx context.Context) error {
+			return client.Ping(ctx, readpref.Primary())
+		}
+
+		if err := lifecycle.WaitFor(context.Background(), "mongodb", startup(cts), ping, logger); err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
+		}
+
 		db = client.Database(cts.DbName)
+		monitor.AddCheck("mongodb", ping)
+
+		health_ctx, stop_health := context.WithCancel(context.Background())
+		go monitor.Run(health_ctx)
+		lc.AddCloser("health", func(context.Context) error {
+			stop_health()
+			return nil
+		})
+		lc.AddCloser("mongodb", client.Disconnect)
 	}
 
-	var grpc_user_details_srv service.GrpcUserDetailsService
+	var users repository.UserRepository
 	{
-		mongo_repository := repository.NewUserDetailsRepository(db, logger)
-		grpc_user_details_srv = service.NewGrpcUserDetailsService(mongo_repository, logger)
+		if cts.VerifyUser {
+			user_addr := fmt.Sprintf("%v:%v", cts.UserHost, cts.UserPort)
+			user_grpc, err := grpc.Dial(user_addr, grpc.WithInsecure(), grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor(), tracing.UnaryClientInterceptor(), grpc_prometheus.UnaryClientInterceptor))
+			if err != nil {
+				level.Error(logger).Log("gRPC", err)
+				os.Exit(-1)
+			}
+			lc.AddCloser("user_grpc", func(context.Context) error {
+				return user_grpc.Close()
+			})
+			users = repository.NewUserRepository(user_grpc, cts.UserCacheTTL, logger)
+		} else {
+			level.Warn(logger).Log("mesg", "user existence check disabled")
+			users = repository.NewUncheckedUserRepository()
+		}
 	}
 
-	errs := make(chan error)
+	var grpc_user_details_srv service.GrpcUserDetailsService
+	{
+		mongo_repository := repository.NewUserDetailsRepository(db, logger)
+		grpc_user_details_srv = service.NewGrpcUserDetailsService(mongo_repository, users, logger)
 
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		errs <- fmt.Errorf("%s", <-c)
-	}()
+		fields := []string{"method"}
+		grpc_user_details_srv = service.InstrumentingMiddleware(service.Metrics{
+			Requests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "user_details_srv",
+				Subsystem: "service",
+				Name:      "requests_total",
+				Help:      "Number of requests received.",
+			}, fields),
+			Errors: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "user_details_srv",
+				Subsystem: "service",
+				Name:      "errors_total",
+				Help:      "Number of failed requests by error code.",
+			}, []string{"method", "code"}),
+			Latency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "user_details_srv",
+				Subsystem: "service",
+				Name:      "request_duration_seconds",
+				Help:      "Request latency in seconds.",
+			}, fields),
+		})(grpc_user_details_srv)
+	}
 
 	grpc_endpoints := transport.MakeGrpcUserDetailsServiceEndpoints(grpc_user_details_srv)
 	grpc_server := transport.NewGrpcUserDetailsServer(grpc_endpoints)
@@ -87,22 +197,121 @@ func main() {
 		os.Exit(-1)
 	}
 
-	go func() {
-		server := grpc.NewServer()
-		detailspb.RegisterUserDetailsServiceServer(server, grpc_server)
-		if err := server.Serve(grpc_listener); err != nil {
-			logger.Log("Error serving", err)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(), recovery.UnaryServerInterceptor(logger), tracing.UnaryServerInterceptor(), grpc_prometheus.UnaryServerInterceptor))
+	detailspb.RegisterUserDetailsServiceServer(server, grpc_server)
+	health_srv := grpc_health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health_srv)
+	// reflection lists the service to anyone able to reach it, which is
+	// only wanted while debugging
+	if cts.Environment != "production" {
+		reflection.Register(server)
+	}
+	monitor.Publish(health_srv, "UserDetailsService")
+	grpc_prometheus.EnableHandlingTimeHistogram()
+	grpc_prometheus.Register(server)
+	lc.AddGrpcServer("grpc", server, grpc_listener)
+	if cts.AdminAddr != "" {
+		lc.AddHttpServer("admin", lifecycle.NewHttpServer(cts.AdminAddr, admin.Handler(admin.NewMux(), cts.AdminToken), httpConfig(cts)))
+	}
+
+	if cts.MetricsAddr != "" {
+		lc.AddHttpServer("metrics", lifecycle.NewHttpServer(cts.MetricsAddr, admin.NewMetricsMux(), httpConfig(cts)))
+	}
+
+	if cts.AdminGrpcAddr != "" {
+		admin_listener, err := net.Listen("tcp", cts.AdminGrpcAddr)
+		if err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
 		}
-		level.Info(logger).Log("info", "grpc server started")
-	}()
+		lc.AddGrpcServer("admin_grpc", admin.NewGrpcServer(cts.AdminToken), admin_listener)
+	}
+	lc.AddCloser("tracing", shutdown_tracing)
 
-	level.Error(logger).Log("exit: ", <-errs)
+	level.Error(logger).Log("exit: ", lc.Run())
 }
 
 type constants struct {
+	Environment string `env:"ENVIRONMENT" envDefault:"production"`
+
+	LogFormat string `env:"LOG_FORMAT" envDefault:"logfmt"`
+	LogLevel  string `env:"LOG_LEVEL" envDefault:"info"`
+
 	DbUser string `env:"DB_USER,required"`
 	DbPwd  string `env:"DB_PASSWORD,required"`
 	DbHost string `env:"DB_HOST,required"`
 	DbPort int    `env:"DB_PORT" envDefault:"27017"`
 	DbName string `env:"DB_NAME" envDefault:"grpc_details"`
+
+	DbMaxPoolSize            uint64        `env:"DB_MAX_POOL_SIZE" envDefault:"100"`
+	DbMinPoolSize            uint64        `env:"DB_MIN_POOL_SIZE" envDefault:"0"`
+	DbMaxConnIdleTime        time.Duration `env:"DB_MAX_CONN_IDLE_TIME" envDefault:"1m"`
+	DbConnectTimeout         time.Duration `env:"DB_CONNECT_TIMEOUT" envDefault:"10s"`
+	DbServerSelectionTimeout time.Duration `env:"DB_SERVER_SELECTION_TIMEOUT" envDefault:"5s"`
+	DbSocketTimeout          time.Duration `env:"DB_SOCKET_TIMEOUT" envDefault:"10s"`
+	DbSlowThreshold          time.Duration `env:"DB_SLOW_THRESHOLD" envDefault:"200ms"`
+
+	StartupAttempts   int           `env:"STARTUP_ATTEMPTS" envDefault:"10"`
+	StartupBackoff    time.Duration `env:"STARTUP_BACKOFF" envDefault:"500ms"`
+	StartupMaxBackoff time.Duration `env:"STARTUP_MAX_BACKOFF" envDefault:"10s"`
+	StartupTimeout    time.Duration `env:"STARTUP_TIMEOUT" envDefault:"2s"`
+
+	UserHost     string        `env:"USER_SERVER" envDefault:"localhost"`
+	UserPort     int           `env:"USER_PORT" envDefault:"50051"`
+	VerifyUser   bool          `env:"VERIFY_USER" envDefault:"true"`
+	UserCacheTTL time.Duration `env:"USER_CACHE_TTL" envDefault:"30s"`
+
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+	ShutdownDelay   time.Duration `env:"SHUTDOWN_DELAY" envDefault:"0s"`
+
+	HealthInterval time.Duration `env:"HEALTH_INTERVAL" envDefault:"5s"`
+	HealthTimeout  time.Duration `env:"HEALTH_TIMEOUT" envDefault:"1s"`
+
+	AdminAddr     string `env:"ADMIN_ADDR" envDefault:"127.0.0.1:9090"`
+	AdminGrpcAddr string `env:"ADMIN_GRPC_ADDR" envDefault:"127.0.0.1:9091"`
+	AdminToken    string `env:"ADMIN_TOKEN"`
+	// MetricsAddr serves /metrics apart from the admin listener, which is
+	// on loopback by default
+	MetricsAddr string `env:"METRICS_ADDR" envDefault:":9100"`
+
+	// The admin and metrics listeners answer quickly; the timeouts only
+	// keep a slow client from holding their connections.
+	HttpReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s"`
+	HttpReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"10s"`
+	HttpWriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"30s"`
+	HttpIdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"120s"`
+	HttpMaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" envDefault:"65536"`
+
+	TraceExporter    string  `env:"TRACE_EXPORTER" envDefault:"none"`
+	TraceEndpoint    string  `env:"TRACE_ENDPOINT" envDefault:"localhost:4317"`
+	TraceInsecure    bool    `env:"TRACE_INSECURE" envDefault:"true"`
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO" envDefault:"1"`
+}
+
+func httpConfig(cts constants) lifecycle.HttpConfig {
+	return lifecycle.HttpConfig{
+		ReadHeaderTimeout: cts.HttpReadHeaderTimeout,
+		ReadTimeout:       cts.HttpReadTimeout,
+		WriteTimeout:      cts.HttpWriteTimeout,
+		IdleTimeout:       cts.HttpIdleTimeout,
+		MaxHeaderBytes:    cts.HttpMaxHeaderBytes,
+	}
+}
+
+func startup(cts constants) lifecycle.Startup {
+	return lifecycle.Startup{
+		Attempts:   cts.StartupAttempts,
+		Backoff:    cts.StartupBackoff,
+		MaxBackoff: cts.StartupMaxBackoff,
+		Timeout:    cts.StartupTimeout,
+	}
+}
+
+func tracingConfig(cts constants) tracing.Config {
+	return tracing.Config{
+		Exporter:    cts.TraceExporter,
+		Endpoint:    cts.TraceEndpoint,
+		Insecure:    cts.TraceInsecure,
+		SampleRatio: cts.TraceSampleRatio,
+	}
 }
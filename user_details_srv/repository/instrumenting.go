@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	ErrorTimeout    = "timeout"
+	ErrorConstraint = "constraint"
+	ErrorConnection = "connection"
+	ErrorCanceled   = "canceled"
+	ErrorOther      = "other"
+)
+
+// ignoredFields carry session bookkeeping rather than the operation itself.
+var ignoredFields = map[string]bool{
+	"$db":             true,
+	"lsid":            true,
+	"$clusterTime":    true,
+	"$readPreference": true,
+}
+
+// NewCommandMonitor records the duration of every command by name, counts
+// failed commands by error class and logs the commands slower than slow.
+// Commands are logged sanitized, with every value replaced by "?".
+func NewCommandMonitor(duration metrics.Histogram, errors metrics.Counter, slow time.Duration, l log.Logger) *event.CommandMonitor {
+	logger := log.With(l, "repository", "mongodb")
+	var started sync.Map
+
+	finish := func(request_id int64, name string, nanos int64) {
+		took := time.Duration(nanos)
+		command, _ := started.LoadAndDelete(request_id)
+
+		duration.With("operation", name).Observe(took.Seconds())
+
+		if slow > 0 && took >= slow && command != nil {
+			level.Warn(logger).Log("slow_command", Sanitize(command.(bson.Raw)), "took", took)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			started.Store(e.RequestID, append(bson.Raw(nil), e.Command...))
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.RequestID, e.CommandName, e.DurationNanos)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.RequestID, e.CommandName, e.DurationNanos)
+			errors.With("operation", e.CommandName, "class", classifyFailure(e.Failure)).Add(1)
+		},
+	}
+}
+
+// ChainCommandMonitors fans the driver's command events out to monitors, as
+// the client accepts a single one.
+func ChainCommandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
+
+// Sanitize renders command as extended JSON keeping its shape, the command
+// name and the collection, with every other value replaced by "?".
+func Sanitize(command bson.Raw) string {
+	elements, err := command.Elements()
+	if err != nil || len(elements) == 0 {
+		return ""
+	}
+
+	doc := bson.D{{Key: elements[0].Key(), Value: elements[0].Value()}}
+	for _, e := range elements[1:] {
+		if !ignoredFields[e.Key()] {
+			doc = append(doc, bson.E{Key: e.Key(), Value: mask(e.Value())})
+		}
+	}
+
+	res, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return ""
+	}
+	return string(res)
+}
+
+func mask(v bson.RawValue) interface{} {
+	if d, ok := v.DocumentOK(); ok {
+		elements, _ := d.Elements()
+		doc := bson.D{}
+		for _, e := range elements {
+			doc = append(doc, bson.E{Key: e.Key(), Value: mask(e.Value())})
+		}
+		return doc
+	}
+	if a, ok := v.ArrayOK(); ok {
+		values, _ := a.Values()
+		arr := bson.A{}
+		for _, e := range values {
+			arr = append(arr, mask(e))
+		}
+		return arr
+	}
+	return "?"
+}
+
+// Classify sorts a driver error into timeout, constraint, connection,
+// canceled or other.
+func Classify(err error) string {
+	switch {
+	case stderrors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case mongo.IsTimeout(err):
+		return ErrorTimeout
+	case mongo.IsDuplicateKeyError(err):
+		return ErrorConstraint
+	case mongo.IsNetworkError(err), stderrors.Is(err, mongo.ErrClientDisconnected):
+		return ErrorConnection
+	}
+	return ErrorOther
+}
+
+// classifyFailure does the same for the text of a failed command event,
+// which is all the driver reports there.
+func classifyFailure(failure string) string {
+	switch f := strings.ToLower(failure); {
+	case strings.Contains(f, "context canceled"):
+		return ErrorCanceled
+	case strings.Contains(f, "timeout"), strings.Contains(f, "deadline exceeded"), strings.Contains(f, "maxtimemsexpired"):
+		return ErrorTimeout
+	case strings.Contains(f, "e11000"), strings.Contains(f, "duplicatekey"), strings.Contains(f, "documentvalidationfailure"):
+		return ErrorConstraint
+	case strings.HasPrefix(f, "connection("), strings.Contains(f, "connection refused"), strings.Contains(f, "network"):
+		return ErrorConnection
+	}
+	return ErrorOther
+}
@@ -0,0 +1,105 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/user_details_srv/repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func command(t *testing.T, d bson.D) bson.Raw {
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestSanitize(t *testing.T) {
+	raw := command(t, bson.D{
+		{Key: "update", Value: "information"},
+		{Key: "updates", Value: bson.A{bson.D{
+			{Key: "q", Value: bson.D{{Key: "_id", Value: 1}}},
+			{Key: "u", Value: bson.D{{Key: "$set", Value: bson.D{{Key: "mobile_number", Value: "11223344"}}}}},
+		}}},
+		{Key: "$db", Value: "grpc_details"},
+	})
+
+	assert.Equal(t,
+		`{"update":"information","updates":[{"q":{"_id":"?"},"u":{"$set":{"mobile_number":"?"}}}]}`,
+		repository.Sanitize(raw),
+	)
+}
+
+func TestCommandMonitor(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		took      time.Duration
+		failure   string
+		slow      bool
+	}{
+		{
+			test_name: "fast command",
+			took:      time.Millisecond,
+		},
+		{
+			test_name: "slow command",
+			took:      time.Second,
+			slow:      true,
+		},
+		{
+			test_name: "failed slow command",
+			took:      time.Second,
+			failure:   "(DuplicateKey) E11000 duplicate key error",
+			slow:      true,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			var buf bytes.Buffer
+			monitor := repository.NewCommandMonitor(generic.NewHistogram("duration", 10), generic.NewCounter("errors"), 100*time.Millisecond, log.NewLogfmtLogger(&buf))
+			raw := command(t, bson.D{{Key: "find", Value: "information"}, {Key: "filter", Value: bson.D{{Key: "_id", Value: 42}}}})
+			finished := event.CommandFinishedEvent{RequestID: 1, CommandName: "find", DurationNanos: tc.took.Nanoseconds()}
+
+			// act
+			monitor.Started(context.Background(), &event.CommandStartedEvent{RequestID: 1, CommandName: "find", Command: raw})
+			if tc.failure != "" {
+				monitor.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: finished, Failure: tc.failure})
+			} else {
+				monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: finished})
+			}
+
+			// assert
+			if tc.slow {
+				assert.Contains(buf.String(), `slow_command="{\"find\":\"information\",\"filter\":{\"_id\":\"?\"}}"`)
+			} else {
+				assert.Empty(buf.String())
+			}
+		})
+	}
+}
+
+func TestChainCommandMonitors(t *testing.T) {
+	// prepare
+	var calls []string
+	first := &event.CommandMonitor{Started: func(context.Context, *event.CommandStartedEvent) { calls = append(calls, "first") }}
+	second := &event.CommandMonitor{Started: func(context.Context, *event.CommandStartedEvent) { calls = append(calls, "second") }}
+
+	// act
+	chained := repository.ChainCommandMonitors(first, second)
+	chained.Started(context.Background(), &event.CommandStartedEvent{})
+	chained.Succeeded(context.Background(), &event.CommandSucceededEvent{})
+
+	// assert
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
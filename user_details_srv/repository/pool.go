@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// NewPoolMonitor mirrors the driver's connection pool events into gauges
+// for open and checked out connections and a counter of failed checkouts.
+func NewPoolMonitor(open metrics.Gauge, in_use metrics.Gauge, checkout_failures metrics.Counter) *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				open.Add(1)
+			case event.ConnectionClosed:
+				open.Add(-1)
+			case event.GetSucceeded:
+				in_use.Add(1)
+			case event.ConnectionReturned:
+				in_use.Add(-1)
+			case event.GetFailed:
+				checkout_failures.Add(1)
+			}
+		},
+	}
+}
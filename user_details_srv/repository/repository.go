@@ -4,16 +4,20 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/helpers"
+	"github.com/mauricioww/user_microsrv/logging"
 	"github.com/mauricioww/user_microsrv/user_details_srv/entities"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserDetailsRepository interface {
 	SetUserDetails(ctx context.Context, info entities.UserDetails) (bool, error)
 	GetUserDetails(ctx context.Context, user_id int) (entities.UserDetails, error)
+	GetUsersDetails(ctx context.Context, user_ids []int) ([]entities.UserDetails, error)
 	DeleteUserDetails(ctx context.Context, user_id int) (bool, error)
 }
 
@@ -40,7 +44,7 @@ func (r *userDetailsRepository) SetUserDetails(ctx context.Context, details enti
 	}
 
 	if err != nil {
-		return false, errors.NewInternalError()
+		return false, r.internal(ctx, "set_user_details", err)
 	}
 
 	return true, nil
@@ -52,8 +56,28 @@ func (r *userDetailsRepository) GetUserDetails(ctx context.Context, user_id int)
 
 	if helpers.NoExists(collection, ctx, user_id) {
 		return res, errors.NewUserNotFoundError()
-	} else if err := collection.FindOne(ctx, bson.D{{"_id", user_id}}).Decode(&res); err != nil {
-		return res, errors.NewInternalError()
+	} else if err := collection.FindOne(ctx, bson.D{{Key: "_id", Value: user_id}}).Decode(&res); err != nil {
+		return res, r.internal(ctx, "get_user_details", err)
+	}
+
+	return res, nil
+}
+
+// GetUsersDetails returns the details of those users that have some, by id.
+// No ids is no query.
+func (r *userDetailsRepository) GetUsersDetails(ctx context.Context, user_ids []int) ([]entities.UserDetails, error) {
+	res := []entities.UserDetails{}
+	if len(user_ids) == 0 {
+		return res, nil
+	}
+
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: user_ids}}}}
+	cursor, err := r.db.Collection("information").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, r.internal(ctx, "get_users_details", err)
+	}
+	if err := cursor.All(ctx, &res); err != nil {
+		return nil, r.internal(ctx, "get_users_details", err)
 	}
 
 	return res, nil
@@ -64,9 +88,16 @@ func (r *userDetailsRepository) DeleteUserDetails(ctx context.Context, user_id i
 
 	if helpers.NoExists(collection, ctx, user_id) {
 		return false, errors.NewUserNotFoundError()
-	} else if _, err := collection.DeleteOne(ctx, bson.D{{"_id", user_id}}); err != nil {
-		return false, errors.NewInternalError()
+	} else if _, err := collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: user_id}}); err != nil {
+		return false, r.internal(ctx, "delete_user_details", err)
 	}
 
 	return true, nil
 }
+
+// internal logs the cause of a failed operation before hiding it behind the
+// generic internal error returned to callers.
+func (r *userDetailsRepository) internal(ctx context.Context, method string, err error) error {
+	level.Error(r.logger).Log("request_id", logging.RequestID(ctx), "method", method, "class", Classify(err), "err", err)
+	return errors.NewInternalError().Wrap(err)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	l "log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type GrpcUserMock struct {
+	mock.Mock
+	userpb.UnimplementedUserServiceServer
+}
+
+func InitUserRepoMock(m *GrpcUserMock, ttl time.Duration) (*grpc.ClientConn, UserRepository) {
+	var logger log.Logger
+	{
+		logger = log.NewLogfmtLogger(os.Stderr)
+		logger = log.NewSyncLogger(logger)
+		logger = log.With(
+			logger,
+			"service",
+			"user_details",
+			"time",
+			log.DefaultTimestampUTC,
+			"caller",
+			log.DefaultCaller,
+		)
+	}
+
+	conn, _ := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(Dialer(m)))
+
+	r := NewUserRepository(conn, ttl, logger)
+	return conn, r
+}
+
+func Dialer(m *GrpcUserMock) func(context.Context, string) (net.Conn, error) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	userpb.RegisterUserServiceServer(server, m)
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			l.Fatal(err)
+		}
+	}()
+
+	return func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}
+}
+
+func (m *GrpcUserMock) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	args := m.Called(ctx, req)
+
+	return args.Get(0).(*userpb.GetUserResponse), args.Error(1)
+}
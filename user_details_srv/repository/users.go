@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type UserRepository interface {
+	UserExists(ctx context.Context, user_id int) (bool, error)
+}
+
+type userRepository struct {
+	client userpb.UserServiceClient
+	ttl    time.Duration
+	logger log.Logger
+
+	mtx  sync.Mutex
+	seen map[int]time.Time
+	// swept is when the expired ids were last dropped from seen
+	swept time.Time
+}
+
+// NewUserRepository checks users against user_srv, remembering the ids
+// that exist for ttl so repeated writes for the same user skip the call.
+func NewUserRepository(conn *grpc.ClientConn, ttl time.Duration, l log.Logger) UserRepository {
+	return &userRepository{
+		client: userpb.NewUserServiceClient(conn),
+		ttl:    ttl,
+		logger: log.With(l, "repository", "user_grpc"),
+		seen:   make(map[int]time.Time),
+		swept:  time.Now(),
+	}
+}
+
+func (r *userRepository) UserExists(ctx context.Context, user_id int) (bool, error) {
+	logger := log.With(r.logger, "request_id", logging.RequestID(ctx), "method", "user_exists")
+
+	if r.cached(user_id) {
+		return true, nil
+	}
+
+	_, err := r.client.GetUser(ctx, &userpb.GetUserRequest{Id: uint32(user_id)})
+
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	} else if err != nil {
+		level.Error(logger).Log("err_user", err)
+		return false, errors.NewInternalError().Wrap(err)
+	}
+
+	r.remember(user_id)
+	return true, nil
+}
+
+func (r *userRepository) cached(user_id int) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	expires, ok := r.seen[user_id]
+	if ok && time.Now().After(expires) {
+		delete(r.seen, user_id)
+		return false
+	}
+
+	return ok
+}
+
+func (r *userRepository) remember(user_id int) {
+	if r.ttl <= 0 {
+		return
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := time.Now()
+	r.seen[user_id] = now.Add(r.ttl)
+	r.sweep(now)
+}
+
+// sweep drops the expired ids at most once per ttl, so seen holds no more
+// than the ids checked in the last two; ids nobody asks for again would
+// otherwise stay forever.
+func (r *userRepository) sweep(now time.Time) {
+	if now.Sub(r.swept) < r.ttl {
+		return
+	}
+	r.swept = now
+
+	for id, expires := range r.seen {
+		if now.After(expires) {
+			delete(r.seen, id)
+		}
+	}
+}
+
+type uncheckedUserRepository struct{}
+
+// NewUncheckedUserRepository accepts every user id. It is meant for bulk
+// migrations where user_srv is not reachable or not yet populated.
+func NewUncheckedUserRepository() UserRepository {
+	return uncheckedUserRepository{}
+}
+
+func (uncheckedUserRepository) UserExists(_ context.Context, _ int) (bool, error) {
+	return true, nil
+}
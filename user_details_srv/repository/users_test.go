@@ -0,0 +1,92 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/user_details_srv/repository"
+	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUserExists(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	conn, user_repository := repository.InitUserRepoMock(user_mock, time.Minute)
+
+	defer conn.Close()
+
+	test_cases := []struct {
+		test_name string
+		user_id   int
+		user_res  *userpb.GetUserResponse
+		user_err  error
+		res       bool
+		err       error
+	}{
+		{
+			test_name: "user exists",
+			user_id:   1,
+			user_res:  &userpb.GetUserResponse{Email: "user@email.com"},
+			res:       true,
+		},
+		{
+			test_name: "user does not exist",
+			user_id:   2,
+			user_err:  status.Error(codes.NotFound, "User not found"),
+			res:       false,
+		},
+		{
+			test_name: "user service failure error",
+			user_id:   3,
+			user_err:  status.Error(codes.Unavailable, "connection refused"),
+			res:       false,
+			err:       errors.NewInternalError(),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			ctx := context.Background()
+			user_mock.On("GetUser", mock.Anything, &userpb.GetUserRequest{Id: uint32(tc.user_id)}).Return(tc.user_res, tc.user_err)
+
+			// act
+			res, err := user_repository.UserExists(ctx, tc.user_id)
+
+			// assert
+			assert.Equal(tc.res, res)
+			if tc.err != nil {
+				assert.ErrorIs(err, tc.err)
+			} else {
+				assert.Nil(err)
+			}
+		})
+	}
+}
+
+func TestUserExistsCache(t *testing.T) {
+	user_mock := new(repository.GrpcUserMock)
+	conn, user_repository := repository.InitUserRepoMock(user_mock, time.Minute)
+
+	defer conn.Close()
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	user_mock.On("GetUser", mock.Anything, &userpb.GetUserRequest{Id: 1}).Return(&userpb.GetUserResponse{}, nil).Once()
+
+	// act
+	first, _ := user_repository.UserExists(ctx, 1)
+	second, _ := user_repository.UserExists(ctx, 1)
+
+	// assert
+	assert.True(first)
+	assert.True(second)
+	user_mock.AssertNumberOfCalls(t, "GetUser", 1)
+}
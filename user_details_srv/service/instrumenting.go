@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/user_details_srv/entities"
+)
+
+type Middleware func(GrpcUserDetailsService) GrpcUserDetailsService
+
+type Metrics struct {
+	Requests metrics.Counter
+	Errors   metrics.Counter
+	Latency  metrics.Histogram
+}
+
+type instrumentingMiddleware struct {
+	metrics Metrics
+	next    GrpcUserDetailsService
+}
+
+func InstrumentingMiddleware(m Metrics) Middleware {
+	return func(next GrpcUserDetailsService) GrpcUserDetailsService {
+		return &instrumentingMiddleware{metrics: m, next: next}
+	}
+}
+
+func (mw *instrumentingMiddleware) observe(method string, begin time.Time, err error) {
+	mw.metrics.Requests.With("method", method).Add(1)
+	if err != nil {
+		mw.metrics.Errors.With("method", method, "code", errors.Code(err).String()).Add(1)
+	}
+	mw.metrics.Latency.With("method", method).Observe(time.Since(begin).Seconds())
+}
+
+func (mw *instrumentingMiddleware) SetUserDetails(ctx context.Context, user_id int, country string, city string, mobile_number string, married bool, height float32, weight float32) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("set_user_details", begin, err)
+	}(time.Now())
+	return mw.next.SetUserDetails(ctx, user_id, country, city, mobile_number, married, height, weight)
+}
+
+func (mw *instrumentingMiddleware) GetUserDetails(ctx context.Context, user_id int) (res entities.UserDetails, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_user_details", begin, err)
+	}(time.Now())
+	return mw.next.GetUserDetails(ctx, user_id)
+}
+
+func (mw *instrumentingMiddleware) GetUsersDetails(ctx context.Context, user_ids []int) (res []entities.UserDetails, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_users_details", begin, err)
+	}(time.Now())
+	return mw.next.GetUsersDetails(ctx, user_ids)
+}
+
+func (mw *instrumentingMiddleware) DeleteUserDetails(ctx context.Context, user_id int) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("delete_user_details", begin, err)
+	}(time.Now())
+	return mw.next.DeleteUserDetails(ctx, user_id)
+}
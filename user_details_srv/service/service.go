@@ -5,6 +5,8 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/logging"
 	"github.com/mauricioww/user_microsrv/user_details_srv/entities"
 	"github.com/mauricioww/user_microsrv/user_details_srv/repository"
 )
@@ -12,23 +14,38 @@ import (
 type GrpcUserDetailsService interface {
 	SetUserDetails(ctx context.Context, user_id int, country string, city string, mobile_number string, married bool, height float32, weigth float32) (bool, error)
 	GetUserDetails(ctx context.Context, user_id int) (entities.UserDetails, error)
+	GetUsersDetails(ctx context.Context, user_ids []int) ([]entities.UserDetails, error)
 	DeleteUserDetails(ctx context.Context, user_id int) (bool, error)
 }
 
+// MaxBatch bounds the ids of a GetUsersDetails call.
+const MaxBatch = 100
+
 type grpcUserDetailsService struct {
 	repository repository.UserDetailsRepository
+	users      repository.UserRepository
 	logger     log.Logger
 }
 
-func NewGrpcUserDetailsService(r repository.UserDetailsRepository, l log.Logger) GrpcUserDetailsService {
+func NewGrpcUserDetailsService(r repository.UserDetailsRepository, u repository.UserRepository, l log.Logger) GrpcUserDetailsService {
 	return &grpcUserDetailsService{
 		repository: r,
+		users:      u,
 		logger:     l,
 	}
 }
 
 func (g *grpcUserDetailsService) SetUserDetails(ctx context.Context, user_id int, country string, city string, mobile_number string, married bool, height float32, weight float32) (bool, error) {
-	logger := log.With(g.logger, "method", "set_user_details")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "set_user_details")
+
+	if exists, err := g.users.UserExists(ctx, user_id); err != nil {
+		level.Error(logger).Log("ERROR", err)
+		return false, err
+	} else if !exists {
+		e := errors.NewUserNotFoundError()
+		level.Error(logger).Log("validation: ", e)
+		return false, e
+	}
 
 	information := entities.UserDetails{
 		UserId:       user_id,
@@ -52,7 +69,7 @@ func (g *grpcUserDetailsService) SetUserDetails(ctx context.Context, user_id int
 }
 
 func (g *grpcUserDetailsService) GetUserDetails(ctx context.Context, user_id int) (entities.UserDetails, error) {
-	logger := log.With(g.logger, "method", "get_user_details")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "get_user_details")
 	res, err := g.repository.GetUserDetails(ctx, user_id)
 
 	if err != nil {
@@ -64,8 +81,23 @@ func (g *grpcUserDetailsService) GetUserDetails(ctx context.Context, user_id int
 	return res, err
 }
 
+// GetUsersDetails looks up to MaxBatch users' details up at once; users with
+// none are left out of the result.
+func (g *grpcUserDetailsService) GetUsersDetails(ctx context.Context, user_ids []int) ([]entities.UserDetails, error) {
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "get_users_details")
+	res, err := g.repository.GetUsersDetails(ctx, user_ids)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR", err)
+	} else {
+		logger.Log("action", "success", "requested", len(user_ids), "results", len(res))
+	}
+
+	return res, err
+}
+
 func (g *grpcUserDetailsService) DeleteUserDetails(ctx context.Context, user_id int) (bool, error) {
-	logger := log.With(g.logger, "method", "delete_user_details")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "delete_user_details")
 	res, err := g.repository.DeleteUserDetails(ctx, user_id)
 
 	if err != nil {
@@ -25,12 +25,28 @@ func (r *UserDetailsRepositoryMock) GetUserDetails(ctx context.Context, user_id
 	return args.Get(0).(entities.UserDetails), args.Error(1)
 }
 
+func (r *UserDetailsRepositoryMock) GetUsersDetails(ctx context.Context, user_ids []int) ([]entities.UserDetails, error) {
+	args := r.Called(ctx, user_ids)
+
+	return args.Get(0).([]entities.UserDetails), args.Error(1)
+}
+
 func (r *UserDetailsRepositoryMock) DeleteUserDetails(ctx context.Context, user_id int) (bool, error) {
 	args := r.Called(ctx, user_id)
 
 	return args.Bool(0), args.Error(1)
 }
 
+type UserRepositoryMock struct {
+	mock.Mock
+}
+
+func (r *UserRepositoryMock) UserExists(ctx context.Context, user_id int) (bool, error) {
+	args := r.Called(ctx, user_id)
+
+	return args.Bool(0), args.Error(1)
+}
+
 func InitLogger() log.Logger {
 	var logger log.Logger
 	{
@@ -14,13 +14,16 @@ func TestSetUserDetails(t *testing.T) {
 	var grpc_user_details_srv service.GrpcUserDetailsService
 
 	user_details_repo_mock := new(service.UserDetailsRepositoryMock)
-	grpc_user_details_srv = service.NewGrpcUserDetailsService(user_details_repo_mock, service.InitLogger())
+	user_repo_mock := new(service.UserRepositoryMock)
+	grpc_user_details_srv = service.NewGrpcUserDetailsService(user_details_repo_mock, user_repo_mock, service.InitLogger())
 
 	test_cases := []struct {
-		test_name string
-		data      entities.UserDetails
-		res       bool
-		err       error
+		test_name   string
+		data        entities.UserDetails
+		user_exists bool
+		user_err    error
+		res         bool
+		err         error
 	}{
 		{
 			test_name: "set user details which no exists success",
@@ -33,8 +36,27 @@ func TestSetUserDetails(t *testing.T) {
 				Height:       1.75,
 				Weight:       76.0,
 			},
-			res: true,
-			err: nil,
+			user_exists: true,
+			res:         true,
+			err:         nil,
+		},
+		{
+			test_name: "set user details for unknown user error",
+			data: entities.UserDetails{
+				UserId:  2,
+				Country: "Mexico",
+			},
+			user_exists: false,
+			err:         errors.NewUserNotFoundError(),
+		},
+		{
+			test_name: "user service failure error",
+			data: entities.UserDetails{
+				UserId:  3,
+				Country: "Mexico",
+			},
+			user_err: errors.NewInternalError(),
+			err:      errors.NewInternalError(),
 		},
 	}
 
@@ -45,6 +67,7 @@ func TestSetUserDetails(t *testing.T) {
 			assert := assert.New(t)
 
 			// act
+			user_repo_mock.On("UserExists", ctx, tc.data.UserId).Return(tc.user_exists, tc.user_err)
 			user_details_repo_mock.On("SetUserDetails", ctx, tc.data).Return(tc.res, tc.err)
 			res, err := grpc_user_details_srv.SetUserDetails(ctx, tc.data.UserId, tc.data.Country, tc.data.City,
 				tc.data.MobileNumber, tc.data.Married, tc.data.Height, tc.data.Weight)
@@ -60,7 +83,7 @@ func TestGetUserDetails(t *testing.T) {
 	var grpc_user_details_srv service.GrpcUserDetailsService
 
 	user_details_repo_mock := new(service.UserDetailsRepositoryMock)
-	grpc_user_details_srv = service.NewGrpcUserDetailsService(user_details_repo_mock, service.InitLogger())
+	grpc_user_details_srv = service.NewGrpcUserDetailsService(user_details_repo_mock, new(service.UserRepositoryMock), service.InitLogger())
 
 	test_cases := []struct {
 		test_name string
@@ -106,11 +129,52 @@ func TestGetUserDetails(t *testing.T) {
 	}
 }
 
+func TestGetUsersDetails(t *testing.T) {
+	var grpc_user_details_srv service.GrpcUserDetailsService
+
+	user_details_repo_mock := new(service.UserDetailsRepositoryMock)
+	grpc_user_details_srv = service.NewGrpcUserDetailsService(user_details_repo_mock, new(service.UserRepositoryMock), service.InitLogger())
+
+	test_cases := []struct {
+		test_name string
+		data      []int
+		res       []entities.UserDetails
+		err       error
+	}{
+		{
+			test_name: "some found",
+			data:      []int{1, 2},
+			res:       []entities.UserDetails{{UserId: 2, Country: "MX", City: "CDMX"}},
+		},
+		{
+			test_name: "repository error",
+			data:      []int{3},
+			err:       errors.NewInternalError(),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			// act
+			user_details_repo_mock.On("GetUsersDetails", ctx, tc.data).Return(tc.res, tc.err)
+			res, err := grpc_user_details_srv.GetUsersDetails(ctx, tc.data)
+
+			// assert
+			assert.Equal(tc.res, res)
+			assert.Equal(tc.err, err)
+		})
+	}
+}
+
 func TestDeleteUserDetails(t *testing.T) {
 	var grpc_user_details_srv service.GrpcUserDetailsService
 
 	user_details_repo_mock := new(service.UserDetailsRepositoryMock)
-	grpc_user_details_srv = service.NewGrpcUserDetailsService(user_details_repo_mock, service.InitLogger())
+	grpc_user_details_srv = service.NewGrpcUserDetailsService(user_details_repo_mock, new(service.UserRepositoryMock), service.InitLogger())
 
 	test_cases := []struct {
 		test_name string
@@ -15,6 +15,10 @@ type (
 		UserId int
 	}
 
+	GetUsersDetailsRequest struct {
+		UserIds []int
+	}
+
 	DeleteUserDetailsRequest struct {
 		UserId int
 	}
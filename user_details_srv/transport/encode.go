@@ -1,5 +1,7 @@
 package transport
 
+import "github.com/mauricioww/user_microsrv/user_details_srv/entities"
+
 type (
 	SetUserDetailsResponse struct {
 		Success bool
@@ -14,6 +16,10 @@ type (
 		Weight       float32
 	}
 
+	GetUsersDetailsResponse struct {
+		Details []entities.UserDetails
+	}
+
 	DeleteUserDetailsResponse struct {
 		Success bool
 	}
@@ -10,6 +10,7 @@ import (
 type GrpcUserDetailsServiceEndpoints struct {
 	SetUserDetails    endpoint.Endpoint
 	GetUserDetails    endpoint.Endpoint
+	GetUsersDetails   endpoint.Endpoint
 	DeleteUserDetails endpoint.Endpoint
 }
 
@@ -17,6 +18,7 @@ func MakeGrpcUserDetailsServiceEndpoints(grpc_srv service.GrpcUserDetailsService
 	return GrpcUserDetailsServiceEndpoints{
 		SetUserDetails:    makeSetUserDetailsEndpoint(grpc_srv),
 		GetUserDetails:    makeGetUserDetailsEndpoint(grpc_srv),
+		GetUsersDetails:   makeGetUsersDetailsEndpoint(grpc_srv),
 		DeleteUserDetails: makeDeleteUserDetailsEndpoint(grpc_srv),
 	}
 }
@@ -37,6 +39,14 @@ func makeGetUserDetailsEndpoint(srv service.GrpcUserDetailsService) endpoint.End
 	}
 }
 
+func makeGetUsersDetailsEndpoint(srv service.GrpcUserDetailsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req, _ := request.(GetUsersDetailsRequest)
+		res, err := srv.GetUsersDetails(ctx, req.UserIds)
+		return GetUsersDetailsResponse{Details: res}, err
+	}
+}
+
 func makeDeleteUserDetailsEndpoint(srv service.GrpcUserDetailsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req, _ := request.(DeleteUserDetailsRequest)
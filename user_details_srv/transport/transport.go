@@ -7,12 +7,14 @@ import (
 	grpc_gokit "github.com/go-kit/kit/transport/grpc"
 	grpc_err "github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/user_details_srv/detailspb"
-	"google.golang.org/grpc/status"
+	"github.com/mauricioww/user_microsrv/user_details_srv/service"
+	"github.com/mauricioww/user_microsrv/validation"
 )
 
 type gRPCServer struct {
 	setUserDetails    grpc_gokit.Handler
 	getUserDetails    grpc_gokit.Handler
+	getUsersDetails   grpc_gokit.Handler
 	deleteUserDetails grpc_gokit.Handler
 
 	detailspb.UnimplementedUserDetailsServiceServer
@@ -32,6 +34,12 @@ func NewGrpcUserDetailsServer(endpoints GrpcUserDetailsServiceEndpoints) details
 			encodeGetUserDetailsResponse,
 		),
 
+		getUsersDetails: grpc_gokit.NewServer(
+			endpoints.GetUsersDetails,
+			decodeGetUsersDetailsRequest,
+			encodeGetUsersDetailsResponse,
+		),
+
 		deleteUserDetails: grpc_gokit.NewServer(
 			endpoints.DeleteUserDetails,
 			decodeDeleteUserDetails,
@@ -57,7 +65,13 @@ func decodeSetUserDetailsRequest(_ context.Context, request interface{}) (interf
 		Weigth:       set_details.GetWeight(),
 	}
 
-	return req, nil
+	return req, validation.Validate(
+		validation.Check("country", req.Country, validation.Required, validation.CountryCode),
+		validation.Check("city", req.City, validation.MaxLength(100)),
+		validation.Check("mobile_number", req.MobileNumber, validation.Optional(validation.Phone)),
+		validation.Check("height", req.Height, validation.Optional(validation.Height)),
+		validation.Check("weight", req.Weigth, validation.Optional(validation.Weight)),
+	)
 }
 
 func encodeSetUserDetailsResponse(_ context.Context, response interface{}) (interface{}, error) {
@@ -86,6 +100,35 @@ func encodeGetUserDetailsResponse(_ context.Context, response interface{}) (inte
 		Married: res.Married, Height: res.Height, Weight: res.Weight}, nil
 }
 
+func decodeGetUsersDetailsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	get_details, ok := request.(*detailspb.GetUsersDetailsRequest)
+
+	if !ok {
+		return nil, errors.New("No proto message 'GetUsersDetailsRequest'")
+	}
+
+	req := GetUsersDetailsRequest{UserIds: make([]int, len(get_details.GetUserIds()))}
+	for i, id := range get_details.GetUserIds() {
+		req.UserIds[i] = int(id)
+	}
+
+	return req, validation.Validate(
+		validation.Check("user_ids", len(req.UserIds), validation.MaxItems(service.MaxBatch)),
+	)
+}
+
+func encodeGetUsersDetailsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(GetUsersDetailsResponse)
+	details := make([]*detailspb.UserDetails, 0, len(res.Details))
+
+	for _, d := range res.Details {
+		details = append(details, &detailspb.UserDetails{UserId: uint32(d.UserId), Country: d.Country, City: d.City,
+			MobileNumber: d.MobileNumber, Married: d.Married, Height: d.Height, Weight: d.Weight})
+	}
+
+	return &detailspb.GetUsersDetailsResponse{Details: details}, nil
+}
+
 func decodeDeleteUserDetails(_ context.Context, request interface{}) (interface{}, error) {
 	delete_details, ok := request.(*detailspb.DeleteUserDetailsRequest)
 
@@ -109,8 +152,7 @@ func (g *gRPCServer) SetUserDetails(ctx context.Context, req *detailspb.SetUserD
 	_, res, err := g.setUserDetails.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*detailspb.SetUserDetailsResponse), nil
@@ -120,19 +162,27 @@ func (g *gRPCServer) GetUserDetails(ctx context.Context, req *detailspb.GetUserD
 	_, res, err := g.getUserDetails.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*detailspb.GetUserDetailsResponse), nil
 }
 
+func (g *gRPCServer) GetUsersDetails(ctx context.Context, req *detailspb.GetUsersDetailsRequest) (*detailspb.GetUsersDetailsResponse, error) {
+	_, res, err := g.getUsersDetails.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, grpc_err.Translate(err)
+	}
+
+	return res.(*detailspb.GetUsersDetailsResponse), nil
+}
+
 func (g *gRPCServer) DeleteUserDetails(ctx context.Context, req *detailspb.DeleteUserDetailsRequest) (*detailspb.DeleteUserDetailsResponse, error) {
 	_, res, err := g.deleteUserDetails.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*detailspb.DeleteUserDetailsResponse), nil
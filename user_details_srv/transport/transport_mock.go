@@ -5,6 +5,7 @@ import (
 
 	"github.com/mauricioww/user_microsrv/user_details_srv/entities"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/status"
 )
 
 type GrpcUserDetailsSrvMock struct {
@@ -23,8 +24,20 @@ func (g *GrpcUserDetailsSrvMock) GetUserDetails(ctx context.Context, user_id int
 	return args.Get(0).(entities.UserDetails), args.Error(1)
 }
 
+func (g *GrpcUserDetailsSrvMock) GetUsersDetails(ctx context.Context, user_ids []int) ([]entities.UserDetails, error) {
+	args := g.Called(ctx, user_ids)
+
+	return args.Get(0).([]entities.UserDetails), args.Error(1)
+}
+
 func (g *GrpcUserDetailsSrvMock) DeleteUserDetails(ctx context.Context, user_id int) (bool, error) {
 	args := g.Called(ctx, user_id)
 
 	return args.Bool(0), args.Error(1)
 }
+
+func TestErrors(err1 error, err2 error) bool {
+	e1 := status.Convert(err1)
+	e2 := status.Convert(err2)
+	return (e1.Code() == e2.Code()) && (e1.Message() == e2.Message())
+}
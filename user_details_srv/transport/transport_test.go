@@ -7,8 +7,10 @@ import (
 	"github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/user_details_srv/detailspb"
 	"github.com/mauricioww/user_microsrv/user_details_srv/entities"
+	"github.com/mauricioww/user_microsrv/user_details_srv/service"
 	"github.com/mauricioww/user_microsrv/user_details_srv/transport"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -28,7 +30,7 @@ func TestSetUserDetails(t *testing.T) {
 			test_name: "set details success",
 			data: &detailspb.SetUserDetailsRequest{
 				UserId:       1,
-				Country:      "Mexico",
+				Country:      "MX",
 				City:         "CDMX",
 				MobileNumber: "11223344",
 				Married:      false,
@@ -42,6 +44,7 @@ func TestSetUserDetails(t *testing.T) {
 			test_name: "update details success",
 			data: &detailspb.SetUserDetailsRequest{
 				UserId:       1,
+				Country:      "US",
 				MobileNumber: "12345789",
 				Married:      false,
 				Height:       1.75,
@@ -49,6 +52,21 @@ func TestSetUserDetails(t *testing.T) {
 			srv_res: true,
 			err:     nil,
 		},
+		{
+			test_name: "invalid details error",
+			data: &detailspb.SetUserDetailsRequest{
+				UserId:       1,
+				MobileNumber: "call me",
+				Height:       -1.75,
+				Weight:       500,
+			},
+			err: errors.NewInvalidArgumentError(
+				errors.FieldViolation{Field: "country", Description: "is required"},
+				errors.FieldViolation{Field: "mobile_number", Description: "must be a phone number of 7 to 15 digits"},
+				errors.FieldViolation{Field: "height", Description: "must be between 0.3 and 2.8"},
+				errors.FieldViolation{Field: "weight", Description: "must be between 1 and 350"},
+			),
+		},
 	}
 	for _, tc := range test_cases {
 		t.Run(tc.test_name, func(t *testing.T) {
@@ -70,7 +88,10 @@ func TestSetUserDetails(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
+			if tc.err != nil {
+				assert.Equal(errors.FromError(tc.err).Violations, errors.FromError(err).Violations)
+			}
 		})
 	}
 }
@@ -131,11 +152,39 @@ func TestGetUserDetails(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
 		})
 	}
 }
 
+func TestGetUsersDetails(t *testing.T) {
+	mock_srv := new(transport.GrpcUserDetailsSrvMock)
+	endpoints := transport.MakeGrpcUserDetailsServiceEndpoints(mock_srv)
+	grpc_service := transport.NewGrpcUserDetailsServer(endpoints)
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	mock_srv.On("GetUsersDetails", ctx, []int{1, 2}).Return([]entities.UserDetails{
+		{UserId: 2, Country: "MX", City: "CDMX", Height: 1.75},
+	}, nil)
+	too_many := make([]uint32, service.MaxBatch+1)
+
+	// act
+	res, err := grpc_service.GetUsersDetails(ctx, &detailspb.GetUsersDetailsRequest{UserIds: []uint32{1, 2}})
+	_, invalid := grpc_service.GetUsersDetails(ctx, &detailspb.GetUsersDetailsRequest{UserIds: too_many})
+
+	// assert
+	assert.Nil(err)
+	if assert.Len(res.GetDetails(), 1) {
+		assert.Equal(uint32(2), res.GetDetails()[0].GetUserId())
+		assert.Equal("MX", res.GetDetails()[0].GetCountry())
+		assert.Equal(float32(1.75), res.GetDetails()[0].GetHeight())
+	}
+	assert.Equal(codes.FailedPrecondition, status.Code(invalid))
+	mock_srv.AssertNumberOfCalls(t, "GetUsersDetails", 1)
+}
+
 func TestDeleteUserDetails(t *testing.T) {
 	mock_srv := new(transport.GrpcUserDetailsSrvMock)
 	endpoints := transport.MakeGrpcUserDetailsServiceEndpoints(mock_srv)
@@ -184,7 +233,7 @@ func TestDeleteUserDetails(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
 		})
 	}
 }
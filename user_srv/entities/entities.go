@@ -17,4 +17,10 @@ type (
 		UserId int
 		User
 	}
+
+	Account struct {
+		Id    int
+		Email string
+		Age   int
+	}
 )
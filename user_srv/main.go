@@ -1,22 +1,34 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net"
 	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
 	"github.com/caarlos0/env/v6"
-	"github.com/go-kit/log"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/log/level"
 	_ "github.com/go-sql-driver/mysql"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/mauricioww/user_microsrv/admin"
+	"github.com/mauricioww/user_microsrv/health"
+	"github.com/mauricioww/user_microsrv/lifecycle"
+	"github.com/mauricioww/user_microsrv/logging"
+	"github.com/mauricioww/user_microsrv/recovery"
+	"github.com/mauricioww/user_microsrv/tracing"
 	"github.com/mauricioww/user_microsrv/user_srv/repository"
 	"github.com/mauricioww/user_microsrv/user_srv/service"
 	"github.com/mauricioww/user_microsrv/user_srv/transport"
 	"github.com/mauricioww/user_microsrv/user_srv/userpb"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
@@ -26,25 +38,22 @@ func main() {
 		fmt.Printf("%+v\n", err)
 	}
 
-	var logger log.Logger
-	{
-		logger = log.NewLogfmtLogger(os.Stderr)
-		logger = log.NewSyncLogger(logger)
-		logger = log.With(
-			logger,
-			"service",
-			"GRPC_SRV",
-			"time",
-			log.DefaultTimestampUTC,
-			"caller",
-			log.DefaultCaller,
-		)
+	logger, err := logging.New(os.Stderr, "GRPC_SRV", logging.Config{Format: cts.LogFormat, Level: cts.LogLevel})
+	if err != nil {
+		fmt.Printf("%+v\n", err)
+		os.Exit(-1)
 	}
 
 	level.Info(logger).Log("mesg", "service started")
 
 	defer level.Info(logger).Log("msg", "service ended")
 
+	shutdown_tracing, err := tracing.Init(context.Background(), "user_srv", tracingConfig(cts))
+	if err != nil {
+		level.Error(logger).Log("exit", err)
+		os.Exit(-1)
+	}
+
 	var db *sql.DB
 	{
 		var err error
@@ -54,21 +63,92 @@ func main() {
 			level.Error(logger).Log("exit", err)
 			os.Exit(-1)
 		}
+
+		db.SetMaxOpenConns(cts.DbMaxOpenConns)
+		db.SetMaxIdleConns(cts.DbMaxIdleConns)
+		db.SetConnMaxLifetime(cts.DbConnMaxLifetime)
+		db.SetConnMaxIdleTime(cts.DbConnMaxIdleTime)
+
+		if err := lifecycle.WaitFor(context.Background(), "mysql", startup(cts), db.PingContext, logger); err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
+		}
 	}
 
 	var grpc_user_srv service.GrpcUserService
 	{
-		mysql_repository := repository.NewUserRepository(db, logger)
+		instrumented_db := repository.NewInstrumentedDB(db,
+			kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "user_srv",
+				Subsystem: "mysql",
+				Name:      "statement_duration_seconds",
+				Help:      "SQL statement latency in seconds.",
+			}, []string{"operation"}),
+			kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "user_srv",
+				Subsystem: "mysql",
+				Name:      "errors_total",
+				Help:      "Number of failed SQL statements by error class.",
+			}, []string{"operation", "class"}),
+			cts.DbSlowThreshold,
+			logger,
+		)
+		mysql_repository := repository.NewUserRepository(instrumented_db, logger)
 		grpc_user_srv = service.NewGrpcUserService(mysql_repository, logger)
+
+		fields := []string{"method"}
+		grpc_user_srv = service.InstrumentingMiddleware(service.Metrics{
+			Requests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "user_srv",
+				Subsystem: "service",
+				Name:      "requests_total",
+				Help:      "Number of requests received.",
+			}, fields),
+			Errors: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "user_srv",
+				Subsystem: "service",
+				Name:      "errors_total",
+				Help:      "Number of failed requests by error code.",
+			}, []string{"method", "code"}),
+			Latency: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "user_srv",
+				Subsystem: "service",
+				Name:      "request_duration_seconds",
+				Help:      "Request latency in seconds.",
+			}, fields),
+			Signups: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "user_srv",
+				Name:      "signups_total",
+				Help:      "Number of users created.",
+			}, []string{}),
+			FailedLogins: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "user_srv",
+				Name:      "failed_logins_total",
+				Help:      "Number of rejected authentication attempts.",
+			}, []string{}),
+		})(grpc_user_srv)
+
+		stdprometheus.MustRegister(collectors.NewDBStatsCollector(db, cts.DbName))
 	}
 
-	errs := make(chan error)
+	lc := lifecycle.New(lifecycle.Config{Timeout: cts.ShutdownTimeout, DrainDelay: cts.ShutdownDelay}, logger)
 
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		errs <- fmt.Errorf("%s", <-c)
-	}()
+	monitor := health.NewMonitor(cts.HealthInterval, cts.HealthTimeout, lc.Ready, logger)
+	{
+		monitor.AddCheck("mysql", db.PingContext)
+		health_ctx, stop_health := context.WithCancel(context.Background())
+		go monitor.Run(health_ctx)
+		lc.OnReadyChange(func(bool) { monitor.Refresh() })
+		lc.AddCloser("health", func(context.Context) error {
+			stop_health()
+			return nil
+		})
+	}
+
+	lc.AddCloser("mysql", func(context.Context) error {
+		return db.Close()
+	})
+	lc.AddCloser("tracing", shutdown_tracing)
 
 	grpc_endpoints := transport.MakeGrpcUserServiceEndpoints(grpc_user_srv)
 	grpc_server := transport.NewGrpcUserServer(grpc_endpoints)
@@ -79,22 +159,113 @@ func main() {
 		os.Exit(-1)
 	}
 
-	go func() {
-		server := grpc.NewServer()
-		userpb.RegisterUserServiceServer(server, grpc_server)
-		if err := server.Serve(grpc_listener); err != nil {
-			logger.Log("Error serving", err)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(), recovery.UnaryServerInterceptor(logger), tracing.UnaryServerInterceptor(), grpc_prometheus.UnaryServerInterceptor))
+	userpb.RegisterUserServiceServer(server, grpc_server)
+	health_srv := grpc_health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health_srv)
+	// reflection lists the service to anyone able to reach it, which is
+	// only wanted while debugging
+	if cts.Environment != "production" {
+		reflection.Register(server)
+	}
+	monitor.Publish(health_srv, "UserService")
+	grpc_prometheus.EnableHandlingTimeHistogram()
+	grpc_prometheus.Register(server)
+	lc.AddGrpcServer("grpc", server, grpc_listener)
+	if cts.AdminAddr != "" {
+		lc.AddHttpServer("admin", lifecycle.NewHttpServer(cts.AdminAddr, admin.Handler(admin.NewMux(), cts.AdminToken), httpConfig(cts)))
+	}
+
+	if cts.MetricsAddr != "" {
+		lc.AddHttpServer("metrics", lifecycle.NewHttpServer(cts.MetricsAddr, admin.NewMetricsMux(), httpConfig(cts)))
+	}
+
+	if cts.AdminGrpcAddr != "" {
+		admin_listener, err := net.Listen("tcp", cts.AdminGrpcAddr)
+		if err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
 		}
-		level.Info(logger).Log("info", "grpc server started")
-	}()
+		lc.AddGrpcServer("admin_grpc", admin.NewGrpcServer(cts.AdminToken), admin_listener)
+	}
 
-	level.Error(logger).Log("exit: ", <-errs)
+	level.Error(logger).Log("exit: ", lc.Run())
 }
 
 type constants struct {
+	Environment string `env:"ENVIRONMENT" envDefault:"production"`
+
+	LogFormat string `env:"LOG_FORMAT" envDefault:"logfmt"`
+	LogLevel  string `env:"LOG_LEVEL" envDefault:"info"`
+
 	DbUser string `env:"DB_USER,required"`
 	DbPwd  string `env:"DB_PASSWORD,required"`
 	DbHost string `env:"DB_HOST,required"`
 	DbPort int    `env:"DB_PORT" envDefault:"3306"`
 	DbName string `env:"DB_NAME" envDefault:"grpc_user"`
+
+	DbMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
+	DbMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"5"`
+	DbConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"5m"`
+	DbConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"1m"`
+	DbSlowThreshold   time.Duration `env:"DB_SLOW_THRESHOLD" envDefault:"200ms"`
+
+	StartupAttempts   int           `env:"STARTUP_ATTEMPTS" envDefault:"10"`
+	StartupBackoff    time.Duration `env:"STARTUP_BACKOFF" envDefault:"500ms"`
+	StartupMaxBackoff time.Duration `env:"STARTUP_MAX_BACKOFF" envDefault:"10s"`
+	StartupTimeout    time.Duration `env:"STARTUP_TIMEOUT" envDefault:"2s"`
+
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+	ShutdownDelay   time.Duration `env:"SHUTDOWN_DELAY" envDefault:"0s"`
+
+	HealthInterval time.Duration `env:"HEALTH_INTERVAL" envDefault:"5s"`
+	HealthTimeout  time.Duration `env:"HEALTH_TIMEOUT" envDefault:"1s"`
+
+	AdminAddr     string `env:"ADMIN_ADDR" envDefault:"127.0.0.1:9090"`
+	AdminGrpcAddr string `env:"ADMIN_GRPC_ADDR" envDefault:"127.0.0.1:9091"`
+	AdminToken    string `env:"ADMIN_TOKEN"`
+	// MetricsAddr serves /metrics apart from the admin listener, which is
+	// on loopback by default
+	MetricsAddr string `env:"METRICS_ADDR" envDefault:":9100"`
+
+	// The admin and metrics listeners answer quickly; the timeouts only
+	// keep a slow client from holding their connections.
+	HttpReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s"`
+	HttpReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"10s"`
+	HttpWriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"30s"`
+	HttpIdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"120s"`
+	HttpMaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" envDefault:"65536"`
+
+	TraceExporter    string  `env:"TRACE_EXPORTER" envDefault:"none"`
+	TraceEndpoint    string  `env:"TRACE_ENDPOINT" envDefault:"localhost:4317"`
+	TraceInsecure    bool    `env:"TRACE_INSECURE" envDefault:"true"`
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO" envDefault:"1"`
+}
+
+func httpConfig(cts constants) lifecycle.HttpConfig {
+	return lifecycle.HttpConfig{
+		ReadHeaderTimeout: cts.HttpReadHeaderTimeout,
+		ReadTimeout:       cts.HttpReadTimeout,
+		WriteTimeout:      cts.HttpWriteTimeout,
+		IdleTimeout:       cts.HttpIdleTimeout,
+		MaxHeaderBytes:    cts.HttpMaxHeaderBytes,
+	}
+}
+
+func startup(cts constants) lifecycle.Startup {
+	return lifecycle.Startup{
+		Attempts:   cts.StartupAttempts,
+		Backoff:    cts.StartupBackoff,
+		MaxBackoff: cts.StartupMaxBackoff,
+		Timeout:    cts.StartupTimeout,
+	}
+}
+
+func tracingConfig(cts constants) tracing.Config {
+	return tracing.Config{
+		Exporter:    cts.TraceExporter,
+		Endpoint:    cts.TraceEndpoint,
+		Insecure:    cts.TraceInsecure,
+		SampleRatio: cts.TraceSampleRatio,
+	}
 }
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	ErrorTimeout    = "timeout"
+	ErrorConstraint = "constraint"
+	ErrorConnection = "connection"
+	ErrorCanceled   = "canceled"
+	ErrorOther      = "other"
+)
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// DB is the part of *sql.DB the repository runs its statements through.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type instrumentedDB struct {
+	next     DB
+	duration metrics.Histogram
+	errors   metrics.Counter
+	slow     time.Duration
+	logger   log.Logger
+}
+
+// NewInstrumentedDB records the duration of every statement by operation,
+// counts failed statements by error class and logs the statements slower
+// than slow. Queries are logged sanitized, without their arguments.
+func NewInstrumentedDB(next DB, duration metrics.Histogram, errors metrics.Counter, slow time.Duration, l log.Logger) DB {
+	return &instrumentedDB{
+		next:     next,
+		duration: duration,
+		errors:   errors,
+		slow:     slow,
+		logger:   log.With(l, "repository", "mysql"),
+	}
+}
+
+func (db *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	defer func(begin time.Time) {
+		db.observe(query, begin, err)
+	}(time.Now())
+	return db.next.ExecContext(ctx, query, args...)
+}
+
+func (db *instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	begin := time.Now()
+	row := db.next.QueryRowContext(ctx, query, args...)
+	db.observe(query, begin, row.Err())
+	return row
+}
+
+func (db *instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	defer func(begin time.Time) {
+		db.observe(query, begin, err)
+	}(time.Now())
+	return db.next.QueryContext(ctx, query, args...)
+}
+
+func (db *instrumentedDB) observe(query string, begin time.Time, err error) {
+	took := time.Since(begin)
+	sanitized := Sanitize(query)
+	operation := strings.ToLower(strings.SplitN(sanitized, " ", 2)[0])
+
+	db.duration.With("operation", operation).Observe(took.Seconds())
+
+	if err != nil && err != sql.ErrNoRows {
+		db.errors.With("operation", operation, "class", Classify(err)).Add(1)
+	}
+
+	if db.slow > 0 && took >= db.slow {
+		level.Warn(db.logger).Log("slow_query", sanitized, "took", took)
+	}
+}
+
+// Sanitize collapses whitespace and replaces literals with placeholders, so a
+// statement can be logged without the values it was built with.
+func Sanitize(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	query = numericLiteral.ReplaceAllString(query, "?")
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// Classify sorts a driver error into timeout, constraint, connection,
+// canceled or other.
+func Classify(err error) string {
+	var mysql_err *mysql.MySQLError
+	var net_err net.Error
+
+	switch {
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case stderrors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case stderrors.As(err, &mysql_err):
+		switch mysql_err.Number {
+		// lock wait timeout, statement execution time exceeded
+		case 1205, 3024:
+			return ErrorTimeout
+		// duplicate entry, foreign key, not null, data too long, check
+		case 1062, 1451, 1452, 1048, 1406, 3819:
+			return ErrorConstraint
+		// too many connections, server gone away, lost connection
+		case 1040, 2006, 2013:
+			return ErrorConnection
+		}
+		return ErrorOther
+	case stderrors.As(err, &net_err) && net_err.Timeout():
+		return ErrorTimeout
+	case stderrors.As(err, &net_err),
+		stderrors.Is(err, driver.ErrBadConn),
+		stderrors.Is(err, mysql.ErrInvalidConn),
+		stderrors.Is(err, sql.ErrConnDone):
+		return ErrorConnection
+	}
+	return ErrorOther
+}
@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/log"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mauricioww/user_microsrv/user_srv/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+type slowDB struct {
+	delay time.Duration
+	err   error
+}
+
+func (db slowDB) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	time.Sleep(db.delay)
+	return nil, db.err
+}
+
+func (db slowDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (db slowDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	time.Sleep(db.delay)
+	return nil, db.err
+}
+
+func TestSanitize(t *testing.T) {
+	query := `
+		SELECT u.email FROM USERS u
+			WHERE u.email = 'email@domain.com' AND u.age > 23 AND u.id = ?
+	`
+
+	assert.Equal(t, "SELECT u.email FROM USERS u WHERE u.email = ? AND u.age > ? AND u.id = ?", repository.Sanitize(query))
+}
+
+func TestClassify(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		err       error
+		class     string
+	}{
+		{
+			test_name: "context deadline",
+			err:       fmt.Errorf("exec: %w", context.DeadlineExceeded),
+			class:     repository.ErrorTimeout,
+		},
+		{
+			test_name: "lock wait timeout",
+			err:       &mysql.MySQLError{Number: 1205},
+			class:     repository.ErrorTimeout,
+		},
+		{
+			test_name: "duplicate entry",
+			err:       &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"},
+			class:     repository.ErrorConstraint,
+		},
+		{
+			test_name: "refused connection",
+			err:       &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			class:     repository.ErrorConnection,
+		},
+		{
+			test_name: "bad connection",
+			err:       driver.ErrBadConn,
+			class:     repository.ErrorConnection,
+		},
+		{
+			test_name: "canceled",
+			err:       context.Canceled,
+			class:     repository.ErrorCanceled,
+		},
+		{
+			test_name: "syntax error",
+			err:       &mysql.MySQLError{Number: 1064},
+			class:     repository.ErrorOther,
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			assert.Equal(t, tc.class, repository.Classify(tc.err))
+		})
+	}
+}
+
+func TestInstrumentedDB(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		delay     time.Duration
+		err       error
+		slow      bool
+	}{
+		{
+			test_name: "fast statement",
+		},
+		{
+			test_name: "slow statement",
+			delay:     20 * time.Millisecond,
+			slow:      true,
+		},
+		{
+			test_name: "failed statement",
+			err:       &mysql.MySQLError{Number: 1062},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			var buf bytes.Buffer
+			duration, errs := generic.NewHistogram("duration", 10), generic.NewCounter("errors")
+			db := repository.NewInstrumentedDB(slowDB{delay: tc.delay, err: tc.err}, duration, errs, 10*time.Millisecond, log.NewLogfmtLogger(&buf))
+
+			// act
+			db.ExecContext(context.Background(), "DELETE FROM USERS WHERE id = ?", 7)
+
+			// assert
+			if tc.slow {
+				assert.Contains(buf.String(), `slow_query="DELETE FROM USERS WHERE id = ?"`)
+			} else {
+				assert.Empty(buf.String())
+			}
+		})
+	}
+}
@@ -3,9 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/logging"
 	"github.com/mauricioww/user_microsrv/user_srv/entities"
 )
 
@@ -32,31 +36,45 @@ const (
 	delete_user_sql = `
 		DELETE FROM USERS WHERE id = ?
 	`
+
+	search_users_sql = `
+		SELECT u.id, u.email, u.age FROM USERS u
+			WHERE u.email LIKE ? ORDER BY u.id LIMIT ?
+	`
+
+	get_users_sql = `
+		SELECT u.id, u.email, u.age FROM USERS u
+			WHERE u.id IN (%s) ORDER BY u.id
+	`
 )
 
+var like_escaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user entities.User) (int, error)
 	Authenticate(ctx context.Context, session *entities.Session) (string, error)
 	UpdateUser(ctx context.Context, information entities.Update) (entities.User, error)
 	GetUser(ctx context.Context, id int) (entities.User, error)
 	DeleteUser(ctx context.Context, id int) (bool, error)
+	SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error)
+	GetUsers(ctx context.Context, ids []int) ([]entities.Account, error)
 }
 
 type userRepository struct {
-	db     *sql.DB
+	db     tracedDB
 	logger log.Logger
 }
 
-func NewUserRepository(mysql_db *sql.DB, l log.Logger) UserRepository {
+func NewUserRepository(mysql_db DB, l log.Logger) UserRepository {
 	return &userRepository{
-		db:     mysql_db,
+		db:     tracedDB{db: mysql_db},
 		logger: log.With(l, "repository", "mysql"),
 	}
 }
 
 func (r *userRepository) CreateUser(ctx context.Context, user entities.User) (int, error) {
 	if id, err := r.db.ExecContext(ctx, create_user_sql, user.Email, user.Password, user.Age); err != nil {
-		return -1, errors.NewInternalError()
+		return -1, r.internal(ctx, "create_user", err)
 	} else {
 		n, _ := id.LastInsertId()
 		return int(n), nil
@@ -66,10 +84,10 @@ func (r *userRepository) CreateUser(ctx context.Context, user entities.User) (in
 func (r *userRepository) Authenticate(ctx context.Context, session *entities.Session) (string, error) {
 	var hash string
 
-	if err := r.db.QueryRow(authenticate_sql, session.Email).Scan(&session.Id, &hash); err == sql.ErrNoRows {
+	if err := r.db.QueryRowContext(ctx, authenticate_sql, session.Email).Scan(&session.Id, &hash); err == sql.ErrNoRows {
 		return "", errors.NewUserNotFoundError()
 	} else if err != nil {
-		return "", errors.NewInternalError()
+		return "", r.internal(ctx, "authenticate", err)
 	}
 
 	return hash, nil
@@ -78,23 +96,23 @@ func (r *userRepository) Authenticate(ctx context.Context, session *entities.Ses
 func (r *userRepository) UpdateUser(ctx context.Context, update entities.Update) (entities.User, error) {
 	var u entities.User
 
-	if err := r.db.QueryRow(get_user_by_id, update.UserId).Scan(); err == sql.ErrNoRows {
+	if err := r.db.QueryRowContext(ctx, get_user_by_id, update.UserId).Scan(); err == sql.ErrNoRows {
 		return u, errors.NewUserNotFoundError()
 	} else if _, err := r.db.ExecContext(ctx, update_user_sql, update.Email, update.Password, update.Age, update.UserId); err != nil {
-		return u, errors.NewInternalError()
+		return u, r.internal(ctx, "update_user", err)
 	}
 
-	_ = r.db.QueryRow(get_user_by_id, update.UserId).Scan(&u.Email, &u.Password, &u.Age)
+	_ = r.db.QueryRowContext(ctx, get_user_by_id, update.UserId).Scan(&u.Email, &u.Password, &u.Age)
 	return u, nil
 }
 
 func (r *userRepository) GetUser(ctx context.Context, id int) (entities.User, error) {
 	var u entities.User
 
-	if err := r.db.QueryRow(get_user_by_id, id).Scan(&u.Email, &u.Password, &u.Age); err == sql.ErrNoRows {
+	if err := r.db.QueryRowContext(ctx, get_user_by_id, id).Scan(&u.Email, &u.Password, &u.Age); err == sql.ErrNoRows {
 		return entities.User{}, errors.NewUserNotFoundError()
 	} else if err != nil {
-		return entities.User{}, errors.NewInternalError()
+		return entities.User{}, r.internal(ctx, "get_user", err)
 	}
 
 	return u, nil
@@ -102,11 +120,76 @@ func (r *userRepository) GetUser(ctx context.Context, id int) (entities.User, er
 
 func (r *userRepository) DeleteUser(ctx context.Context, id int) (bool, error) {
 
-	if err := r.db.QueryRow(get_user_by_id, id).Scan(); err == sql.ErrNoRows {
+	if err := r.db.QueryRowContext(ctx, get_user_by_id, id).Scan(); err == sql.ErrNoRows {
 		return false, errors.NewUserNotFoundError()
 	} else if _, err := r.db.ExecContext(ctx, delete_user_sql, id); err != nil {
-		return false, errors.NewInternalError()
+		return false, r.internal(ctx, "delete_user", err)
 	}
 
 	return true, nil
 }
+
+// SearchUsers returns up to limit accounts whose email contains email, in
+// creation order.
+func (r *userRepository) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	rows, err := r.db.QueryContext(ctx, search_users_sql, "%"+like_escaper.Replace(email)+"%", limit)
+	if err != nil {
+		return nil, r.internal(ctx, "search_users", err)
+	}
+	defer rows.Close()
+
+	res := []entities.Account{}
+	for rows.Next() {
+		var a entities.Account
+		if err := rows.Scan(&a.Id, &a.Email, &a.Age); err != nil {
+			return nil, r.internal(ctx, "search_users", err)
+		}
+		res = append(res, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, r.internal(ctx, "search_users", err)
+	}
+
+	return res, nil
+}
+
+// GetUsers returns the accounts of those ids that exist, by id. No ids is
+// no query.
+func (r *userRepository) GetUsers(ctx context.Context, ids []int) ([]entities.Account, error) {
+	res := []entities.Account{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(get_users_sql, placeholders), args...)
+	if err != nil {
+		return nil, r.internal(ctx, "get_users", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a entities.Account
+		if err := rows.Scan(&a.Id, &a.Email, &a.Age); err != nil {
+			return nil, r.internal(ctx, "get_users", err)
+		}
+		res = append(res, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, r.internal(ctx, "get_users", err)
+	}
+
+	return res, nil
+}
+
+// internal logs the cause of a failed statement before hiding it behind the
+// generic internal error returned to callers.
+func (r *userRepository) internal(ctx context.Context, method string, err error) error {
+	level.Error(r.logger).Log("request_id", logging.RequestID(ctx), "method", method, "class", Classify(err), "err", err)
+	return errors.NewInternalError().Wrap(err)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/user_srv/entities"
+	"google.golang.org/grpc/codes"
+)
+
+type Middleware func(GrpcUserService) GrpcUserService
+
+type Metrics struct {
+	Requests     metrics.Counter
+	Errors       metrics.Counter
+	Latency      metrics.Histogram
+	Signups      metrics.Counter
+	FailedLogins metrics.Counter
+}
+
+type instrumentingMiddleware struct {
+	metrics Metrics
+	next    GrpcUserService
+}
+
+func InstrumentingMiddleware(m Metrics) Middleware {
+	return func(next GrpcUserService) GrpcUserService {
+		return &instrumentingMiddleware{metrics: m, next: next}
+	}
+}
+
+func (mw *instrumentingMiddleware) observe(method string, begin time.Time, err error) {
+	mw.metrics.Requests.With("method", method).Add(1)
+	if err != nil {
+		mw.metrics.Errors.With("method", method, "code", errors.Code(err).String()).Add(1)
+	}
+	mw.metrics.Latency.With("method", method).Observe(time.Since(begin).Seconds())
+}
+
+func (mw *instrumentingMiddleware) CreateUser(ctx context.Context, email string, pwd string, age int) (res int, err error) {
+	defer func(begin time.Time) {
+		mw.observe("create_user", begin, err)
+		if err == nil {
+			mw.metrics.Signups.Add(1)
+		}
+	}(time.Now())
+	return mw.next.CreateUser(ctx, email, pwd, age)
+}
+
+func (mw *instrumentingMiddleware) Authenticate(ctx context.Context, email string, pwd string) (res int, err error) {
+	defer func(begin time.Time) {
+		mw.observe("authenticate", begin, err)
+		if c := errors.Code(err); c == codes.Unauthenticated || c == codes.NotFound {
+			mw.metrics.FailedLogins.Add(1)
+		}
+	}(time.Now())
+	return mw.next.Authenticate(ctx, email, pwd)
+}
+
+func (mw *instrumentingMiddleware) UpdateUser(ctx context.Context, id int, email string, pwd string, age int) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("update_user", begin, err)
+	}(time.Now())
+	return mw.next.UpdateUser(ctx, id, email, pwd, age)
+}
+
+func (mw *instrumentingMiddleware) GetUser(ctx context.Context, id int) (res entities.User, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_user", begin, err)
+	}(time.Now())
+	return mw.next.GetUser(ctx, id)
+}
+
+func (mw *instrumentingMiddleware) DeleteUser(ctx context.Context, id int) (res bool, err error) {
+	defer func(begin time.Time) {
+		mw.observe("delete_user", begin, err)
+	}(time.Now())
+	return mw.next.DeleteUser(ctx, id)
+}
+
+func (mw *instrumentingMiddleware) SearchUsers(ctx context.Context, email string, limit int) (res []entities.Account, err error) {
+	defer func(begin time.Time) {
+		mw.observe("search_users", begin, err)
+	}(time.Now())
+	return mw.next.SearchUsers(ctx, email, limit)
+}
+
+func (mw *instrumentingMiddleware) GetUsers(ctx context.Context, ids []int) (res []entities.Account, err error) {
+	defer func(begin time.Time) {
+		mw.observe("get_users", begin, err)
+	}(time.Now())
+	return mw.next.GetUsers(ctx, ids)
+}
@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/user_srv/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// labelCounter records the label values of every increment; generic.Counter
+// does not share its value with the counters returned by With.
+type labelCounter struct {
+	mtx    *sync.Mutex
+	lvs    []string
+	values *[][]string
+}
+
+func newLabelCounter() *labelCounter {
+	return &labelCounter{mtx: &sync.Mutex{}, values: &[][]string{}}
+}
+
+func (c *labelCounter) With(lvs ...string) metrics.Counter {
+	return &labelCounter{mtx: c.mtx, lvs: append(append([]string{}, c.lvs...), lvs...), values: c.values}
+}
+
+func (c *labelCounter) Add(float64) {
+	c.mtx.Lock()
+	*c.values = append(*c.values, c.lvs)
+	c.mtx.Unlock()
+}
+
+func TestInstrumentingMiddleware(t *testing.T) {
+	user_repo_mock := new(service.UserRepositoryMock)
+	requests, errs := newLabelCounter(), newLabelCounter()
+	signups, failed_logins := generic.NewCounter("signups"), generic.NewCounter("failed_logins")
+
+	grpc_user_srv := service.InstrumentingMiddleware(service.Metrics{
+		Requests:     requests,
+		Errors:       errs,
+		Latency:      generic.NewHistogram("latency", 10),
+		Signups:      signups,
+		FailedLogins: failed_logins,
+	})(service.NewGrpcUserService(user_repo_mock, service.InitLogger()))
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	user_repo_mock.On("CreateUser", mock.Anything, mock.Anything).Return(1, nil)
+	user_repo_mock.On("Authenticate", mock.Anything, mock.Anything).Return("", errors.NewUserNotFoundError())
+
+	// act
+	grpc_user_srv.CreateUser(ctx, "email@domain.com", "qwerty", 23)
+	grpc_user_srv.CreateUser(ctx, "", "qwerty", 23)
+	grpc_user_srv.Authenticate(ctx, "email@domain.com", "qwerty")
+
+	// assert
+	assert.Len(*requests.values, 3)
+	assert.Equal([][]string{
+		{"method", "create_user", "code", "FailedPrecondition"},
+		{"method", "authenticate", "code", "NotFound"},
+	}, *errs.values)
+	assert.Equal(float64(1), signups.Value())
+	assert.Equal(float64(1), failed_logins.Value())
+}
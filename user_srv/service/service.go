@@ -7,6 +7,7 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/helpers"
+	"github.com/mauricioww/user_microsrv/logging"
 	"github.com/mauricioww/user_microsrv/user_srv/entities"
 	"github.com/mauricioww/user_microsrv/user_srv/repository"
 )
@@ -17,8 +18,17 @@ type GrpcUserService interface {
 	UpdateUser(ctx context.Context, id int, email string, pwd string, age int) (bool, error)
 	GetUser(ctx context.Context, id int) (entities.User, error)
 	DeleteUser(ctx context.Context, id int) (bool, error)
+	SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error)
+	GetUsers(ctx context.Context, ids []int) ([]entities.Account, error)
 }
 
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	// MaxBatch bounds the ids of a GetUsers call.
+	MaxBatch = 100
+)
+
 type grpcUserService struct {
 	repository repository.UserRepository
 	logger     log.Logger
@@ -32,7 +42,7 @@ func NewGrpcUserService(r repository.UserRepository, l log.Logger) GrpcUserServi
 }
 
 func (g *grpcUserService) CreateUser(ctx context.Context, email string, pwd string, age int) (int, error) {
-	logger := log.With(g.logger, "method", "create_user")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "create_user")
 
 	if email == "" {
 		e := errors.NewBadRequestEmailError()
@@ -64,7 +74,7 @@ func (g *grpcUserService) CreateUser(ctx context.Context, email string, pwd stri
 }
 
 func (g *grpcUserService) Authenticate(ctx context.Context, email string, pwd string) (int, error) {
-	logger := log.With(g.logger, "method", "authenticate")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "authenticate")
 
 	if email == "" {
 		e := errors.NewBadRequestEmailError()
@@ -97,7 +107,7 @@ func (g *grpcUserService) Authenticate(ctx context.Context, email string, pwd st
 }
 
 func (g *grpcUserService) UpdateUser(ctx context.Context, id int, email string, pwd string, age int) (bool, error) {
-	logger := log.With(g.logger, "method", "update_user")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "update_user")
 
 	if email == "" {
 		e := errors.NewBadRequestEmailError()
@@ -134,7 +144,7 @@ func (g *grpcUserService) UpdateUser(ctx context.Context, id int, email string,
 }
 
 func (g *grpcUserService) GetUser(ctx context.Context, id int) (entities.User, error) {
-	logger := log.With(g.logger, "method", "get_user")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "get_user")
 
 	res, err := g.repository.GetUser(ctx, id)
 
@@ -149,7 +159,7 @@ func (g *grpcUserService) GetUser(ctx context.Context, id int) (entities.User, e
 }
 
 func (g *grpcUserService) DeleteUser(ctx context.Context, id int) (bool, error) {
-	logger := log.With(g.logger, "method", "delete_user")
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "delete_user")
 
 	res, err := g.repository.DeleteUser(ctx, id)
 
@@ -161,3 +171,41 @@ func (g *grpcUserService) DeleteUser(ctx context.Context, id int) (bool, error)
 
 	return res, err
 }
+
+// SearchUsers looks accounts up by a fragment of their email. A limit of 0
+// means DefaultSearchLimit and none goes above MaxSearchLimit.
+func (g *grpcUserService) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "search_users")
+
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	} else if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	res, err := g.repository.SearchUsers(ctx, email, limit)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR", err)
+	} else {
+		logger.Log("action", "success", "results", len(res))
+	}
+
+	return res, err
+}
+
+// GetUsers looks up to MaxBatch accounts up at once; ids with no account
+// are left out of the result.
+func (g *grpcUserService) GetUsers(ctx context.Context, ids []int) ([]entities.Account, error) {
+	logger := log.With(g.logger, "request_id", logging.RequestID(ctx), "method", "get_users")
+
+	res, err := g.repository.GetUsers(ctx, ids)
+
+	if err != nil {
+		level.Error(logger).Log("ERROR", err)
+	} else {
+		logger.Log("action", "success", "requested", len(ids), "results", len(res))
+	}
+
+	return res, err
+}
@@ -43,6 +43,18 @@ func (r *UserRepositoryMock) DeleteUser(ctx context.Context, id int) (bool, erro
 	return args.Bool(0), args.Error(1)
 }
 
+func (r *UserRepositoryMock) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	args := r.Called(ctx, email, limit)
+
+	return args.Get(0).([]entities.Account), args.Error(1)
+}
+
+func (r *UserRepositoryMock) GetUsers(ctx context.Context, ids []int) ([]entities.Account, error) {
+	args := r.Called(ctx, ids)
+
+	return args.Get(0).([]entities.Account), args.Error(1)
+}
+
 func InitLogger() log.Logger {
 	var logger log.Logger
 	{
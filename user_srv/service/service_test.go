@@ -314,3 +314,109 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchUsers(t *testing.T) {
+	var grpc_user_srv service.GrpcUserService
+
+	user_repo_mock := new(service.UserRepositoryMock)
+	grpc_user_srv = service.NewGrpcUserService(user_repo_mock, service.InitLogger())
+
+	test_cases := []struct {
+		test_name  string
+		email      string
+		limit      int
+		repo_limit int
+		res        []entities.Account
+		err        error
+	}{
+		{
+			test_name:  "search with limit",
+			email:      "domain.com",
+			limit:      5,
+			repo_limit: 5,
+			res:        []entities.Account{{Id: 1, Email: "email@domain.com", Age: 23}},
+		},
+		{
+			test_name:  "default limit",
+			email:      "example",
+			repo_limit: service.DefaultSearchLimit,
+			res:        []entities.Account{},
+		},
+		{
+			test_name:  "limit capped",
+			email:      "other",
+			limit:      1000,
+			repo_limit: service.MaxSearchLimit,
+			res:        []entities.Account{},
+		},
+		{
+			test_name:  "repository error",
+			email:      "broken",
+			limit:      5,
+			repo_limit: 5,
+			err:        errors.NewInternalError(),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			// act
+			user_repo_mock.On("SearchUsers", ctx, tc.email, tc.repo_limit).Return(tc.res, tc.err)
+			res, err := grpc_user_srv.SearchUsers(ctx, tc.email, tc.limit)
+
+			// assert
+			assert.Equal(tc.res, res)
+			assert.Equal(tc.err, err)
+		})
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	var grpc_user_srv service.GrpcUserService
+
+	user_repo_mock := new(service.UserRepositoryMock)
+	grpc_user_srv = service.NewGrpcUserService(user_repo_mock, service.InitLogger())
+
+	test_cases := []struct {
+		test_name string
+		ids       []int
+		res       []entities.Account
+		err       error
+	}{
+		{
+			test_name: "some found",
+			ids:       []int{1, 2, 3},
+			res:       []entities.Account{{Id: 1, Email: "email@domain.com", Age: 23}, {Id: 3, Email: "other@domain.com", Age: 32}},
+		},
+		{
+			test_name: "none found",
+			ids:       []int{4},
+			res:       []entities.Account{},
+		},
+		{
+			test_name: "repository error",
+			ids:       []int{5},
+			err:       errors.NewInternalError(),
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			ctx := context.Background()
+			assert := assert.New(t)
+
+			// act
+			user_repo_mock.On("GetUsers", ctx, tc.ids).Return(tc.res, tc.err)
+			res, err := grpc_user_srv.GetUsers(ctx, tc.ids)
+
+			// assert
+			assert.Equal(tc.res, res)
+			assert.Equal(tc.err, err)
+		})
+	}
+}
@@ -26,4 +26,13 @@ type (
 	DeleteUserRequest struct {
 		UserId int
 	}
+
+	SearchUsersRequest struct {
+		Email string
+		Limit int
+	}
+
+	GetUsersRequest struct {
+		Ids []int
+	}
 )
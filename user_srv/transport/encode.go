@@ -1,5 +1,7 @@
 package transport
 
+import "github.com/mauricioww/user_microsrv/user_srv/entities"
+
 type (
 	CreateUserResponse struct {
 		Id int
@@ -22,4 +24,12 @@ type (
 	DeleteUserResponse struct {
 		Success bool
 	}
+
+	SearchUsersResponse struct {
+		Users []entities.Account
+	}
+
+	GetUsersResponse struct {
+		Users []entities.Account
+	}
 )
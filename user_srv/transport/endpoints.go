@@ -13,6 +13,8 @@ type GrpcUserServiceEndpoints struct {
 	UpdateUser   endpoint.Endpoint
 	GetUser      endpoint.Endpoint
 	DeleteUser   endpoint.Endpoint
+	SearchUsers  endpoint.Endpoint
+	GetUsers     endpoint.Endpoint
 }
 
 func MakeGrpcUserServiceEndpoints(grpc_user_srv service.GrpcUserService) GrpcUserServiceEndpoints {
@@ -22,6 +24,8 @@ func MakeGrpcUserServiceEndpoints(grpc_user_srv service.GrpcUserService) GrpcUse
 		UpdateUser:   makeUpdateUserEndpoint(grpc_user_srv),
 		GetUser:      makeGetUserEndpoint(grpc_user_srv),
 		DeleteUser:   makeDeleteUserEndpoint(grpc_user_srv),
+		SearchUsers:  makeSearchUsersEndpoint(grpc_user_srv),
+		GetUsers:     makeGetUsersEndpoint(grpc_user_srv),
 	}
 }
 
@@ -64,3 +68,19 @@ func makeDeleteUserEndpoint(srv service.GrpcUserService) endpoint.Endpoint {
 		return DeleteUserResponse{Success: res}, err
 	}
 }
+
+func makeSearchUsersEndpoint(srv service.GrpcUserService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req, _ := request.(SearchUsersRequest)
+		res, err := srv.SearchUsers(ctx, req.Email, req.Limit)
+		return SearchUsersResponse{Users: res}, err
+	}
+}
+
+func makeGetUsersEndpoint(srv service.GrpcUserService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req, _ := request.(GetUsersRequest)
+		res, err := srv.GetUsers(ctx, req.Ids)
+		return GetUsersResponse{Users: res}, err
+	}
+}
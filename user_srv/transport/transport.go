@@ -6,8 +6,9 @@ import (
 
 	grpc_gokit "github.com/go-kit/kit/transport/grpc"
 	grpc_err "github.com/mauricioww/user_microsrv/errors"
-	"google.golang.org/grpc/status"
+	"github.com/mauricioww/user_microsrv/validation"
 
+	"github.com/mauricioww/user_microsrv/user_srv/service"
 	"github.com/mauricioww/user_microsrv/user_srv/userpb"
 )
 
@@ -17,6 +18,8 @@ type gRPCServer struct {
 	updateUser   grpc_gokit.Handler
 	getUser      grpc_gokit.Handler
 	deleteUser   grpc_gokit.Handler
+	searchUsers  grpc_gokit.Handler
+	getUsers     grpc_gokit.Handler
 
 	userpb.UnimplementedUserServiceServer
 }
@@ -52,6 +55,18 @@ func NewGrpcUserServer(grpc_endpoints GrpcUserServiceEndpoints) userpb.UserServi
 			decodeDeleteUserRequest,
 			encondeDeleteUserResponse,
 		),
+
+		searchUsers: grpc_gokit.NewServer(
+			grpc_endpoints.SearchUsers,
+			decodeSearchUsersRequest,
+			encodeSearchUsersResponse,
+		),
+
+		getUsers: grpc_gokit.NewServer(
+			grpc_endpoints.GetUsers,
+			decodeGetUsersRequest,
+			encodeGetUsersResponse,
+		),
 	}
 }
 
@@ -68,7 +83,11 @@ func decodeCreateUserRequest(_ context.Context, request interface{}) (interface{
 		Age:      int(create_pb.GetAge()),
 	}
 
-	return req, nil
+	return req, validation.Validate(
+		validation.Check("email", req.Email, validation.Required, validation.Email),
+		validation.Check("password", req.Password, validation.Required, validation.Password),
+		validation.Check("age", req.Age, validation.Required, validation.Age),
+	)
 }
 
 func encodeCreateUserResponse(_ context.Context, response interface{}) (interface{}, error) {
@@ -88,7 +107,11 @@ func decodeAuthenticateRequest(_ context.Context, request interface{}) (interfac
 		Password: auth_pb.GetPassword(),
 	}
 
-	return req, nil
+	// no password policy here: accounts created before it must still log in
+	return req, validation.Validate(
+		validation.Check("email", req.Email, validation.Required),
+		validation.Check("password", req.Password, validation.Required),
+	)
 }
 
 func encodeAuthenticateResponse(_ context.Context, response interface{}) (interface{}, error) {
@@ -111,7 +134,11 @@ func decodeUpdateUserRequest(_ context.Context, request interface{}) (interface{
 		Age:      int(update_pb.GetAge()),
 	}
 
-	return req, nil
+	return req, validation.Validate(
+		validation.Check("email", req.Email, validation.Required, validation.Email),
+		validation.Check("password", req.Password, validation.Required, validation.Password),
+		validation.Check("age", req.Age, validation.Required, validation.Age),
+	)
 }
 
 func encondeUpdateUserResponse(_ context.Context, response interface{}) (interface{}, error) {
@@ -164,12 +191,67 @@ func encondeDeleteUserResponse(_ context.Context, response interface{}) (interfa
 	return &userpb.DeleteUserResponse{Success: res.Success}, nil
 }
 
+func decodeSearchUsersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	search_pb, ok := request.(*userpb.SearchUsersRequest)
+
+	if !ok {
+		return nil, errors.New("No proto message 'SearchUsersRequest'")
+	}
+
+	req := SearchUsersRequest{
+		Email: search_pb.GetEmail(),
+		Limit: int(search_pb.GetLimit()),
+	}
+
+	return req, validation.Validate(
+		validation.Check("email", req.Email, validation.Required, validation.MinLength(validation.MinSearchLength), validation.MaxLength(validation.MaxEmailLength)),
+	)
+}
+
+func encodeSearchUsersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(SearchUsersResponse)
+	users := make([]*userpb.UserSummary, 0, len(res.Users))
+
+	for _, u := range res.Users {
+		users = append(users, &userpb.UserSummary{Id: int32(u.Id), Email: u.Email, Age: uint32(u.Age)})
+	}
+
+	return &userpb.SearchUsersResponse{Users: users}, nil
+}
+
+func decodeGetUsersRequest(_ context.Context, request interface{}) (interface{}, error) {
+	users_pb, ok := request.(*userpb.GetUsersRequest)
+
+	if !ok {
+		return nil, errors.New("No proto message 'GetUsersRequest'")
+	}
+
+	req := GetUsersRequest{Ids: make([]int, len(users_pb.GetIds()))}
+	for i, id := range users_pb.GetIds() {
+		req.Ids[i] = int(id)
+	}
+
+	return req, validation.Validate(
+		validation.Check("ids", len(req.Ids), validation.MaxItems(service.MaxBatch)),
+	)
+}
+
+func encodeGetUsersResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(GetUsersResponse)
+	users := make([]*userpb.UserSummary, 0, len(res.Users))
+
+	for _, u := range res.Users {
+		users = append(users, &userpb.UserSummary{Id: int32(u.Id), Email: u.Email, Age: uint32(u.Age)})
+	}
+
+	return &userpb.GetUsersResponse{Users: users}, nil
+}
+
 func (g *gRPCServer) CreateUser(ctx context.Context, req *userpb.CreateUserRequest) (*userpb.CreateUserResponse, error) {
 	_, res, err := g.createUser.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*userpb.CreateUserResponse), err
@@ -179,8 +261,7 @@ func (g *gRPCServer) Authenticate(ctx context.Context, req *userpb.AuthenticateR
 	_, res, err := g.authenticate.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*userpb.AuthenticateResponse), nil
@@ -190,8 +271,7 @@ func (g *gRPCServer) UpdateUser(ctx context.Context, req *userpb.UpdateUserReque
 	_, res, err := g.updateUser.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*userpb.UpdateUserResponse), nil
@@ -201,8 +281,7 @@ func (g *gRPCServer) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*
 	_, res, err := g.getUser.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*userpb.GetUserResponse), nil
@@ -212,9 +291,28 @@ func (g *gRPCServer) DeleteUser(ctx context.Context, req *userpb.DeleteUserReque
 	_, res, err := g.deleteUser.ServeGRPC(ctx, req)
 
 	if err != nil {
-		e, _ := err.(grpc_err.ErrorResolver)
-		return nil, status.Error(e.GrpcCode(), err.Error())
+		return nil, grpc_err.Translate(err)
 	}
 
 	return res.(*userpb.DeleteUserResponse), nil
 }
+
+func (g *gRPCServer) SearchUsers(ctx context.Context, req *userpb.SearchUsersRequest) (*userpb.SearchUsersResponse, error) {
+	_, res, err := g.searchUsers.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, grpc_err.Translate(err)
+	}
+
+	return res.(*userpb.SearchUsersResponse), nil
+}
+
+func (g *gRPCServer) GetUsers(ctx context.Context, req *userpb.GetUsersRequest) (*userpb.GetUsersResponse, error) {
+	_, res, err := g.getUsers.ServeGRPC(ctx, req)
+
+	if err != nil {
+		return nil, grpc_err.Translate(err)
+	}
+
+	return res.(*userpb.GetUsersResponse), nil
+}
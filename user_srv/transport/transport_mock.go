@@ -5,6 +5,7 @@ import (
 
 	"github.com/mauricioww/user_microsrv/user_srv/entities"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/status"
 )
 
 type GrpcUserSrvMock struct {
@@ -40,3 +41,21 @@ func (s *GrpcUserSrvMock) DeleteUser(ctx context.Context, id int) (bool, error)
 
 	return args.Bool(0), args.Error(1)
 }
+
+func (s *GrpcUserSrvMock) SearchUsers(ctx context.Context, email string, limit int) ([]entities.Account, error) {
+	args := s.Called(ctx, email, limit)
+
+	return args.Get(0).([]entities.Account), args.Error(1)
+}
+
+func (s *GrpcUserSrvMock) GetUsers(ctx context.Context, ids []int) ([]entities.Account, error) {
+	args := s.Called(ctx, ids)
+
+	return args.Get(0).([]entities.Account), args.Error(1)
+}
+
+func TestErrors(err1 error, err2 error) bool {
+	e1 := status.Convert(err1)
+	e2 := status.Convert(err2)
+	return (e1.Code() == e2.Code()) && (e1.Message() == e2.Message())
+}
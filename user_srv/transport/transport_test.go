@@ -2,13 +2,16 @@ package transport_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/mauricioww/user_microsrv/errors"
 	"github.com/mauricioww/user_microsrv/user_srv/entities"
+	"github.com/mauricioww/user_microsrv/user_srv/service"
 	"github.com/mauricioww/user_microsrv/user_srv/transport"
 	"github.com/mauricioww/user_microsrv/user_srv/userpb"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -29,7 +32,7 @@ func TestCreateUser(t *testing.T) {
 			test_name: "user created successfully",
 			user_req: &userpb.CreateUserRequest{
 				Email:    "success@email.com",
-				Password: "qwerty",
+				Password: "qwerty123",
 				Age:      23,
 			},
 			srv_res: 0,
@@ -42,16 +45,30 @@ func TestCreateUser(t *testing.T) {
 				Age:   23,
 			},
 			srv_res: -1,
-			srv_err: errors.NewBadRequestPasswordError(),
+			srv_err: errors.NewInvalidArgumentError(errors.FieldViolation{Field: "password", Description: "is required"}),
 		},
 		{
 			test_name: "no email error",
 			user_req: &userpb.CreateUserRequest{
-				Password: "qwerty",
+				Password: "qwerty123",
 				Age:      23,
 			},
 			srv_res: -1,
-			srv_err: errors.NewBadRequestEmailError(),
+			srv_err: errors.NewInvalidArgumentError(errors.FieldViolation{Field: "email", Description: "is required"}),
+		},
+		{
+			test_name: "invalid fields error",
+			user_req: &userpb.CreateUserRequest{
+				Email:    "not-an-email",
+				Password: "short",
+				Age:      200,
+			},
+			srv_res: -1,
+			srv_err: errors.NewInvalidArgumentError(
+				errors.FieldViolation{Field: "email", Description: "must be a valid email address"},
+				errors.FieldViolation{Field: "password", Description: "must be between 8 and 72 characters"},
+				errors.FieldViolation{Field: "age", Description: "must be between 1 and 150"},
+			),
 		},
 	}
 	for _, tc := range test_cases {
@@ -73,7 +90,10 @@ func TestCreateUser(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.user_res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
+			if tc.srv_err != nil {
+				assert.Equal(errors.FromError(tc.srv_err).Violations, errors.FromError(err).Violations)
+			}
 		})
 	}
 }
@@ -105,7 +125,7 @@ func TestAuthenticate(t *testing.T) {
 				Email: "user@email.com",
 			},
 			srv_res: -1,
-			srv_err: errors.NewBadRequestPasswordError(),
+			srv_err: errors.NewInvalidArgumentError(errors.FieldViolation{Field: "password", Description: "is required"}),
 		},
 		{
 			test_name: "no email error",
@@ -113,7 +133,7 @@ func TestAuthenticate(t *testing.T) {
 				Password: "invalid_password",
 			},
 			srv_res: -1,
-			srv_err: errors.NewBadRequestEmailError(),
+			srv_err: errors.NewInvalidArgumentError(errors.FieldViolation{Field: "email", Description: "is required"}),
 		},
 		{
 			test_name: "invalid password error",
@@ -146,7 +166,7 @@ func TestAuthenticate(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
 		})
 	}
 }
@@ -169,7 +189,7 @@ func TestUpdateUser(t *testing.T) {
 			data: &userpb.UpdateUserRequest{
 				Id:       1,
 				Email:    "new_email@domain.com",
-				Password: "new_password",
+				Password: "new_password1",
 				Age:      25,
 			},
 			srv_res: true,
@@ -182,23 +202,24 @@ func TestUpdateUser(t *testing.T) {
 				Email: "new_email@domain.com",
 				Age:   25,
 			},
-			srv_err: errors.NewBadRequestPasswordError(),
+			srv_err: errors.NewInvalidArgumentError(errors.FieldViolation{Field: "password", Description: "is required"}),
 		},
 		{
 			test_name: "no email error",
 			data: &userpb.UpdateUserRequest{
 				Id:       1,
-				Password: "new_password",
+				Password: "new_password1",
 				Age:      25,
 			},
-			srv_err: errors.NewBadRequestEmailError(),
+			srv_err: errors.NewInvalidArgumentError(errors.FieldViolation{Field: "email", Description: "is required"}),
 		},
 		{
 			test_name: "user not found error",
 			data: &userpb.UpdateUserRequest{
-				Id:       1,
+				Id:       2,
 				Email:    "new_email@domain.com",
-				Password: "new_password",
+				Password: "new_password1",
+				Age:      25,
 			},
 			srv_err: errors.NewUserNotFoundError(),
 		},
@@ -223,7 +244,7 @@ func TestUpdateUser(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
 		})
 	}
 }
@@ -285,7 +306,7 @@ func TestGetUser(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
 		})
 	}
 }
@@ -339,7 +360,123 @@ func TestDeleteUser(t *testing.T) {
 
 			// assert
 			assert.Equal(tc.res, res)
-			assert.Equal(tc.err, err)
+			assert.True(transport.TestErrors(err, tc.err))
+		})
+	}
+}
+
+func TestErrorTranslation(t *testing.T) {
+	mock_srv := new(transport.GrpcUserSrvMock)
+	endpoints := transport.MakeGrpcUserServiceEndpoints(mock_srv)
+	grpc_service := transport.NewGrpcUserServer(endpoints)
+
+	test_cases := []struct {
+		test_name string
+		user_id   int
+		srv_err   error
+		code      codes.Code
+		msg       string
+	}{
+		{
+			test_name: "typed error",
+			user_id:   1,
+			srv_err:   errors.NewUserNotFoundError(),
+			code:      codes.NotFound,
+			msg:       "User not found",
+		},
+		{
+			test_name: "unknown error",
+			user_id:   2,
+			srv_err:   fmt.Errorf("dial tcp 10.0.0.3:3306: connection refused"),
+			code:      codes.Internal,
+			msg:       "Internal server error",
+		},
+		{
+			test_name: "deadline exceeded",
+			user_id:   3,
+			srv_err:   errors.NewInternalError().Wrap(context.DeadlineExceeded),
+			code:      codes.DeadlineExceeded,
+			msg:       "Request timed out",
+		},
+		{
+			test_name: "canceled",
+			user_id:   4,
+			srv_err:   fmt.Errorf("get user: %w", context.Canceled),
+			code:      codes.Canceled,
+			msg:       "Request canceled",
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+			ctx := context.Background()
+
+			// act
+			mock_srv.On("GetUser", ctx, tc.user_id).Return(entities.User{}, tc.srv_err)
+			res, err := grpc_service.GetUser(ctx, &userpb.GetUserRequest{Id: uint32(tc.user_id)})
+
+			// assert
+			assert.Nil(res)
+			assert.Equal(tc.code, status.Code(err))
+			assert.Equal(tc.msg, status.Convert(err).Message())
 		})
 	}
 }
+
+func TestSearchUsers(t *testing.T) {
+	mock_srv := new(transport.GrpcUserSrvMock)
+	endpoints := transport.MakeGrpcUserServiceEndpoints(mock_srv)
+	grpc_service := transport.NewGrpcUserServer(endpoints)
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	mock_srv.On("SearchUsers", ctx, "domain", 10).Return([]entities.Account{
+		{Id: 1, Email: "first@domain.com", Age: 23},
+		{Id: 2, Email: "second@domain.com", Age: 32},
+	}, nil)
+
+	// act
+	res, err := grpc_service.SearchUsers(ctx, &userpb.SearchUsersRequest{Email: "domain", Limit: 10})
+	_, invalid := grpc_service.SearchUsers(ctx, &userpb.SearchUsersRequest{Limit: 10})
+	_, too_short := grpc_service.SearchUsers(ctx, &userpb.SearchUsersRequest{Email: "do", Limit: 10})
+
+	// assert
+	assert.Nil(err)
+	assert.Len(res.GetUsers(), 2)
+	assert.Equal(int32(2), res.GetUsers()[1].GetId())
+	assert.Equal("second@domain.com", res.GetUsers()[1].GetEmail())
+	assert.Equal(uint32(32), res.GetUsers()[1].GetAge())
+	assert.Equal(codes.FailedPrecondition, status.Code(invalid))
+	assert.Equal(codes.FailedPrecondition, status.Code(too_short))
+	mock_srv.AssertNumberOfCalls(t, "SearchUsers", 1)
+}
+
+func TestGetUsers(t *testing.T) {
+	mock_srv := new(transport.GrpcUserSrvMock)
+	endpoints := transport.MakeGrpcUserServiceEndpoints(mock_srv)
+	grpc_service := transport.NewGrpcUserServer(endpoints)
+
+	// prepare
+	assert := assert.New(t)
+	ctx := context.Background()
+	mock_srv.On("GetUsers", ctx, []int{1, 2, 3}).Return([]entities.Account{
+		{Id: 1, Email: "first@domain.com", Age: 23},
+		{Id: 3, Email: "third@domain.com", Age: 32},
+	}, nil)
+	too_many := make([]uint32, service.MaxBatch+1)
+
+	// act
+	res, err := grpc_service.GetUsers(ctx, &userpb.GetUsersRequest{Ids: []uint32{1, 2, 3}})
+	_, invalid := grpc_service.GetUsers(ctx, &userpb.GetUsersRequest{Ids: too_many})
+
+	// assert
+	assert.Nil(err)
+	assert.Len(res.GetUsers(), 2)
+	assert.Equal(int32(3), res.GetUsers()[1].GetId())
+	assert.Equal("third@domain.com", res.GetUsers()[1].GetEmail())
+	assert.Equal(codes.FailedPrecondition, status.Code(invalid))
+	mock_srv.AssertNumberOfCalls(t, "GetUsers", 1)
+}
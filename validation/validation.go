@@ -0,0 +1,251 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mauricioww/user_microsrv/errors"
+)
+
+// Bounds enforced by the rules below. Passwords stop at 72 bytes because
+// bcrypt ignores anything past that; searches by email need a few characters
+// so a single letter cannot list every account.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+	MinAge            = 1
+	MaxAge            = 150
+	MinHeight         = 0.3
+	MaxHeight         = 2.8
+	MinWeight         = 1
+	MaxWeight         = 350
+	MaxEmailLength    = 254
+	MinSearchLength   = 3
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+type (
+	// Rule checks a single value and describes what is wrong with it, or
+	// returns "" when it holds.
+	Rule func(value interface{}) string
+
+	// Field pairs a request field with the rules its value must satisfy.
+	Field struct {
+		name  string
+		value interface{}
+		rules []Rule
+	}
+)
+
+func Check(name string, value interface{}, rules ...Rule) Field {
+	return Field{name: name, value: value, rules: rules}
+}
+
+// Validate checks every field and reports all violations at once as an
+// invalid argument error. Rules of a field run in order and stop at its
+// first violation, so an empty email is "is required" and nothing else.
+func Validate(fields ...Field) error {
+	var violations []errors.FieldViolation
+
+	for _, f := range fields {
+		for _, rule := range f.rules {
+			if desc := rule(f.value); desc != "" {
+				violations = append(violations, errors.FieldViolation{Field: f.name, Description: desc})
+				break
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return errors.NewInvalidArgumentError(violations...)
+}
+
+// Optional skips the remaining rules when the value is empty.
+func Optional(rules ...Rule) Rule {
+	return func(value interface{}) string {
+		if isZero(value) {
+			return ""
+		}
+		for _, rule := range rules {
+			if desc := rule(value); desc != "" {
+				return desc
+			}
+		}
+		return ""
+	}
+}
+
+func Required(value interface{}) string {
+	if isZero(value) {
+		return "is required"
+	}
+	return ""
+}
+
+func Email(value interface{}) string {
+	s, _ := value.(string)
+	if len(s) > MaxEmailLength {
+		return fmt.Sprintf("must be at most %d characters", MaxEmailLength)
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || !strings.Contains(s[strings.LastIndex(s, "@"):], ".") {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+// Password requires MinPasswordLength to MaxPasswordLength bytes with at
+// least one letter and one digit.
+func Password(value interface{}) string {
+	s, _ := value.(string)
+	if len(s) < MinPasswordLength || len(s) > MaxPasswordLength {
+		return fmt.Sprintf("must be between %d and %d characters", MinPasswordLength, MaxPasswordLength)
+	}
+
+	var letter, digit bool
+	for _, r := range s {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	if !letter || !digit {
+		return "must contain at least one letter and one digit"
+	}
+	return ""
+}
+
+func Age(value interface{}) string {
+	return between(value, MinAge, MaxAge)
+}
+
+// Height is in meters.
+func Height(value interface{}) string {
+	return between(value, MinHeight, MaxHeight)
+}
+
+// Weight is in kilograms.
+func Weight(value interface{}) string {
+	return between(value, MinWeight, MaxWeight)
+}
+
+func Positive(value interface{}) string {
+	if n, ok := number(value); !ok || n <= 0 {
+		return "must be positive"
+	}
+	return ""
+}
+
+// Phone accepts E.164 numbers: up to 15 digits with an optional leading +.
+func Phone(value interface{}) string {
+	s, _ := value.(string)
+	if !phonePattern.MatchString(s) {
+		return "must be a phone number of 7 to 15 digits"
+	}
+	return ""
+}
+
+// CountryCode accepts ISO 3166-1 alpha-2 codes in upper case.
+func CountryCode(value interface{}) string {
+	s, _ := value.(string)
+	if !countryCodes[s] {
+		return "must be an ISO 3166-1 alpha-2 country code"
+	}
+	return ""
+}
+
+// MaxLength counts characters, not bytes.
+func MaxLength(max int) Rule {
+	return func(value interface{}) string {
+		s, _ := value.(string)
+		if utf8.RuneCountInString(s) > max {
+			return fmt.Sprintf("must be at most %d characters", max)
+		}
+		return ""
+	}
+}
+
+// MinLength counts characters, not bytes.
+func MinLength(min int) Rule {
+	return func(value interface{}) string {
+		s, _ := value.(string)
+		if utf8.RuneCountInString(s) < min {
+			return fmt.Sprintf("must be at least %d characters", min)
+		}
+		return ""
+	}
+}
+
+// MaxItems bounds a list, checked through its length.
+func MaxItems(max int) Rule {
+	return func(value interface{}) string {
+		if n, _ := value.(int); n > max {
+			return fmt.Sprintf("must have at most %d items", max)
+		}
+		return ""
+	}
+}
+
+// URL accepts absolute http and https URLs.
+func URL(value interface{}) string {
+	s, _ := value.(string)
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "must be an absolute http or https URL"
+	}
+	return ""
+}
+
+func OneOf(values ...string) Rule {
+	return func(value interface{}) string {
+		s, _ := value.(string)
+		for _, v := range values {
+			if s == v {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(values, ", ")
+	}
+}
+
+func between(value interface{}, min float64, max float64) string {
+	if n, ok := number(value); !ok || n < min || n > max {
+		return fmt.Sprintf("must be between %v and %v", min, max)
+	}
+	return ""
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func isZero(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	}
+	n, ok := number(value)
+	return ok && n == 0
+}
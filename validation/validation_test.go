@@ -0,0 +1,136 @@
+package validation_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mauricioww/user_microsrv/errors"
+	"github.com/mauricioww/user_microsrv/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	test_cases := []struct {
+		test_name string
+		rule      validation.Rule
+		valid     []interface{}
+		invalid   []interface{}
+	}{
+		{
+			test_name: "required",
+			rule:      validation.Required,
+			valid:     []interface{}{"a", 1, float32(0.1)},
+			invalid:   []interface{}{nil, "", "   ", 0, float32(0)},
+		},
+		{
+			test_name: "email",
+			rule:      validation.Email,
+			valid:     []interface{}{"user@email.com", "first.last+tag@sub.domain.mx"},
+			invalid:   []interface{}{"user", "user@email", "User <user@email.com>", "user@@email.com", strings.Repeat("a", 250) + "@e.com"},
+		},
+		{
+			test_name: "password",
+			rule:      validation.Password,
+			valid:     []interface{}{"qwerty123", "contraseña9"},
+			invalid:   []interface{}{"qwe123", "qwertyuiop", "1234567890", strings.Repeat("a1", 37)},
+		},
+		{
+			test_name: "age",
+			rule:      validation.Age,
+			valid:     []interface{}{1, 23, 150},
+			invalid:   []interface{}{-1, 0, 151},
+		},
+		{
+			test_name: "height",
+			rule:      validation.Height,
+			valid:     []interface{}{float32(1.75), float32(0.3)},
+			invalid:   []interface{}{float32(-1.75), float32(175)},
+		},
+		{
+			test_name: "weight",
+			rule:      validation.Weight,
+			valid:     []interface{}{float32(76), float32(350)},
+			invalid:   []interface{}{float32(-76), float32(500)},
+		},
+		{
+			test_name: "phone",
+			rule:      validation.Phone,
+			valid:     []interface{}{"11223344", "+525512345678"},
+			invalid:   []interface{}{"123456", "55-1234-5678", "call me", "+1234567890123456"},
+		},
+		{
+			test_name: "country code",
+			rule:      validation.CountryCode,
+			valid:     []interface{}{"MX", "US", "DE"},
+			invalid:   []interface{}{"mx", "Mexico", "XX", ""},
+		},
+		{
+			test_name: "min length",
+			rule:      validation.MinLength(4),
+			valid:     []interface{}{"abcd", "añoñ"},
+			invalid:   []interface{}{"", "abc"},
+		},
+		{
+			test_name: "max items",
+			rule:      validation.MaxItems(2),
+			valid:     []interface{}{0, 2},
+			invalid:   []interface{}{3},
+		},
+		{
+			test_name: "url",
+			rule:      validation.URL,
+			valid:     []interface{}{"https://partner.example.com/hooks", "http://localhost:8080"},
+			invalid:   []interface{}{"", "partner.example.com/hooks", "ftp://partner.example.com", "https://"},
+		},
+		{
+			test_name: "one of",
+			rule:      validation.OneOf("a", "b"),
+			valid:     []interface{}{"a", "b"},
+			invalid:   []interface{}{"", "c", "A"},
+		},
+		{
+			test_name: "optional",
+			rule:      validation.Optional(validation.Phone),
+			valid:     []interface{}{"", "11223344"},
+			invalid:   []interface{}{"call me"},
+		},
+	}
+
+	for _, tc := range test_cases {
+		t.Run(tc.test_name, func(t *testing.T) {
+			// prepare
+			assert := assert.New(t)
+
+			// act & assert
+			for _, v := range tc.valid {
+				assert.Empty(tc.rule(v), "%v", v)
+			}
+			for _, v := range tc.invalid {
+				assert.NotEmpty(tc.rule(v), "%v", v)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// prepare
+	assert := assert.New(t)
+
+	// act
+	err := validation.Validate(
+		validation.Check("email", "", validation.Required, validation.Email),
+		validation.Check("password", "qwerty123", validation.Required, validation.Password),
+		validation.Check("age", 0, validation.Required, validation.Age),
+		validation.Check("country", "Mexico", validation.Required, validation.CountryCode),
+	)
+
+	// assert
+	e := errors.FromError(err)
+	assert.ErrorIs(err, errors.NewInvalidArgumentError())
+	assert.Equal([]errors.FieldViolation{
+		{Field: "email", Description: "is required"},
+		{Field: "age", Description: "is required"},
+		{Field: "country", Description: "must be an ISO 3166-1 alpha-2 country code"},
+	}, e.Violations)
+	assert.Nil(validation.Validate(validation.Check("email", "user@email.com", validation.Required, validation.Email)))
+}